@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"go.mood/internal/jwtkeys"
+)
+
+const keysUsage = "использование: keys generate [RS256|EdDSA]"
+
+// runKeys выполняет подкоманду keys: generate создаёт в auth.keys_dir новый ключ подписи JWT.
+func runKeys(args []string) error {
+	if len(args) == 0 || args[0] != "generate" {
+		return errors.New(keysUsage)
+	}
+	dir := viper.GetString("auth.keys_dir")
+	if dir == "" {
+		return errors.New("в config.yaml не задан auth.keys_dir")
+	}
+
+	alg := "EdDSA"
+	if len(args) > 1 {
+		alg = args[1]
+	}
+	kid, err := jwtkeys.Generate(dir, alg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Создан ключ %s в %s; он станет активным после перечитывания каталога\n", kid, dir)
+	return nil
+}
@@ -2,13 +2,21 @@ package main
 
 import (
 	"github.com/joho/godotenv"
+	"github.com/spf13/viper"
 	"go.mood/internal/database"
+	"go.mood/internal/database/migrations"
 	"go.mood/internal/handler"
+	"go.mood/internal/jwtkeys"
+	"go.mood/internal/mailer"
+	"go.mood/internal/oidc"
 	"go.mood/internal/server"
 	"go.mood/internal/service"
 	"go.mood/pkg"
+	"golang.org/x/crypto/bcrypt"
 	"log"
 	"os"
+	"strings"
+	"time"
 )
 
 func main() {
@@ -22,24 +30,56 @@ func main() {
 		log.Fatal("Ошибка загрузки .env файла:", err)
 	}
 
-	// Получаем JWT_SECRET из переменных окружения
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("Переменная окружения JWT_SECRET не установлена")
+	// Подкоманда migrate: управление схемой БД без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal("Ошибка миграции:", err)
+		}
+		return
 	}
 
-	// 3. Подключение к базе данных
-	connection := database.NewConnectPostgres()
-	if connection == nil {
-		log.Fatal("Не удалось установить соединение с базой данных")
+	// Подкоманда keys: создание ключей подписи JWT
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		if err := runKeys(os.Args[2:]); err != nil {
+			log.Fatal("Ошибка:", err)
+		}
+		return
+	}
+
+	// Ключи подписи JWT: каталог auth.keys_dir (RS256/EdDSA) или JWT_SECRET (HS256).
+	// Выведенный ключ принимается, пока не истекут подписанные им access-токены
+	accessTTL := viper.GetDuration("auth.access_ttl")
+	keys, err := jwtkeys.Load(viper.GetString("auth.keys_dir"), os.Getenv("JWT_SECRET"), accessTTL)
+	if err != nil {
+		log.Fatal("Ошибка загрузки ключей подписи:", err)
 	}
+	go reloadKeys(keys, viper.GetDuration("auth.keys_reload"))
 
-	// 4. Создание объекта базы данных
-	db := database.NewDatabase(connection)
+	// 3-4. Подключение к хранилищу и создание объекта базы данных
+	db := openDatabase()
 
 	// 5. Создание сервисов (бизнес-логики)
-	// Теперь передаём db И jwtSecret
-	services := service.NewService(db, jwtSecret)
+	// Сроки жизни токенов берутся из секции auth в config.yaml
+	services := service.NewService(db, service.Config{
+		Keys:                     keys,
+		AccessTokenTTL:           accessTTL,
+		RefreshTokenTTL:          viper.GetDuration("auth.refresh_ttl"),
+		Mailer:                   openMailer(),
+		BaseURL:                  viper.GetString("app.base_url"),
+		PasswordResetTTL:         viper.GetDuration("auth.password_reset_ttl"),
+		EmailVerificationTTL:     viper.GetDuration("auth.email_verification_ttl"),
+		RequireEmailVerification: viper.GetBool("auth.require_email_verification"),
+		MFAIssuer:                viper.GetString("auth.mfa_issuer"),
+		Lockout: service.LockoutPolicy{
+			Threshold:   viper.GetInt("auth.lockout.threshold"),
+			IPThreshold: viper.GetInt("auth.lockout.ip_threshold"),
+			Lockout:     viper.GetDuration("auth.lockout.duration"),
+			MaxLockout:  viper.GetDuration("auth.lockout.max_duration"),
+		},
+		Passwords:     passwordPolicy(),
+		Hasher:        passwordHasher(),
+		OIDCProviders: oidcProviders(),
+	})
 
 	// 6. Создание обработчиков
 	// Теперь передаём db и services
@@ -52,6 +92,146 @@ func main() {
 	}
 }
 
+// openDatabase создает Database в зависимости от db.driver в config.yaml:
+// "postgres" (по умолчанию) или "memory" — хранение в памяти без PostgreSQL.
+func openDatabase() *database.Database {
+	switch driver := viper.GetString("db.driver"); driver {
+	case "memory":
+		log.Println("Используется хранилище в памяти, данные не сохраняются между запусками")
+		return database.NewMemoryDatabase()
+	case "", "postgres":
+	default:
+		log.Fatalf("Неизвестный db.driver: %s", driver)
+	}
+
+	connection := database.NewConnectPostgres()
+	if connection == nil {
+		log.Fatal("Не удалось установить соединение с базой данных")
+	}
+
+	// Применяем миграции при старте, если это включено в config.yaml
+	if viper.GetBool("db.auto_migrate") {
+		migrator, err := migrations.NewMigrator(connection)
+		if err != nil {
+			log.Fatal("Ошибка загрузки миграций:", err)
+		}
+		if err := migrator.Up(); err != nil {
+			log.Fatal("Ошибка применения миграций:", err)
+		}
+	}
+
+	return database.NewDatabase(connection)
+}
+
+// reloadKeys периодически перечитывает каталог ключей, чтобы новый ключ
+// начал использоваться без перезапуска. interval 0 — не перечитывать.
+func reloadKeys(keys *jwtkeys.KeySet, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	for range time.Tick(interval) {
+		if err := keys.Reload(); err != nil {
+			log.Println("Не удалось перечитать ключи подписи:", err)
+		}
+	}
+}
+
+// oidcProviders читает провайдеров входа OpenID Connect из секции auth.oidc в config.yaml.
+// Секрет клиента берётся из переменной окружения OIDC_<ИМЯ>_CLIENT_SECRET, а адрес
+// возврата строится из app.base_url.
+func oidcProviders() []oidc.Config {
+	var raw map[string]struct {
+		Issuer   string   `mapstructure:"issuer"`
+		ClientID string   `mapstructure:"client_id"`
+		Scopes   []string `mapstructure:"scopes"`
+	}
+	if err := viper.UnmarshalKey("auth.oidc", &raw); err != nil {
+		log.Fatal("Ошибка чтения auth.oidc:", err)
+	}
+
+	baseURL := strings.TrimSuffix(viper.GetString("app.base_url"), "/")
+	providers := make([]oidc.Config, 0, len(raw))
+	for name, p := range raw {
+		if p.Issuer == "" || p.ClientID == "" {
+			log.Fatalf("Провайдер auth.oidc.%s: нужно указать issuer и client_id", name)
+		}
+		providers = append(providers, oidc.Config{
+			Name:         name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: os.Getenv("OIDC_" + strings.ToUpper(name) + "_CLIENT_SECRET"),
+			RedirectURL:  baseURL + "/auth/oidc/" + name + "/callback",
+			Scopes:       p.Scopes,
+		})
+	}
+	return providers
+}
+
+// passwordPolicy читает требования к паролям из секции auth.password в config.yaml.
+// Файл утёкших паролей проверяется при запуске, чтобы опечатка в пути не отключала проверку молча.
+func passwordPolicy() service.PasswordPolicy {
+	policy := service.PasswordPolicy{
+		MinLength:    viper.GetInt("auth.password.min_length"),
+		MinClasses:   viper.GetInt("auth.password.min_classes"),
+		BannedWords:  viper.GetStringSlice("auth.password.banned_words"),
+		BreachedFile: viper.GetString("auth.password.breached_file"),
+	}
+	if policy.BreachedFile != "" {
+		if _, err := os.Stat(policy.BreachedFile); err != nil {
+			log.Fatal("Ошибка чтения auth.password.breached_file:", err)
+		}
+	}
+	return policy
+}
+
+// passwordHasher создает алгоритм хеширования паролей по auth.password_hash.algorithm в config.yaml:
+// "argon2id" (по умолчанию) или "bcrypt". Хеши, сохранённые другим алгоритмом или с другими
+// параметрами, по-прежнему принимаются и пересчитываются при следующем входе.
+func passwordHasher() service.PasswordHasher {
+	switch algorithm := viper.GetString("auth.password_hash.algorithm"); algorithm {
+	case "", "argon2id":
+		parallelism := viper.GetUint("auth.password_hash.argon2_parallelism")
+		if parallelism > 255 {
+			log.Fatal("auth.password_hash.argon2_parallelism должен быть не больше 255")
+		}
+		return &service.Argon2idHasher{
+			Memory:      viper.GetUint32("auth.password_hash.argon2_memory"),
+			Iterations:  viper.GetUint32("auth.password_hash.argon2_iterations"),
+			Parallelism: uint8(parallelism),
+		}
+	case "bcrypt":
+		cost := viper.GetInt("auth.password_hash.bcrypt_cost")
+		if cost > bcrypt.MaxCost {
+			log.Fatalf("auth.password_hash.bcrypt_cost должен быть не больше %d", bcrypt.MaxCost)
+		}
+		return &service.BcryptHasher{Cost: cost}
+	default:
+		log.Fatalf("Неизвестный auth.password_hash.algorithm: %s", algorithm)
+		return nil
+	}
+}
+
+// openMailer создает Mailer в зависимости от mail.driver в config.yaml:
+// "file" (по умолчанию) — письма пишутся в файл mail.file или в лог, "smtp" — отправка через SMTP.
+// Пароль SMTP берётся из переменной окружения SMTP_PASSWORD.
+func openMailer() mailer.Mailer {
+	switch driver := viper.GetString("mail.driver"); driver {
+	case "", "file":
+		return mailer.NewFileMailer(viper.GetString("mail.file"))
+	case "smtp":
+		return mailer.NewSMTPMailer(
+			viper.GetString("mail.smtp_host"),
+			viper.GetString("mail.smtp_port"),
+			viper.GetString("mail.smtp_user"),
+			os.Getenv("SMTP_PASSWORD"),
+			viper.GetString("mail.from"),
+		)
+	default:
+		log.Fatalf("Неизвестный mail.driver: %s", driver)
+		return nil
+	}
+}
+
 //func main() {
 //	// 1. Загружаем конфиг из config.yaml
 //	if err := pkg.InitConfig(); err != nil {
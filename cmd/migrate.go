@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go.mood/internal/database"
+	"go.mood/internal/database/migrations"
+	"strconv"
+)
+
+const migrateUsage = "использование: migrate up|down|status|to N"
+
+// runMigrate выполняет подкоманду migrate: up, down, status или to N.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	connection := database.NewConnectPostgres()
+	defer connection.Close()
+
+	migrator, err := migrations.NewMigrator(connection)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		return migrator.Up()
+	case "down":
+		return migrator.Down()
+	case "to":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("некорректная версия миграции: %s", args[1])
+		}
+		return migrator.To(version)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			applied := "не применена"
+			if st.Applied {
+				applied = "применена " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s  %s\n", st.Version, st.Name, applied)
+		}
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
@@ -1,6 +0,0 @@
-package config
-
-import "os"
-
-// JWT
-var JWTKey = []byte(os.Getenv("1234567890abcdef"))
@@ -0,0 +1,607 @@
+package database_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go.mood/internal/database"
+	"go.mood/internal/database/migrations"
+	"go.mood/internal/model"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// backend создаёт пустое хранилище для одного теста.
+type backend struct {
+	name string
+	open func(t *testing.T) *database.Database
+}
+
+// backends возвращает реализации, на которых проверяется контракт репозиториев:
+// хранилище в памяти всегда, PostgreSQL — если задан TEST_POSTGRES_DSN
+// (отдельная тестовая база: перед каждым тестом её данные удаляются).
+func backends(t *testing.T) []backend {
+	list := []backend{{name: "memory", open: func(t *testing.T) *database.Database {
+		return database.NewMemoryDatabase()
+	}}}
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		return list
+	}
+
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("не удалось открыть PostgreSQL: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	migrator, err := migrations.NewMigrator(conn)
+	if err != nil {
+		t.Fatalf("ошибка загрузки миграций: %v", err)
+	}
+	if err := migrator.Up(); err != nil {
+		t.Fatalf("ошибка применения миграций: %v", err)
+	}
+	return append(list, backend{name: "postgres", open: func(t *testing.T) *database.Database {
+		if _, err := conn.Exec("TRUNCATE users RESTART IDENTITY CASCADE"); err != nil {
+			t.Fatalf("не удалось очистить базу: %v", err)
+		}
+		return database.NewDatabase(conn)
+	}})
+}
+
+// runContract запускает тест на каждой реализации хранилища.
+func runContract(t *testing.T, test func(t *testing.T, db *database.Database)) {
+	for _, b := range backends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			test(t, b.open(t))
+		})
+	}
+}
+
+func createUser(t *testing.T, db *database.Database, name string) model.User {
+	t.Helper()
+	user := model.User{Username: name, Email: name + "@example.com", PasswordHash: "hash"}
+	if err := db.Users.CreateUser(&user); err != nil {
+		t.Fatalf("CreateUser(%s): %v", name, err)
+	}
+	return user
+}
+
+func createTask(t *testing.T, db *database.Database, task model.Task) model.Task {
+	t.Helper()
+	if err := db.Tasks.CreateTask(&task); err != nil {
+		t.Fatalf("CreateTask(%q): %v", task.Task, err)
+	}
+	return task
+}
+
+func getTask(t *testing.T, db *database.Database, id int) model.Task {
+	t.Helper()
+	task, err := db.Tasks.GetTaskByID(int64(id))
+	if err != nil {
+		t.Fatalf("GetTaskByID(%d): %v", id, err)
+	}
+	return task
+}
+
+func taskIDs(tasks []model.Task) []int {
+	ids := make([]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.Id
+	}
+	return ids
+}
+
+func sameIDs(got []model.Task, want ...model.Task) bool {
+	return fmt.Sprint(taskIDs(got)) == fmt.Sprint(taskIDs(want))
+}
+
+func TestUserRepositoryCreateAndGet(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		alice := createUser(t, db, "alice")
+		if alice.Id == 0 {
+			t.Fatal("CreateUser не заполнил id")
+		}
+
+		byID, err := db.Users.GetUserByID(int64(alice.Id))
+		if err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		if byID.Username != "alice" || byID.Email != "alice@example.com" || byID.PasswordHash != "hash" {
+			t.Errorf("GetUserByID вернул %+v", byID)
+		}
+		if byID.Role != string(model.RoleUser) {
+			t.Errorf("роль по умолчанию %q, ожидалась %q", byID.Role, model.RoleUser)
+		}
+		if byName, err := db.Users.GetUserByUsername("alice"); err != nil || byName.Id != alice.Id {
+			t.Errorf("GetUserByUsername: %+v, %v", byName, err)
+		}
+		if byEmail, err := db.Users.GetUserByEmail("alice@example.com"); err != nil || byEmail.Id != alice.Id {
+			t.Errorf("GetUserByEmail: %+v, %v", byEmail, err)
+		}
+
+		if _, err := db.Users.GetUserByID(int64(alice.Id) + 100); !errors.Is(err, model.ErrUnknownUser) {
+			t.Errorf("GetUserByID неизвестного пользователя: %v, ожидалась model.ErrUnknownUser", err)
+		}
+		if _, err := db.Users.GetUserByUsername("nobody"); err == nil {
+			t.Error("GetUserByUsername нашёл несуществующего пользователя")
+		}
+		if _, err := db.Users.GetUserByEmail("nobody@example.com"); err == nil {
+			t.Error("GetUserByEmail нашёл несуществующего пользователя")
+		}
+	})
+}
+
+func TestUserRepositoryEmailTaken(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		createUser(t, db, "alice")
+		bob := createUser(t, db, "bob")
+
+		dup := model.User{Username: "alice2", Email: "alice@example.com", PasswordHash: "hash"}
+		if err := db.Users.CreateUser(&dup); !errors.Is(err, model.ErrEmailTaken) {
+			t.Errorf("CreateUser с занятым email: %v, ожидалась model.ErrEmailTaken", err)
+		}
+		if err := db.Users.UpdateEmail(int64(bob.Id), "alice@example.com", nil); !errors.Is(err, model.ErrEmailTaken) {
+			t.Errorf("UpdateEmail на занятый email: %v, ожидалась model.ErrEmailTaken", err)
+		}
+
+		now := time.Now()
+		if err := db.Users.UpdateEmail(int64(bob.Id), "robert@example.com", &now); err != nil {
+			t.Fatalf("UpdateEmail: %v", err)
+		}
+		got, _ := db.Users.GetUserByID(int64(bob.Id))
+		if got.Email != "robert@example.com" || got.EmailVerifiedAt == nil {
+			t.Errorf("после UpdateEmail: email %q, подтверждён %v", got.Email, got.EmailVerifiedAt)
+		}
+	})
+}
+
+func TestUserRepositoryLoginState(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		id := int64(createUser(t, db, "alice").Id)
+
+		for want := 1; want <= 3; want++ {
+			count, err := db.Users.IncrementFailedLogins(id)
+			if err != nil || count != want {
+				t.Fatalf("IncrementFailedLogins = %d, %v; ожидалось %d", count, err, want)
+			}
+		}
+		until := time.Now().Add(time.Hour)
+		if err := db.Users.LockUser(id, until); err != nil {
+			t.Fatalf("LockUser: %v", err)
+		}
+		got, _ := db.Users.GetUserByID(id)
+		if got.FailedLoginCount != 3 || got.LockedUntil == nil {
+			t.Errorf("после LockUser: попыток %d, блокировка %v", got.FailedLoginCount, got.LockedUntil)
+		}
+
+		if err := db.Users.RecordLogin(id, time.Now()); err != nil {
+			t.Fatalf("RecordLogin: %v", err)
+		}
+		got, _ = db.Users.GetUserByID(id)
+		if got.FailedLoginCount != 0 || got.LockedUntil != nil || got.LastLoginAt == nil {
+			t.Errorf("после RecordLogin: попыток %d, блокировка %v, вход %v", got.FailedLoginCount, got.LockedUntil, got.LastLoginAt)
+		}
+
+		if err := db.Users.SuspendUser(id, time.Now()); err != nil {
+			t.Fatalf("SuspendUser: %v", err)
+		}
+		if got, _ = db.Users.GetUserByID(id); !got.Suspended() {
+			t.Error("SuspendUser не приостановил аккаунт")
+		}
+		if err := db.Users.ReactivateUser(id); err != nil {
+			t.Fatalf("ReactivateUser: %v", err)
+		}
+		if got, _ = db.Users.GetUserByID(id); got.Suspended() {
+			t.Error("ReactivateUser не снял приостановку")
+		}
+
+		if err := db.Users.UpdatePassword(id, "new-hash"); err != nil {
+			t.Fatalf("UpdatePassword: %v", err)
+		}
+		if got, _ = db.Users.GetUserByID(id); got.PasswordHash != "new-hash" {
+			t.Errorf("после UpdatePassword хеш %q", got.PasswordHash)
+		}
+	})
+}
+
+func TestUserRepositoryListAndRoles(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		alice := createUser(t, db, "alice")
+		bob := createUser(t, db, "bob")
+		carol := createUser(t, db, "carol")
+		if err := db.Users.UpdateRole(int64(bob.Id), string(model.RoleAdmin)); err != nil {
+			t.Fatalf("UpdateRole: %v", err)
+		}
+		if err := db.Users.UpdateRole(int64(carol.Id), "no-such-role"); !errors.Is(err, model.ErrUnknownRole) {
+			t.Errorf("UpdateRole с неизвестной ролью: %v, ожидалась model.ErrUnknownRole", err)
+		}
+		if n, err := db.Users.CountUsersByRole(string(model.RoleAdmin)); err != nil || n != 1 {
+			t.Errorf("CountUsersByRole(admin) = %d, %v; ожидалось 1", n, err)
+		}
+
+		users, err := db.Users.ListUsers(model.UserFilter{Limit: 2})
+		if err != nil {
+			t.Fatalf("ListUsers: %v", err)
+		}
+		if len(users) != 2 || users[0].Id != alice.Id || users[1].Id != bob.Id {
+			t.Errorf("первая страница: %+v", users)
+		}
+		users, _ = db.Users.ListUsers(model.UserFilter{AfterID: bob.Id})
+		if len(users) != 1 || users[0].Id != carol.Id {
+			t.Errorf("страница после bob: %+v", users)
+		}
+		users, _ = db.Users.ListUsers(model.UserFilter{Search: "CAR"})
+		if len(users) != 1 || users[0].Id != carol.Id {
+			t.Errorf("поиск CAR: %+v", users)
+		}
+		users, _ = db.Users.ListUsers(model.UserFilter{Role: string(model.RoleAdmin)})
+		if len(users) != 1 || users[0].Id != bob.Id {
+			t.Errorf("фильтр по роли admin: %+v", users)
+		}
+	})
+}
+
+func TestUserRepositoryDeleteRemovesTasks(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		alice := createUser(t, db, "alice")
+		bob := createUser(t, db, "bob")
+		createTask(t, db, model.Task{UserId: int64(alice.Id), Task: "alice"})
+		kept := createTask(t, db, model.Task{UserId: int64(bob.Id), Task: "bob"})
+
+		if err := db.Users.DeleteUserByID(int64(alice.Id)); err != nil {
+			t.Fatalf("DeleteUserByID: %v", err)
+		}
+		if _, err := db.Users.GetUserByID(int64(alice.Id)); !errors.Is(err, model.ErrUnknownUser) {
+			t.Errorf("удалённый пользователь найден: %v", err)
+		}
+		all, err := db.Tasks.GetAllTasks()
+		if err != nil {
+			t.Fatalf("GetAllTasks: %v", err)
+		}
+		if !sameIDs(all, kept) {
+			t.Errorf("после удаления пользователя остались задачи %v", taskIDs(all))
+		}
+	})
+}
+
+func TestUserRepositoryCreateWithInitialTask(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		user := model.User{Username: "alice", Email: "alice@example.com", PasswordHash: "hash"}
+		task := model.Task{Task: "первая задача"}
+		if err := db.Users.CreateUserAndInitialTask(&user, &task); err != nil {
+			t.Fatalf("CreateUserAndInitialTask: %v", err)
+		}
+		tasks, err := db.Tasks.GetTasksByUserID(int64(user.Id))
+		if err != nil {
+			t.Fatalf("GetTasksByUserID: %v", err)
+		}
+		if len(tasks) != 1 || tasks[0].Task != "первая задача" {
+			t.Errorf("задачи нового пользователя: %+v", tasks)
+		}
+
+		dup := model.User{Username: "alice2", Email: "alice@example.com", PasswordHash: "hash"}
+		if err := db.Users.CreateUserAndInitialTask(&dup, &model.Task{Task: "лишняя"}); err == nil {
+			t.Error("CreateUserAndInitialTask с занятым email прошёл")
+		}
+		if all, _ := db.Tasks.GetAllTasks(); len(all) != 1 {
+			t.Errorf("после неудачной транзакции задач %d, ожидалась 1", len(all))
+		}
+	})
+}
+
+func TestTaskRepositoryCreateAndOwnership(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		alice := int64(createUser(t, db, "alice").Id)
+		bob := int64(createUser(t, db, "bob").Id)
+		task := createTask(t, db, model.Task{UserId: alice, Task: "купить хлеб"})
+		createTask(t, db, model.Task{UserId: bob, Task: "чужая"})
+
+		got := getTask(t, db, task.Id)
+		if got.Status != model.StatusPending || got.Priority != model.PriorityNormal || got.CompletedAt != nil {
+			t.Errorf("новая задача: статус %q, приоритет %q, выполнена %v", got.Status, got.Priority, got.CompletedAt)
+		}
+		if own, _ := db.Tasks.GetTasksByUserID(alice); !sameIDs(own, task) {
+			t.Errorf("GetTasksByUserID вернул %v", taskIDs(own))
+		}
+		if err := db.Tasks.CreateTask(&model.Task{UserId: bob + 100, Task: "без владельца"}); err == nil {
+			t.Error("CreateTask для несуществующего пользователя прошёл")
+		}
+
+		update := model.Task{Task: "купить молоко", Priority: model.PriorityHigh}
+		if err := db.Tasks.UpdateTaskByIDWithOwner(int64(task.Id), &update, bob); err == nil {
+			t.Error("чужой пользователь изменил задачу")
+		}
+		if err := db.Tasks.UpdateTaskByIDWithOwner(int64(task.Id), &update, alice); err != nil {
+			t.Fatalf("UpdateTaskByIDWithOwner: %v", err)
+		}
+		if got = getTask(t, db, task.Id); got.Task != "купить молоко" || got.Priority != model.PriorityHigh {
+			t.Errorf("после изменения: %q, %q", got.Task, got.Priority)
+		}
+
+		if err := db.Tasks.DeleteTaskByIDWithOwner(int64(task.Id), bob, false); err == nil {
+			t.Error("чужой пользователь удалил задачу")
+		}
+		if err := db.Tasks.DeleteTaskByIDWithOwner(int64(task.Id), alice, false); err != nil {
+			t.Fatalf("DeleteTaskByIDWithOwner: %v", err)
+		}
+		if _, err := db.Tasks.GetTaskByID(int64(task.Id)); err == nil {
+			t.Error("удалённая задача найдена")
+		}
+	})
+}
+
+func TestTaskRepositoryBulkIsAtomic(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		alice := int64(createUser(t, db, "alice").Id)
+		tasks := []*model.Task{
+			{UserId: alice, Task: "первая"},
+			{UserId: alice + 100, Task: "без владельца"},
+		}
+		if err := db.Tasks.CreateTasksInBulk(tasks); err == nil {
+			t.Fatal("CreateTasksInBulk с несуществующим владельцем прошёл")
+		}
+		if all, _ := db.Tasks.GetAllTasks(); len(all) != 0 {
+			t.Errorf("после неудачной вставки сохранено задач: %d", len(all))
+		}
+
+		tasks = []*model.Task{{UserId: alice, Task: "первая"}, {UserId: alice, Task: "вторая"}}
+		if err := db.Tasks.CreateTasksInBulk(tasks); err != nil {
+			t.Fatalf("CreateTasksInBulk: %v", err)
+		}
+		if all, _ := db.Tasks.GetAllTasks(); len(all) != 2 {
+			t.Errorf("сохранено задач %d, ожидалось 2", len(all))
+		}
+	})
+}
+
+func TestTaskRepositoryStatusAndSubtasks(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		alice := int64(createUser(t, db, "alice").Id)
+		parent := createTask(t, db, model.Task{UserId: alice, Task: "ремонт"})
+		parentID := int64(parent.Id)
+		child := createTask(t, db, model.Task{UserId: alice, Task: "купить краску", ParentID: &parentID})
+		cancelled := createTask(t, db, model.Task{UserId: alice, Task: "нанять мастера", ParentID: &parentID})
+
+		if subtasks, err := db.Tasks.GetSubtasks(parentID, alice); err != nil || !sameIDs(subtasks, child, cancelled) {
+			t.Errorf("GetSubtasks = %v, %v", taskIDs(subtasks), err)
+		}
+		if err := db.Tasks.DeleteTaskByIDWithOwner(parentID, alice, false); !errors.Is(err, model.ErrTaskHasSubtasks) {
+			t.Errorf("удаление задачи с подзадачами без cascade: %v, ожидалась model.ErrTaskHasSubtasks", err)
+		}
+
+		if err := db.Tasks.UpdateTaskStatus(int64(cancelled.Id), alice, model.StatusCancelled, false); err != nil {
+			t.Fatalf("UpdateTaskStatus(cancelled): %v", err)
+		}
+		// Хранилище само отклоняет запрещённый переход: сервис проверяет его до блокировки строки,
+		// и параллельный запрос мог успеть сменить статус
+		if err := db.Tasks.UpdateTaskStatus(int64(cancelled.Id), alice, model.StatusDone, false); !errors.Is(err, model.ErrInvalidTransition) {
+			t.Errorf("UpdateTaskStatus(cancelled → done): %v, ожидалась model.ErrInvalidTransition", err)
+		}
+		// Из cancelled в done перейти нельзя, поэтому cascade её не трогает
+		if err := db.Tasks.UpdateTaskStatus(parentID, alice, model.StatusDone, true); err != nil {
+			t.Fatalf("UpdateTaskStatus(done, cascade): %v", err)
+		}
+		if got := getTask(t, db, parent.Id); got.Status != model.StatusDone || got.CompletedAt == nil {
+			t.Errorf("родитель: статус %q, выполнена %v", got.Status, got.CompletedAt)
+		}
+		if got := getTask(t, db, child.Id); got.Status != model.StatusDone {
+			t.Errorf("подзадача: статус %q, ожидался done", got.Status)
+		}
+		if got := getTask(t, db, cancelled.Id); got.Status != model.StatusCancelled {
+			t.Errorf("отменённая подзадача: статус %q, ожидался cancelled", got.Status)
+		}
+		progress, err := db.Tasks.GetProgressByTaskIDs([]int64{parentID})
+		if err != nil {
+			t.Fatalf("GetProgressByTaskIDs: %v", err)
+		}
+		// Отменённые подзадачи в прогрессе не учитываются
+		if p := progress[parentID]; p.Total != 1 || p.Done != 1 {
+			t.Errorf("прогресс родителя: %+v", p)
+		}
+
+		if err := db.Tasks.UpdateTaskStatus(parentID, alice, model.StatusPending, false); err != nil {
+			t.Fatalf("UpdateTaskStatus(pending): %v", err)
+		}
+		if got := getTask(t, db, parent.Id); got.CompletedAt != nil {
+			t.Error("после возврата в pending осталась отметка о выполнении")
+		}
+
+		if err := db.Tasks.DeleteTaskByIDWithOwner(parentID, alice, true); err != nil {
+			t.Fatalf("DeleteTaskByIDWithOwner(cascade): %v", err)
+		}
+		if all, _ := db.Tasks.GetAllTasks(); len(all) != 0 {
+			t.Errorf("после каскадного удаления остались задачи %v", taskIDs(all))
+		}
+	})
+}
+
+func TestTaskRepositoryDueAndOverdue(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		alice := int64(createUser(t, db, "alice").Id)
+		bob := int64(createUser(t, db, "bob").Id)
+		// Сроки приходят со смещением, а границы выборки — в UTC: сравниваться должны моменты времени
+		now := time.Now().UTC().Truncate(time.Second)
+		zone := time.FixedZone("UTC+5", 5*60*60)
+		at := func(d time.Duration) *time.Time {
+			t := now.Add(d).In(zone)
+			return &t
+		}
+
+		overdue := createTask(t, db, model.Task{UserId: alice, Task: "просрочена", DueAt: at(-time.Hour)})
+		done := createTask(t, db, model.Task{UserId: alice, Task: "выполнена", DueAt: at(-2 * time.Hour)})
+		later := createTask(t, db, model.Task{UserId: alice, Task: "завтра", DueAt: at(24 * time.Hour)})
+		soon := createTask(t, db, model.Task{UserId: alice, Task: "скоро", DueAt: at(time.Hour)})
+		createTask(t, db, model.Task{UserId: alice, Task: "без срока"})
+		createTask(t, db, model.Task{UserId: bob, Task: "чужая", DueAt: at(-time.Hour)})
+		if err := db.Tasks.UpdateTaskStatus(int64(done.Id), alice, model.StatusDone, false); err != nil {
+			t.Fatalf("UpdateTaskStatus: %v", err)
+		}
+
+		if due := getTask(t, db, overdue.Id).DueAt; due == nil || !due.Equal(*overdue.DueAt) {
+			t.Errorf("сохранён срок %v, ожидался %v", due, overdue.DueAt)
+		}
+		got, err := db.Tasks.GetOverdueTasks(alice, now)
+		if err != nil || !sameIDs(got, overdue) {
+			t.Errorf("GetOverdueTasks = %v, %v; ожидалось [%d]", taskIDs(got), err, overdue.Id)
+		}
+		got, err = db.Tasks.GetTasksDueBetween(alice, now.Add(-3*time.Hour), now.Add(48*time.Hour))
+		if err != nil || !sameIDs(got, overdue, soon, later) {
+			t.Errorf("GetTasksDueBetween = %v, %v; ожидалось по сроку %v", taskIDs(got), err, taskIDs([]model.Task{overdue, soon, later}))
+		}
+	})
+}
+
+func TestTaskRepositoryListPagination(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		alice := int64(createUser(t, db, "alice").Id)
+		bob := int64(createUser(t, db, "bob").Id)
+		var want []model.Task
+		for i := 1; i <= 5; i++ {
+			want = append(want, createTask(t, db, model.Task{UserId: alice, Task: fmt.Sprintf("задача %d", i)}))
+		}
+		createTask(t, db, model.Task{UserId: bob, Task: "задача bob"})
+
+		if _, err := db.Tasks.ListTasks(model.TaskFilter{UserID: alice, SortBy: "task; DROP TABLE tasks"}); err == nil {
+			t.Error("ListTasks принял недопустимое поле сортировки")
+		}
+
+		var got []model.Task
+		filter := model.TaskFilter{UserID: alice, SortBy: "id", Limit: 2}
+		for page := 0; page < 5; page++ {
+			tasks, err := db.Tasks.ListTasks(filter)
+			if err != nil {
+				t.Fatalf("ListTasks: %v", err)
+			}
+			if len(tasks) == 0 {
+				break
+			}
+			got = append(got, tasks...)
+			last := tasks[len(tasks)-1]
+			filter.After = &model.TaskCursor{SortBy: "id", Value: last.SortKey("id"), ID: last.Id}
+		}
+		if !sameIDs(got, want...) {
+			t.Errorf("страницы по возрастанию id: %v, ожидалось %v", taskIDs(got), taskIDs(want))
+		}
+
+		desc, err := db.Tasks.ListTasks(model.TaskFilter{UserID: alice, SortBy: "id", Desc: true, Limit: 2})
+		if err != nil || !sameIDs(desc, want[4], want[3]) {
+			t.Errorf("первая страница по убыванию: %v, %v", taskIDs(desc), err)
+		}
+		found, err := db.Tasks.ListTasks(model.TaskFilter{UserID: alice, SortBy: "id", Search: "ЗАДАЧА 3"})
+		if err != nil || !sameIDs(found, want[2]) {
+			t.Errorf("поиск по тексту: %v, %v", taskIDs(found), err)
+		}
+	})
+}
+
+// TestRepositoriesConcurrentAccess проверяет, что репозитории можно вызывать
+// из нескольких горутин одновременно; гонки ловятся при запуске с -race.
+// Каждая горутина выполняет только одну операцию, чтобы блокировки соседних
+// вызовов не упорядочивали её доступ к данным и не скрывали гонку.
+func TestRepositoriesConcurrentAccess(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		const rounds = 50
+		alice := int64(createUser(t, db, "alice").Id)
+		bob := int64(createUser(t, db, "bob").Id)
+		seed := createTask(t, db, model.Task{UserId: alice, Task: "общая"})
+
+		ops := map[string]func(i int) error{
+			"CreateUser": func(i int) error {
+				name := fmt.Sprintf("user%d", i)
+				return db.Users.CreateUser(&model.User{Username: name, Email: name + "@example.com", PasswordHash: "hash"})
+			},
+			"GetUserByID": func(int) error {
+				_, err := db.Users.GetUserByID(alice)
+				return err
+			},
+			"ListUsers": func(int) error {
+				_, err := db.Users.ListUsers(model.UserFilter{Limit: 10})
+				return err
+			},
+			"IncrementFailedLogins": func(int) error {
+				_, err := db.Users.IncrementFailedLogins(alice)
+				return err
+			},
+			"RecordLogin": func(int) error {
+				return db.Users.RecordLogin(bob, time.Now())
+			},
+			"CreateTask": func(i int) error {
+				return db.Tasks.CreateTask(&model.Task{UserId: bob, Task: fmt.Sprintf("задача %d", i)})
+			},
+			"UpdateTaskStatus": func(i int) error {
+				status := model.StatusDone
+				if i%2 == 1 {
+					status = model.StatusPending
+				}
+				return db.Tasks.UpdateTaskStatus(int64(seed.Id), alice, status, false)
+			},
+			"GetTaskByID": func(int) error {
+				_, err := db.Tasks.GetTaskByID(int64(seed.Id))
+				return err
+			},
+			"ListTasks": func(int) error {
+				_, err := db.Tasks.ListTasks(model.TaskFilter{UserID: bob, SortBy: "id", Limit: 10})
+				return err
+			},
+			"GetAllTasks": func(int) error {
+				_, err := db.Tasks.GetAllTasks()
+				return err
+			},
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, len(ops))
+		for name, op := range ops {
+			wg.Add(1)
+			go func(name string, op func(int) error) {
+				defer wg.Done()
+				for i := 0; i < rounds; i++ {
+					if err := op(i); err != nil {
+						errs <- fmt.Errorf("%s: %w", name, err)
+						return
+					}
+				}
+			}(name, op)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+
+		if users, _ := db.Users.ListUsers(model.UserFilter{}); len(users) != rounds+2 {
+			t.Errorf("пользователей %d, ожидалось %d", len(users), rounds+2)
+		}
+		if got, _ := db.Users.GetUserByID(alice); got.FailedLoginCount != rounds {
+			t.Errorf("неудачных попыток %d, ожидалось %d", got.FailedLoginCount, rounds)
+		}
+		if tasks, _ := db.Tasks.GetTasksByUserID(bob); len(tasks) != rounds {
+			t.Errorf("задач bob %d, ожидалось %d", len(tasks), rounds)
+		}
+	})
+}
+
+func TestTaskRepositorySearchEscapesSnippet(t *testing.T) {
+	runContract(t, func(t *testing.T, db *database.Database) {
+		alice := int64(createUser(t, db, "alice").Id)
+		task := createTask(t, db, model.Task{UserId: alice, Task: `купить <img src=x onerror="alert(1)"> молоко & хлеб`})
+
+		results, err := db.Tasks.SearchTasks(alice, model.ParseSearchQuery("молоко"), 10)
+		if err != nil {
+			t.Fatalf("SearchTasks: %v", err)
+		}
+		if len(results) != 1 || results[0].Id != task.Id {
+			t.Fatalf("SearchTasks нашёл %+v", results)
+		}
+		got := results[0].Snippet
+		if !strings.Contains(got, "<b>молоко</b>") || !strings.Contains(got, "&lt;img") || strings.Contains(got, "<img") {
+			t.Errorf("фрагмент %q: текст задачи не экранирован или совпадение не выделено", got)
+		}
+	})
+}
@@ -3,19 +3,60 @@ package database
 import (
 	"database/sql"
 	_ "github.com/lib/pq"
+	"go.mood/internal/database/memory"
 	"go.mood/internal/database/queries"
 )
 
-// Database — структура, содержащая все наборы запросов.
+// Database — структура, содержащая все репозитории.
 type Database struct {
-	UserQueries *queries.UserQueries
-	TaskQueries *queries.TaskQueries
+	Users      UserRepository
+	Tasks      TaskRepository
+	Tags       TagRepository
+	Projects   ProjectRepository
+	Series     SeriesRepository
+	Tokens     TokenRepository
+	MFA        MFARepository
+	APIKeys    APIKeyRepository
+	Roles      RoleRepository
+	Identities IdentityRepository
+	OAuth      OAuthRepository
+	Sessions   SessionRepository
 }
 
-// NewDatabase создает новый экземпляр Database.
+// NewDatabase создает новый экземпляр Database поверх PostgreSQL.
 func NewDatabase(conn *sql.DB) *Database {
 	return &Database{
-		UserQueries: queries.NewUserQueries(conn),
-		TaskQueries: queries.NewTaskQueries(conn),
+		Users:      queries.NewUserQueries(conn),
+		Tasks:      queries.NewTaskQueries(conn),
+		Tags:       queries.NewTagQueries(conn),
+		Projects:   queries.NewProjectQueries(conn),
+		Series:     queries.NewSeriesQueries(conn),
+		Tokens:     queries.NewTokenQueries(conn),
+		MFA:        queries.NewMFAQueries(conn),
+		APIKeys:    queries.NewAPIKeyQueries(conn),
+		Roles:      queries.NewRoleQueries(conn),
+		Identities: queries.NewIdentityQueries(conn),
+		OAuth:      queries.NewOAuthQueries(conn),
+		Sessions:   queries.NewSessionQueries(conn),
+	}
+}
+
+// NewMemoryDatabase создает Database, хранящую данные в памяти процесса.
+// Используется для локального запуска и тестов без PostgreSQL.
+func NewMemoryDatabase() *Database {
+	store := memory.NewStore()
+	return &Database{
+		Users:      memory.NewUserRepository(store),
+		Tasks:      memory.NewTaskRepository(store),
+		Tags:       memory.NewTagRepository(store),
+		Projects:   memory.NewProjectRepository(store),
+		Series:     memory.NewSeriesRepository(store),
+		Tokens:     memory.NewTokenRepository(store),
+		MFA:        memory.NewMFARepository(store),
+		APIKeys:    memory.NewAPIKeyRepository(store),
+		Roles:      memory.NewRoleRepository(store),
+		Identities: memory.NewIdentityRepository(store),
+		OAuth:      memory.NewOAuthRepository(store),
+		Sessions:   memory.NewSessionRepository(store),
 	}
 }
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"go.mood/internal/model"
+	"sort"
+	"time"
+)
+
+// APIKeyRepository хранит персональные API-ключи в памяти.
+type APIKeyRepository struct {
+	store *Store
+}
+
+// NewAPIKeyRepository создает новый экземпляр APIKeyRepository.
+func NewAPIKeyRepository(store *Store) *APIKeyRepository {
+	return &APIKeyRepository{store: store}
+}
+
+// CreateAPIKey сохраняет новый API-ключ.
+func (r *APIKeyRepository) CreateAPIKey(key *model.APIKey) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.nextAPIKeyID++
+	key.Id = r.store.nextAPIKeyID
+	r.store.apiKeys[key.Id] = *key
+	return nil
+}
+
+// GetAPIKeysByUserID получает неотозванные API-ключи пользователя.
+func (r *APIKeyRepository) GetAPIKeysByUserID(userID int64) ([]model.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	keys := []model.APIKey{}
+	for _, key := range r.store.apiKeys {
+		if key.UserId == userID && key.RevokedAt == nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Id < keys[j].Id })
+	return keys, nil
+}
+
+// GetAPIKeyByHash получает API-ключ по SHA-256 от его значения.
+func (r *APIKeyRepository) GetAPIKeyByHash(hash string) (model.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, key := range r.store.apiKeys {
+		if key.TokenHash == hash {
+			return key, nil
+		}
+	}
+	return model.APIKey{}, model.ErrUnknownAPIKey
+}
+
+// RevokeAPIKeyWithOwner отзывает API-ключ, если он принадлежит ownerID.
+func (r *APIKeyRepository) RevokeAPIKeyWithOwner(id, ownerID int64, now time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key, ok := r.store.apiKeys[id]
+	if !ok || key.UserId != ownerID || key.RevokedAt != nil {
+		return model.ErrUnknownAPIKey
+	}
+	key.RevokedAt = &now
+	r.store.apiKeys[id] = key
+	return nil
+}
+
+// TouchAPIKey запоминает время использования ключа, не чаще раза в минуту.
+func (r *APIKeyRepository) TouchAPIKey(id int64, now time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key, ok := r.store.apiKeys[id]
+	if !ok || (key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < time.Minute) {
+		return nil
+	}
+	key.LastUsedAt = &now
+	r.store.apiKeys[id] = key
+	return nil
+}
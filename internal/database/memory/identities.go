@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"fmt"
+	"go.mood/internal/model"
+	"time"
+)
+
+// IdentityRepository хранит учётные записи внешних провайдеров в памяти.
+type IdentityRepository struct {
+	store *Store
+}
+
+// NewIdentityRepository создает новый экземпляр IdentityRepository.
+func NewIdentityRepository(store *Store) *IdentityRepository {
+	return &IdentityRepository{store: store}
+}
+
+// GetIdentity получает учётную запись по провайдеру и sub; model.ErrUnknownIdentity, если её нет.
+func (r *IdentityRepository) GetIdentity(provider, subject string) (model.Identity, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, identity := range r.store.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return model.Identity{}, model.ErrUnknownIdentity
+}
+
+// CreateIdentity привязывает учётную запись провайдера к identity.UserId.
+func (r *IdentityRepository) CreateIdentity(identity *model.Identity) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[identity.UserId]; !ok {
+		return model.ErrUnknownUser
+	}
+	return r.insertIdentity(identity)
+}
+
+// CreateUserWithIdentity создаёт пользователя и привязывает к нему учётную запись провайдера.
+func (r *IdentityRepository) CreateUserWithIdentity(user *model.User, identity *model.Identity) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.findIdentity(identity.Provider, identity.Subject) {
+		return model.ErrIdentityLinked
+	}
+	users := UserRepository{store: r.store}
+	if err := users.insertUser(user); err != nil {
+		return fmt.Errorf("ошибка создания пользователя в транзакции: %w", err)
+	}
+	identity.UserId = int64(user.Id)
+	return r.insertIdentity(identity)
+}
+
+// TouchIdentity запоминает время входа через учётную запись провайдера.
+func (r *IdentityRepository) TouchIdentity(id int64, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if identity, ok := r.store.identities[id]; ok {
+		identity.LastLoginAt = &at
+		r.store.identities[id] = identity
+	}
+	return nil
+}
+
+// insertIdentity сохраняет учётную запись, соблюдая уникальность провайдера и sub.
+// Вызывать под блокировкой на запись.
+func (r *IdentityRepository) insertIdentity(identity *model.Identity) error {
+	if r.findIdentity(identity.Provider, identity.Subject) {
+		return model.ErrIdentityLinked
+	}
+	r.store.nextIdentityID++
+	identity.Id = r.store.nextIdentityID
+	identity.CreatedAt = time.Now()
+	r.store.identities[identity.Id] = *identity
+	return nil
+}
+
+// findIdentity сообщает, привязана ли уже учётная запись. Вызывать под блокировкой.
+func (r *IdentityRepository) findIdentity(provider, subject string) bool {
+	for _, identity := range r.store.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return true
+		}
+	}
+	return false
+}
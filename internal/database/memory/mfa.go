@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"errors"
+	"go.mood/internal/model"
+	"sort"
+	"time"
+)
+
+// MFARepository хранит настройки TOTP и коды восстановления в памяти.
+type MFARepository struct {
+	store *Store
+}
+
+// NewMFARepository создает новый экземпляр MFARepository.
+func NewMFARepository(store *Store) *MFARepository {
+	return &MFARepository{store: store}
+}
+
+// GetMFA получает настройки TOTP пользователя; model.ErrMFANotEnrolled, если их нет.
+func (r *MFARepository) GetMFA(userID int64) (model.MFA, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	m, ok := r.store.mfa[userID]
+	if !ok {
+		return m, model.ErrMFANotEnrolled
+	}
+	return m, nil
+}
+
+// SaveMFASecret сохраняет новый неподтверждённый секрет. Подтверждённый секрет не меняется.
+func (r *MFARepository) SaveMFASecret(userID int64, secret string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if m, ok := r.store.mfa[userID]; ok && m.Enabled() {
+		return nil
+	}
+	r.store.mfa[userID] = model.MFA{UserId: userID, Secret: secret}
+	return nil
+}
+
+// ConfirmMFA включает TOTP, запоминает шаг подтверждающего кода и сохраняет коды восстановления.
+func (r *MFARepository) ConfirmMFA(userID, counter int64, codeHashes []string, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, ok := r.store.mfa[userID]
+	if !ok || m.Enabled() {
+		return errors.New("2FA уже подтверждена или не подключалась")
+	}
+	m.ConfirmedAt = &at
+	m.LastCounter = counter
+	r.store.mfa[userID] = m
+	r.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+// UseTOTPCounter запоминает шаг принятого кода. false — код этого или более
+// позднего шага уже использовался.
+func (r *MFARepository) UseTOTPCounter(userID, counter int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, ok := r.store.mfa[userID]
+	if !ok || m.LastCounter >= counter {
+		return false, nil
+	}
+	m.LastCounter = counter
+	r.store.mfa[userID] = m
+	return true, nil
+}
+
+// GetRecoveryCodes получает неиспользованные коды восстановления пользователя.
+func (r *MFARepository) GetRecoveryCodes(userID int64) ([]model.RecoveryCode, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var codes []model.RecoveryCode
+	for _, c := range r.store.recoveryCodes {
+		if c.UserId == userID && c.UsedAt == nil {
+			codes = append(codes, c)
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Id < codes[j].Id })
+	return codes, nil
+}
+
+// UseRecoveryCode отмечает код использованным. false — код уже был использован.
+func (r *MFARepository) UseRecoveryCode(id int64, at time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c, ok := r.store.recoveryCodes[id]
+	if !ok || c.UsedAt != nil {
+		return false, nil
+	}
+	c.UsedAt = &at
+	r.store.recoveryCodes[id] = c
+	return true, nil
+}
+
+// ReplaceRecoveryCodes заменяет все коды восстановления пользователя новыми.
+func (r *MFARepository) ReplaceRecoveryCodes(userID int64, codeHashes []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+// DeleteMFA отключает TOTP и удаляет коды восстановления пользователя.
+func (r *MFARepository) DeleteMFA(userID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.mfa, userID)
+	r.replaceRecoveryCodes(userID, nil)
+	return nil
+}
+
+// replaceRecoveryCodes вызывать под блокировкой на запись.
+func (r *MFARepository) replaceRecoveryCodes(userID int64, codeHashes []string) {
+	for id, c := range r.store.recoveryCodes {
+		if c.UserId == userID {
+			delete(r.store.recoveryCodes, id)
+		}
+	}
+	for _, hash := range codeHashes {
+		r.store.nextRecoveryCodeID++
+		r.store.recoveryCodes[r.store.nextRecoveryCodeID] = model.RecoveryCode{
+			Id:       r.store.nextRecoveryCodeID,
+			UserId:   userID,
+			CodeHash: hash,
+		}
+	}
+}
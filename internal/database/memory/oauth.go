@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"go.mood/internal/model"
+	"sort"
+	"time"
+)
+
+// OAuthRepository хранит приложения OAuth2 и коды авторизации в памяти.
+type OAuthRepository struct {
+	store *Store
+}
+
+// NewOAuthRepository создает новый экземпляр OAuthRepository.
+func NewOAuthRepository(store *Store) *OAuthRepository {
+	return &OAuthRepository{store: store}
+}
+
+// CreateClient сохраняет новое приложение.
+func (r *OAuthRepository) CreateClient(c *model.OAuthClient) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.oauthClients[c.Id] = *c
+	return nil
+}
+
+// GetClients получает все приложения в порядке регистрации.
+func (r *OAuthRepository) GetClients() ([]model.OAuthClient, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	clients := make([]model.OAuthClient, 0, len(r.store.oauthClients))
+	for _, c := range r.store.oauthClients {
+		clients = append(clients, c)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if !clients[i].CreatedAt.Equal(clients[j].CreatedAt) {
+			return clients[i].CreatedAt.Before(clients[j].CreatedAt)
+		}
+		return clients[i].Id < clients[j].Id
+	})
+	return clients, nil
+}
+
+// GetClient получает приложение по client_id; model.ErrUnknownOAuthClient, если его нет.
+func (r *OAuthRepository) GetClient(id string) (model.OAuthClient, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	c, ok := r.store.oauthClients[id]
+	if !ok {
+		return model.OAuthClient{}, model.ErrUnknownOAuthClient
+	}
+	return c, nil
+}
+
+// DeleteClient удаляет приложение вместе с его кодами и refresh-токенами и отзывает
+// ещё действующие access-токены, выданные приложению.
+func (r *OAuthRepository) DeleteClient(id string, now time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.oauthClients[id]; !ok {
+		return model.ErrUnknownOAuthClient
+	}
+	tokens := TokenRepository{store: r.store}
+	tokens.revokeWhere(func(t model.RefreshToken) bool { return t.ClientID == id }, now)
+	for tokenID, t := range r.store.refreshTokens {
+		if t.ClientID == id {
+			delete(r.store.refreshTokens, tokenID)
+		}
+	}
+	for codeID, c := range r.store.oauthCodes {
+		if c.ClientID == id {
+			delete(r.store.oauthCodes, codeID)
+		}
+	}
+	for sessionID, s := range r.store.sessions {
+		if s.ClientID == id {
+			delete(r.store.sessions, sessionID)
+		}
+	}
+	delete(r.store.oauthClients, id)
+	return nil
+}
+
+// CreateCode сохраняет код авторизации.
+func (r *OAuthRepository) CreateCode(c *model.OAuthCode) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.nextOAuthCodeID++
+	c.Id = r.store.nextOAuthCodeID
+	r.store.oauthCodes[c.Id] = *c
+	return nil
+}
+
+// ConsumeCode отмечает код использованным и запоминает familyID. Неизвестный код —
+// model.ErrInvalidOAuthCode; уже использованный — model.ErrOAuthCodeReused вместе
+// с FamilyID прошлого обмена.
+func (r *OAuthRepository) ConsumeCode(hash, familyID string, now time.Time) (model.OAuthCode, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, c := range r.store.oauthCodes {
+		if c.CodeHash != hash {
+			continue
+		}
+		if c.UsedAt != nil {
+			return model.OAuthCode{FamilyID: c.FamilyID}, model.ErrOAuthCodeReused
+		}
+		c.UsedAt = &now
+		c.FamilyID = familyID
+		r.store.oauthCodes[id] = c
+		return c, nil
+	}
+	return model.OAuthCode{}, model.ErrInvalidOAuthCode
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"go.mood/internal/model"
+	"sort"
+	"time"
+)
+
+// ProjectRepository хранит проекты в памяти.
+type ProjectRepository struct {
+	store *Store
+}
+
+// NewProjectRepository создает новый экземпляр ProjectRepository.
+func NewProjectRepository(store *Store) *ProjectRepository {
+	return &ProjectRepository{store: store}
+}
+
+// CreateProject создает проект пользователя.
+func (r *ProjectRepository) CreateProject(p *model.Project) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.nextProjectID++
+	p.Id = int(r.store.nextProjectID)
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+	p.Archived = false
+	r.store.projects[r.store.nextProjectID] = *p
+	return nil
+}
+
+// GetProjectsByUserID получает проекты пользователя; архивные — только если withArchived.
+func (r *ProjectRepository) GetProjectsByUserID(userID int64, withArchived bool) ([]model.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var projects []model.Project
+	for _, p := range r.store.projects {
+		if p.UserId == userID && (withArchived || !p.Archived) {
+			projects = append(projects, p)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Name != projects[j].Name {
+			return projects[i].Name < projects[j].Name
+		}
+		return projects[i].Id < projects[j].Id
+	})
+	return projects, nil
+}
+
+// GetProjectByIDWithOwner получает проект, только если он принадлежит ownerID.
+func (r *ProjectRepository) GetProjectByIDWithOwner(id, ownerID int64) (model.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	p, ok := r.store.projects[id]
+	if !ok || p.UserId != ownerID {
+		return model.Project{}, model.ErrUnknownProject
+	}
+	return p, nil
+}
+
+// UpdateProjectByIDWithOwner меняет имя, описание и цвет проекта, если он принадлежит ownerID.
+func (r *ProjectRepository) UpdateProjectByIDWithOwner(id int64, p *model.Project, ownerID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.projects[id]
+	if !ok || stored.UserId != ownerID {
+		return model.ErrUnknownProject
+	}
+	stored.Name = p.Name
+	stored.Description = p.Description
+	stored.Color = p.Color
+	stored.UpdatedAt = time.Now()
+	r.store.projects[id] = stored
+	return nil
+}
+
+// SetProjectArchived архивирует или возвращает из архива проект ownerID.
+func (r *ProjectRepository) SetProjectArchived(id, ownerID int64, archived bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.projects[id]
+	if !ok || stored.UserId != ownerID {
+		return model.ErrUnknownProject
+	}
+	stored.Archived = archived
+	stored.UpdatedAt = time.Now()
+	r.store.projects[id] = stored
+	return nil
+}
+
+// DeleteProjectByIDWithOwner удаляет проект ownerID, оставляя его задачи без проекта.
+func (r *ProjectRepository) DeleteProjectByIDWithOwner(id, ownerID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.projects[id]
+	if !ok || stored.UserId != ownerID {
+		return model.ErrUnknownProject
+	}
+	delete(r.store.projects, id)
+	for taskID, t := range r.store.tasks {
+		if t.ProjectID != nil && *t.ProjectID == id {
+			t.ProjectID = nil
+			r.store.tasks[taskID] = t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"fmt"
+	"go.mood/internal/model"
+	"sort"
+	"time"
+)
+
+// RoleRepository хранит роли в памяти.
+type RoleRepository struct {
+	store *Store
+}
+
+// NewRoleRepository создает новый экземпляр RoleRepository.
+func NewRoleRepository(store *Store) *RoleRepository {
+	return &RoleRepository{store: store}
+}
+
+// GetRoles получает все роли: сначала встроенные, затем остальные по имени.
+func (r *RoleRepository) GetRoles() ([]model.RoleDefinition, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	roles := make([]model.RoleDefinition, 0, len(r.store.roles))
+	for _, role := range r.store.roles {
+		roles = append(roles, copyRole(role))
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].BuiltIn != roles[j].BuiltIn {
+			return roles[i].BuiltIn
+		}
+		return roles[i].Name < roles[j].Name
+	})
+	return roles, nil
+}
+
+// GetRole получает роль по имени.
+func (r *RoleRepository) GetRole(name string) (model.RoleDefinition, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	role, ok := r.store.roles[name]
+	if !ok {
+		return model.RoleDefinition{}, model.ErrUnknownRole
+	}
+	return copyRole(role), nil
+}
+
+// CreateRole сохраняет новую роль; занятое имя — model.ErrRoleExists.
+func (r *RoleRepository) CreateRole(role *model.RoleDefinition) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.roles[role.Name]; ok {
+		return model.ErrRoleExists
+	}
+	role.BuiltIn = false
+	role.CreatedAt = time.Now()
+	r.store.roles[role.Name] = copyRole(*role)
+	return nil
+}
+
+// UpdateRole заменяет описание и права роли. Встроенные роли не меняются.
+func (r *RoleRepository) UpdateRole(name, description string, permissions []model.Permission) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	role, ok := r.store.roles[name]
+	if !ok || role.BuiltIn {
+		return model.ErrUnknownRole
+	}
+	role.Description = description
+	role.Permissions = append([]model.Permission{}, permissions...)
+	r.store.roles[name] = role
+	return nil
+}
+
+// DeleteRole удаляет роль, если она не встроенная и не назначена пользователям.
+func (r *RoleRepository) DeleteRole(name string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	role, ok := r.store.roles[name]
+	if !ok || role.BuiltIn {
+		return model.ErrUnknownRole
+	}
+	for _, u := range r.store.users {
+		if u.Role == name {
+			return model.ErrRoleInUse
+		}
+	}
+	delete(r.store.roles, name)
+	return nil
+}
+
+// GetUserPermissions получает права текущей роли пользователя.
+func (r *RoleRepository) GetUserPermissions(userID int64) ([]model.Permission, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, ok := r.store.users[userID]
+	if !ok {
+		return nil, fmt.Errorf("пользователь с id %d не найден", userID)
+	}
+	return append([]model.Permission{}, r.store.roles[user.Role].Permissions...), nil
+}
+
+// copyRole копирует роль вместе со срезом прав, чтобы вызывающий не менял хранилище.
+func copyRole(role model.RoleDefinition) model.RoleDefinition {
+	role.Permissions = append([]model.Permission{}, role.Permissions...)
+	return role
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"go.mood/internal/model"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// snippetWords — сколько слов вокруг первого совпадения попадает во фрагмент.
+const snippetWords = 30
+
+// token — слово текста задачи и его положение в байтах.
+type token struct {
+	word       string
+	start, end int
+}
+
+// SearchTasks ищет задачи пользователя по совпадению слов: задача подходит,
+// если в ней встречаются все термы запроса. Релевантность — доля совпавших слов.
+func (r *TaskRepository) SearchTasks(userID int64, query model.SearchQuery, limit int) ([]model.TaskSearchResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var results []model.TaskSearchResult
+	for _, t := range r.store.sortedTasks(func(t model.Task) bool { return t.UserId == userID }) {
+		tokens := tokenize(t.Task)
+		matched := make([]bool, len(tokens))
+		hits := 0
+		for _, term := range query.Terms {
+			n := matchTerm(tokens, term, matched)
+			if n == 0 {
+				hits = 0
+				break
+			}
+			hits += n
+		}
+		if hits == 0 {
+			continue
+		}
+		results = append(results, model.TaskSearchResult{
+			Task:    t,
+			Rank:    float64(hits) / float64(len(tokens)),
+			Snippet: snippet(t.Task, tokens, matched),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Id > results[j].Id
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// tokenize разбивает текст на слова так же, как model.SearchTokens, запоминая их позиции.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			tokens = append(tokens, token{word: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{word: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// matchTerm отмечает в matched слова всех вхождений терма и возвращает число вхождений.
+func matchTerm(tokens []token, term model.SearchTerm, matched []bool) int {
+	n := len(term.Words)
+	count := 0
+	for i := 0; i+n <= len(tokens); i++ {
+		ok := true
+		for j, w := range term.Words {
+			word := tokens[i+j].word
+			if j == n-1 && term.Prefix {
+				ok = strings.HasPrefix(word, w)
+			} else {
+				ok = word == w
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			count++
+			for j := 0; j < n; j++ {
+				matched[i+j] = true
+			}
+		}
+	}
+	return count
+}
+
+// snippet возвращает фрагмент текста вокруг первого совпадения,
+// выделяя совпавшие слова тегами <b></b>, как ts_headline. Текст экранируется
+// до расстановки тегов, как в search.sql.
+func snippet(text string, tokens []token, matched []bool) string {
+	first := 0
+	for i, m := range matched {
+		if m {
+			first = i
+			break
+		}
+	}
+	from := max(first-snippetWords/3, 0)
+	to := min(from+snippetWords, len(tokens))
+
+	var sb strings.Builder
+	pos := tokens[from].start
+	for i := from; i < to; i++ {
+		sb.WriteString(html.EscapeString(text[pos:tokens[i].start]))
+		if matched[i] {
+			sb.WriteString("<b>" + html.EscapeString(text[tokens[i].start:tokens[i].end]) + "</b>")
+		} else {
+			sb.WriteString(html.EscapeString(text[tokens[i].start:tokens[i].end]))
+		}
+		pos = tokens[i].end
+	}
+	// Знаки препинания сразу после последнего слова оставляем во фрагменте
+	if to == len(tokens) {
+		sb.WriteString(html.EscapeString(strings.TrimRightFunc(text[pos:], unicode.IsSpace)))
+	} else if r, size := utf8.DecodeRuneInString(text[pos:]); unicode.IsPunct(r) {
+		sb.WriteString(html.EscapeString(text[pos : pos+size]))
+	}
+	return sb.String()
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"go.mood/internal/model"
+	"time"
+)
+
+// SeriesRepository хранит серии повторяющихся задач в памяти.
+type SeriesRepository struct {
+	store *Store
+}
+
+// NewSeriesRepository создает новый экземпляр SeriesRepository.
+func NewSeriesRepository(store *Store) *SeriesRepository {
+	return &SeriesRepository{store: store}
+}
+
+// GetSeriesByIDWithOwner получает серию, только если она принадлежит ownerID.
+func (r *SeriesRepository) GetSeriesByIDWithOwner(id, ownerID int64) (model.TaskSeries, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	series, ok := r.store.series[id]
+	if !ok || series.UserId != ownerID {
+		return model.TaskSeries{}, model.ErrUnknownSeries
+	}
+	return series, nil
+}
+
+// UpdateSeriesByIDWithOwner меняет правило серии ownerID и применяет текст
+// и приоритет ко всем её невыполненным задачам.
+func (r *SeriesRepository) UpdateSeriesByIDWithOwner(id int64, update model.SeriesUpdate, ownerID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	series, ok := r.store.series[id]
+	if !ok || series.UserId != ownerID {
+		return model.ErrUnknownSeries
+	}
+	now := time.Now()
+	if update.RRule != "" {
+		series.RRule = update.RRule
+	}
+	series.UpdatedAt = now
+	r.store.series[id] = series
+
+	for taskID, t := range r.store.tasks {
+		if t.SeriesID == nil || *t.SeriesID != id || !t.Status.Open() {
+			continue
+		}
+		if update.Task != "" {
+			t.Task = update.Task
+		}
+		if update.Priority != "" {
+			t.Priority = update.Priority
+		}
+		t.UpdatedAt = now
+		r.store.tasks[taskID] = t
+	}
+	return nil
+}
+
+// StopSeries останавливает серию ownerID: задачи остаются, но новые повторения не создаются.
+func (r *SeriesRepository) StopSeries(id, ownerID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	series, ok := r.store.series[id]
+	if !ok || series.UserId != ownerID {
+		return model.ErrUnknownSeries
+	}
+	series.Stopped = true
+	series.UpdatedAt = time.Now()
+	r.store.series[id] = series
+	return nil
+}
+
+// insertSeries создаёт серию с первым повторением и возвращает её id.
+// Вызывать под блокировкой на запись.
+func insertSeries(s *Store, userID int64, rrule string) *int64 {
+	s.nextSeriesID++
+	id := s.nextSeriesID
+	now := time.Now()
+	s.series[id] = model.TaskSeries{
+		Id:          int(id),
+		UserId:      userID,
+		RRule:       rrule,
+		Occurrences: 1,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	return &id
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"go.mood/internal/model"
+	"sort"
+	"time"
+)
+
+// SessionRepository хранит сессии пользователей в памяти.
+type SessionRepository struct {
+	store *Store
+}
+
+// NewSessionRepository создает новый экземпляр SessionRepository.
+func NewSessionRepository(store *Store) *SessionRepository {
+	return &SessionRepository{store: store}
+}
+
+// CreateSession сохраняет сессию и первый refresh-токен её цепочки.
+func (r *SessionRepository) CreateSession(s *model.Session, first *model.RefreshToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[s.UserId]; !ok {
+		return model.ErrUnknownUser
+	}
+	r.store.sessions[s.Id] = *s
+	tokens := TokenRepository{store: r.store}
+	tokens.insertRefreshToken(first)
+	return nil
+}
+
+// GetSession получает сессию по id; model.ErrUnknownSession, если её нет.
+func (r *SessionRepository) GetSession(id string) (model.Session, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s, ok := r.store.sessions[id]
+	if !ok {
+		return model.Session{}, model.ErrUnknownSession
+	}
+	return r.withClientName(s), nil
+}
+
+// GetSessionsByUserID получает действующие на момент now сессии пользователя,
+// начиная с последней активной.
+func (r *SessionRepository) GetSessionsByUserID(userID int64, now time.Time) ([]model.Session, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	sessions := []model.Session{}
+	for _, s := range r.store.sessions {
+		if s.UserId == userID && s.Active(now) {
+			sessions = append(sessions, r.withClientName(s))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastSeenAt.Equal(sessions[j].LastSeenAt) {
+			return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+		}
+		return sessions[i].Id < sessions[j].Id
+	})
+	return sessions, nil
+}
+
+// TouchSession запоминает время последнего запроса в сессии.
+func (r *SessionRepository) TouchSession(id string, now time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if s, ok := r.store.sessions[id]; ok {
+		s.LastSeenAt = now
+		r.store.sessions[id] = s
+	}
+	return nil
+}
+
+// withClientName подставляет название приложения OAuth2. Вызывать под блокировкой.
+func (r *SessionRepository) withClientName(s model.Session) model.Session {
+	if client, ok := r.store.oauthClients[s.ClientID]; ok {
+		s.ClientName = client.Name
+	}
+	return s
+}
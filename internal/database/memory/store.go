@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"go.mood/internal/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store — общее хранилище данных в памяти. Пользователи и задачи лежат
+// в одном Store, чтобы удаление пользователя каскадно удаляло его задачи,
+// как это делает ON DELETE CASCADE в PostgreSQL.
+type Store struct {
+	mu sync.RWMutex
+
+	users      map[int64]model.User
+	tasks      map[int64]model.Task
+	tags       map[int64]model.Tag
+	taskTags   map[int64]map[int64]bool // task_id -> множество tag_id
+	projects   map[int64]model.Project
+	series     map[int64]model.TaskSeries
+	nextUserID int64
+	nextTaskID int64
+	nextTagID  int64
+
+	nextProjectID int64
+	nextSeriesID  int64
+
+	refreshTokens      map[int64]model.RefreshToken
+	revokedTokens      map[string]time.Time // jti -> срок действия отозванного access-токена
+	nextRefreshTokenID int64
+
+	userTokens      map[int64]model.UserToken
+	nextUserTokenID int64
+
+	mfa                map[int64]model.MFA // user_id -> настройки TOTP
+	recoveryCodes      map[int64]model.RecoveryCode
+	nextRecoveryCodeID int64
+
+	apiKeys      map[int64]model.APIKey
+	nextAPIKeyID int64
+
+	roles map[string]model.RoleDefinition
+
+	identities     map[int64]model.Identity
+	nextIdentityID int64
+
+	oauthClients    map[string]model.OAuthClient
+	oauthCodes      map[int64]model.OAuthCode
+	nextOAuthCodeID int64
+
+	sessions map[string]model.Session // id (family_id цепочки refresh-токенов) -> сессия
+}
+
+// NewStore создает пустое хранилище.
+func NewStore() *Store {
+	return &Store{
+		users:    make(map[int64]model.User),
+		tasks:    make(map[int64]model.Task),
+		tags:     make(map[int64]model.Tag),
+		taskTags: make(map[int64]map[int64]bool),
+		projects: make(map[int64]model.Project),
+		series:   make(map[int64]model.TaskSeries),
+
+		refreshTokens: make(map[int64]model.RefreshToken),
+		revokedTokens: make(map[string]time.Time),
+		userTokens:    make(map[int64]model.UserToken),
+		mfa:           make(map[int64]model.MFA),
+		recoveryCodes: make(map[int64]model.RecoveryCode),
+		apiKeys:       make(map[int64]model.APIKey),
+		roles:         builtInRoles(),
+		identities:    make(map[int64]model.Identity),
+		oauthClients:  make(map[string]model.OAuthClient),
+		oauthCodes:    make(map[int64]model.OAuthCode),
+		sessions:      make(map[string]model.Session),
+	}
+}
+
+// builtInRoles возвращает встроенные роли, с которыми создаётся хранилище (как после миграции).
+func builtInRoles() map[string]model.RoleDefinition {
+	roles := make(map[string]model.RoleDefinition)
+	now := time.Now()
+	for _, role := range model.BuiltInRoles() {
+		role.CreatedAt = now
+		roles[role.Name] = role
+	}
+	return roles
+}
+
+// setTaskTags заменяет метки задачи, проверяя, что все они принадлежат ownerID.
+// Вызывать под блокировкой на запись.
+func (s *Store) setTaskTags(taskID int64, tagIDs []int64, ownerID int64) error {
+	if err := s.checkTagOwner(tagIDs, ownerID); err != nil {
+		return err
+	}
+	set := make(map[int64]bool, len(tagIDs))
+	for _, id := range tagIDs {
+		set[id] = true
+	}
+	s.taskTags[taskID] = set
+	return nil
+}
+
+// checkTagOwner проверяет, что все метки существуют и принадлежат ownerID.
+// Вызывать под блокировкой.
+func (s *Store) checkTagOwner(tagIDs []int64, ownerID int64) error {
+	for _, id := range tagIDs {
+		if tag, ok := s.tags[id]; !ok || tag.UserId != ownerID {
+			return model.ErrUnknownTag
+		}
+	}
+	return nil
+}
+
+// checkProjectOwner проверяет, что проект существует и принадлежит ownerID;
+// nil означает «без проекта». Вызывать под блокировкой.
+func (s *Store) checkProjectOwner(projectID *int64, ownerID int64) error {
+	if projectID == nil {
+		return nil
+	}
+	if p, ok := s.projects[*projectID]; !ok || p.UserId != ownerID {
+		return model.ErrUnknownProject
+	}
+	return nil
+}
+
+// checkParentOwner проверяет, что родительская задача существует и принадлежит ownerID;
+// nil означает задачу верхнего уровня. Вызывать под блокировкой.
+func (s *Store) checkParentOwner(parentID *int64, ownerID int64) error {
+	if parentID == nil {
+		return nil
+	}
+	if t, ok := s.tasks[*parentID]; !ok || t.UserId != ownerID {
+		return model.ErrUnknownParentTask
+	}
+	return nil
+}
+
+// subtaskIDs возвращает id всех подзадач задачи на любой глубине. Вызывать под блокировкой.
+func (s *Store) subtaskIDs(id int64) []int64 {
+	var ids []int64
+	for taskID, t := range s.tasks {
+		if t.ParentID != nil && *t.ParentID == id {
+			ids = append(ids, taskID)
+			ids = append(ids, s.subtaskIDs(taskID)...)
+		}
+	}
+	return ids
+}
+
+// deleteTask удаляет задачу с её подзадачами и связями с метками,
+// как ON DELETE CASCADE в PostgreSQL. Вызывать под блокировкой на запись.
+func (s *Store) deleteTask(id int64) {
+	for _, subID := range s.subtaskIDs(id) {
+		delete(s.tasks, subID)
+		delete(s.taskTags, subID)
+	}
+	delete(s.tasks, id)
+	delete(s.taskTags, id)
+}
+
+// sortedUsers возвращает пользователей, упорядоченных по id. Вызывать под блокировкой.
+func (s *Store) sortedUsers() []model.User {
+	users := make([]model.User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Id < users[j].Id })
+	return users
+}
+
+// sortedTasks возвращает задачи, подходящие под фильтр, упорядоченные по id.
+// Вызывать под блокировкой.
+func (s *Store) sortedTasks(filter func(t model.Task) bool) []model.Task {
+	var tasks []model.Task
+	for _, t := range s.tasks {
+		if filter == nil || filter(t) {
+			tasks = append(tasks, t)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Id < tasks[j].Id })
+	return tasks
+}
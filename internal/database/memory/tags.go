@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"go.mood/internal/model"
+	"sort"
+	"time"
+)
+
+// TagRepository хранит метки в памяти.
+type TagRepository struct {
+	store *Store
+}
+
+// NewTagRepository создает новый экземпляр TagRepository.
+func NewTagRepository(store *Store) *TagRepository {
+	return &TagRepository{store: store}
+}
+
+// CreateTag создает метку пользователя.
+func (r *TagRepository) CreateTag(tag *model.Tag) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.nameTaken(tag.UserId, tag.Name, 0) {
+		return model.ErrTagNameTaken
+	}
+	r.store.nextTagID++
+	tag.Id = int(r.store.nextTagID)
+	tag.CreatedAt = time.Now()
+	r.store.tags[r.store.nextTagID] = *tag
+	return nil
+}
+
+// GetTagsByUserID получает все метки пользователя, упорядоченные по имени.
+func (r *TagRepository) GetTagsByUserID(userID int64) ([]model.Tag, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var tags []model.Tag
+	for _, tag := range r.store.tags {
+		if tag.UserId == userID {
+			tags = append(tags, tag)
+		}
+	}
+	sortTags(tags)
+	return tags, nil
+}
+
+// GetTagByIDWithOwner получает метку, только если она принадлежит ownerID.
+func (r *TagRepository) GetTagByIDWithOwner(id, ownerID int64) (model.Tag, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tag, ok := r.store.tags[id]
+	if !ok || tag.UserId != ownerID {
+		return model.Tag{}, model.ErrUnknownTag
+	}
+	return tag, nil
+}
+
+// UpdateTagByIDWithOwner меняет имя и цвет метки, если она принадлежит ownerID.
+func (r *TagRepository) UpdateTagByIDWithOwner(id int64, tag *model.Tag, ownerID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.tags[id]
+	if !ok || stored.UserId != ownerID {
+		return model.ErrUnknownTag
+	}
+	if r.nameTaken(ownerID, tag.Name, id) {
+		return model.ErrTagNameTaken
+	}
+	stored.Name = tag.Name
+	stored.Color = tag.Color
+	r.store.tags[id] = stored
+	return nil
+}
+
+// DeleteTagByIDWithOwner удаляет метку, если она принадлежит ownerID,
+// и отвязывает её от всех задач.
+func (r *TagRepository) DeleteTagByIDWithOwner(id, ownerID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.tags[id]
+	if !ok || stored.UserId != ownerID {
+		return model.ErrUnknownTag
+	}
+	delete(r.store.tags, id)
+	for _, set := range r.store.taskTags {
+		delete(set, id)
+	}
+	return nil
+}
+
+// GetTagsByTaskIDs получает метки для списка задач: task_id -> метки.
+func (r *TagRepository) GetTagsByTaskIDs(taskIDs []int64) (map[int64][]model.Tag, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	result := make(map[int64][]model.Tag)
+	for _, taskID := range taskIDs {
+		var tags []model.Tag
+		for tagID := range r.store.taskTags[taskID] {
+			tags = append(tags, r.store.tags[tagID])
+		}
+		if len(tags) > 0 {
+			sortTags(tags)
+			result[taskID] = tags
+		}
+	}
+	return result, nil
+}
+
+// nameTaken сообщает, есть ли у пользователя другая метка с таким именем.
+// Вызывать под блокировкой.
+func (r *TagRepository) nameTaken(userID int64, name string, exceptID int64) bool {
+	for id, tag := range r.store.tags {
+		if tag.UserId == userID && tag.Name == name && id != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func sortTags(tags []model.Tag) {
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+}
@@ -0,0 +1,388 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"go.mood/internal/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TaskRepository хранит задачи в памяти.
+type TaskRepository struct {
+	store *Store
+}
+
+// NewTaskRepository создает новый экземпляр TaskRepository.
+func NewTaskRepository(store *Store) *TaskRepository {
+	return &TaskRepository{store: store}
+}
+
+// CreateTasksInBulk создает список задач атомарно: если владелец хотя бы одной
+// задачи не существует, ни одна задача не сохраняется.
+func (r *TaskRepository) CreateTasksInBulk(tasks []*model.Task) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, task := range tasks {
+		if _, ok := r.store.users[task.UserId]; !ok {
+			return fmt.Errorf("ошибка создания задачи в транзакции: пользователь с id %d не найден", task.UserId)
+		}
+	}
+	for _, task := range tasks {
+		insertTask(r.store, task)
+	}
+	return nil
+}
+
+func (r *TaskRepository) GetAllTasks() ([]model.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.sortedTasks(nil), nil
+}
+
+func (r *TaskRepository) GetTasksByUserID(userID int64) ([]model.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.sortedTasks(func(t model.Task) bool { return t.UserId == userID }), nil
+}
+
+func (r *TaskRepository) GetTaskByID(id int64) (model.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	task, ok := r.store.tasks[id]
+	if !ok {
+		return task, fmt.Errorf("задача с id %d не найдена", id)
+	}
+	return task, nil
+}
+
+// CreateTask создает задачу. Если задан task.Recurrence, создаётся серия повторений
+// и задача становится её первым повторением.
+func (r *TaskRepository) CreateTask(task *model.Task) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[task.UserId]; !ok {
+		return fmt.Errorf("ошибка создания задачи: пользователь с id %d не найден", task.UserId)
+	}
+	if err := r.store.checkTagOwner(task.TagIDs, task.UserId); err != nil {
+		return err
+	}
+	if err := r.store.checkProjectOwner(task.ProjectID, task.UserId); err != nil {
+		return err
+	}
+	if err := r.store.checkParentOwner(task.ParentID, task.UserId); err != nil {
+		return err
+	}
+	if task.Recurrence != "" {
+		task.SeriesID = insertSeries(r.store, task.UserId, task.Recurrence)
+	}
+	insertTask(r.store, task)
+	if task.TagIDs != nil {
+		r.store.setTaskTags(int64(task.Id), task.TagIDs, task.UserId)
+	}
+	return nil
+}
+
+func (r *TaskRepository) UpdateTaskByIDWithOwner(id int64, task *model.Task, ownerID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.tasks[id]
+	if !ok || stored.UserId != ownerID {
+		return fmt.Errorf("задача не найдена или вы не являетесь владельцем")
+	}
+	if task.TagIDs != nil {
+		if err := r.store.setTaskTags(id, task.TagIDs, ownerID); err != nil {
+			return err
+		}
+	}
+	stored.Task = task.Task
+	stored.DueAt = task.DueAt
+	stored.Priority = task.Priority
+	stored.UpdatedAt = time.Now()
+	r.store.tasks[id] = stored
+	return nil
+}
+
+// DeleteTaskByIDWithOwner удаляет задачу ownerID вместе с подзадачами; без cascade
+// задача с подзадачами не удаляется — model.ErrTaskHasSubtasks.
+func (r *TaskRepository) DeleteTaskByIDWithOwner(id int64, ownerID int64, cascade bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.tasks[id]
+	if !ok || stored.UserId != ownerID {
+		return fmt.Errorf("задача не найдена или вы не являетесь владельцем")
+	}
+	if !cascade && len(r.store.subtaskIDs(id)) > 0 {
+		return model.ErrTaskHasSubtasks
+	}
+	r.store.deleteTask(id)
+	return nil
+}
+
+// UpdateTaskStatus меняет статус задачи userID; с cascade — и тех её подзадач на любой глубине,
+// для которых такой переход разрешён. Недопустимый переход самой задачи возвращает
+// model.ErrInvalidTransition; проверка идёт под блокировкой вместе с записью.
+// Если выполняется задача серии, создаётся её следующее повторение.
+func (r *TaskRepository) UpdateTaskStatus(taskID int64, userID int64, status model.TaskStatus, cascade bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.tasks[taskID]
+	if !ok || stored.UserId != userID {
+		return errors.New("задача не найдена или вы не владелец")
+	}
+	if !stored.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s → %s", model.ErrInvalidTransition, stored.Status, status)
+	}
+	ids := []int64{taskID}
+	if cascade {
+		for _, id := range r.store.subtaskIDs(taskID) {
+			if r.store.tasks[id].Status.CanTransitionTo(status) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	now := time.Now()
+	for _, id := range ids {
+		t := r.store.tasks[id]
+		if t.Status != status {
+			t.StatusChangedAt = &now
+		}
+		t.Status = status
+		if status != model.StatusDone {
+			t.CompletedAt = nil
+		} else if t.CompletedAt == nil {
+			t.CompletedAt = &now
+		}
+		t.UpdatedAt = now
+		r.store.tasks[id] = t
+	}
+	if status == model.StatusDone && stored.Status != model.StatusDone && stored.SeriesID != nil && stored.DueAt != nil {
+		r.createNextOccurrence(stored)
+	}
+	return nil
+}
+
+// createNextOccurrence создаёт следующую задачу серии после выполненной задачи done,
+// копируя её текст, приоритет, проект, родителя и метки. Вызывать под блокировкой на запись.
+func (r *TaskRepository) createNextOccurrence(done model.Task) {
+	series := r.store.series[*done.SeriesID]
+	due, ok := series.NextOccurrence(*done.DueAt)
+	if !ok {
+		return
+	}
+	next := model.Task{
+		UserId:    done.UserId,
+		Task:      done.Task,
+		DueAt:     &due,
+		Priority:  done.Priority,
+		ProjectID: done.ProjectID,
+		ParentID:  done.ParentID,
+		SeriesID:  done.SeriesID,
+	}
+	insertTask(r.store, &next)
+	tags := make(map[int64]bool, len(r.store.taskTags[int64(done.Id)]))
+	for tagID := range r.store.taskTags[int64(done.Id)] {
+		tags[tagID] = true
+	}
+	r.store.taskTags[int64(next.Id)] = tags
+
+	series.Occurrences++
+	series.UpdatedAt = time.Now()
+	r.store.series[*done.SeriesID] = series
+}
+
+// GetTasksDueBetween возвращает невыполненные задачи пользователя со сроком в [from, to).
+func (r *TaskRepository) GetTasksDueBetween(userID int64, from, to time.Time) ([]model.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tasks := r.store.sortedTasks(func(t model.Task) bool {
+		return t.UserId == userID && t.Status.Open() && t.DueAt != nil &&
+			!t.DueAt.Before(from) && t.DueAt.Before(to)
+	})
+	sortByDueAt(tasks)
+	return tasks, nil
+}
+
+// GetOverdueTasks возвращает невыполненные задачи пользователя, срок которых раньше now.
+func (r *TaskRepository) GetOverdueTasks(userID int64, now time.Time) ([]model.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tasks := r.store.sortedTasks(func(t model.Task) bool {
+		return t.UserId == userID && t.Status.Open() && t.DueAt != nil && t.DueAt.Before(now)
+	})
+	sortByDueAt(tasks)
+	return tasks, nil
+}
+
+// ListTasks возвращает задачи пользователя по фильтру, отсортированные по
+// (filter.SortBy, id), начиная строго после filter.After.
+func (r *TaskRepository) ListTasks(filter model.TaskFilter) ([]model.Task, error) {
+	if !model.ValidTaskSortField(filter.SortBy) {
+		return nil, fmt.Errorf("недопустимое поле сортировки: %s", filter.SortBy)
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	// after сообщает, идёт ли задача после позиции (key, id) в выбранном порядке.
+	after := func(t model.Task, key string, id int) bool {
+		k := t.SortKey(filter.SortBy)
+		if k == key {
+			if t.Id == id {
+				return false
+			}
+			return t.Id > id != filter.Desc
+		}
+		return k > key != filter.Desc
+	}
+
+	search := strings.ToLower(filter.Search)
+	tasks := r.store.sortedTasks(func(t model.Task) bool {
+		switch {
+		case t.UserId != filter.UserID,
+			filter.Status != "" && t.Status != filter.Status,
+			filter.Priority != "" && t.Priority != filter.Priority,
+			filter.CreatedFrom != nil && t.CreatedAt.Before(*filter.CreatedFrom),
+			filter.CreatedTo != nil && !t.CreatedAt.Before(*filter.CreatedTo),
+			filter.UpdatedFrom != nil && t.UpdatedAt.Before(*filter.UpdatedFrom),
+			filter.UpdatedTo != nil && !t.UpdatedAt.Before(*filter.UpdatedTo),
+			search != "" && !strings.Contains(strings.ToLower(t.Task), search),
+			!r.hasTags(int64(t.Id), filter.UserID, filter.Tags),
+			filter.SeriesID != nil && (t.SeriesID == nil || *t.SeriesID != *filter.SeriesID),
+			filter.ProjectID != nil && (t.ProjectID == nil || *t.ProjectID != *filter.ProjectID),
+			filter.ProjectID == nil && !filter.WithArchive && r.inArchivedProject(t),
+			filter.After != nil && !after(t, filter.After.Value, filter.After.ID):
+			return false
+		}
+		return true
+	})
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return after(tasks[j], tasks[i].SortKey(filter.SortBy), tasks[i].Id)
+	})
+	if filter.Limit > 0 && len(tasks) > filter.Limit {
+		tasks = tasks[:filter.Limit]
+	}
+	return tasks, nil
+}
+
+// MoveTaskToProject переносит задачу ownerID в проект projectID (nil — убрать из проекта).
+func (r *TaskRepository) MoveTaskToProject(taskID, ownerID int64, projectID *int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.tasks[taskID]
+	if !ok || stored.UserId != ownerID || r.store.checkProjectOwner(projectID, ownerID) != nil {
+		return fmt.Errorf("задача или проект не найдены, либо вы не являетесь владельцем")
+	}
+	stored.ProjectID = projectID
+	stored.UpdatedAt = time.Now()
+	r.store.tasks[taskID] = stored
+	return nil
+}
+
+// GetSubtasks возвращает все подзадачи задачи ownerID на любой глубине, упорядоченные по id.
+func (r *TaskRepository) GetSubtasks(taskID, ownerID int64) ([]model.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	ids := make(map[int64]bool)
+	if t, ok := r.store.tasks[taskID]; ok && t.UserId == ownerID {
+		for _, id := range r.store.subtaskIDs(taskID) {
+			ids[id] = true
+		}
+	}
+	return r.store.sortedTasks(func(t model.Task) bool { return ids[int64(t.Id)] }), nil
+}
+
+// GetProgressByTaskIDs считает прогресс по прямым подзадачам: task_id -> прогресс.
+// Задачи без подзадач в результат не попадают.
+func (r *TaskRepository) GetProgressByTaskIDs(taskIDs []int64) (map[int64]model.Progress, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	wanted := make(map[int64]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		wanted[id] = true
+	}
+	total := make(map[int64]int)
+	done := make(map[int64]int)
+	for _, t := range r.store.tasks {
+		if t.ParentID == nil || !wanted[*t.ParentID] {
+			continue
+		}
+		// Отменённые подзадачи в прогрессе не учитываются
+		if t.Status == model.StatusCancelled {
+			continue
+		}
+		total[*t.ParentID]++
+		if t.Status == model.StatusDone {
+			done[*t.ParentID]++
+		}
+	}
+
+	progress := make(map[int64]model.Progress, len(total))
+	for id, n := range total {
+		progress[id] = *model.NewProgress(n, done[id])
+	}
+	return progress, nil
+}
+
+// inArchivedProject сообщает, лежит ли задача в архивном проекте. Вызывать под блокировкой.
+func (r *TaskRepository) inArchivedProject(t model.Task) bool {
+	return t.ProjectID != nil && r.store.projects[*t.ProjectID].Archived
+}
+
+// hasTags сообщает, есть ли у задачи все метки пользователя с именами names.
+// Вызывать под блокировкой.
+func (r *TaskRepository) hasTags(taskID, userID int64, names []string) bool {
+	for _, name := range names {
+		found := false
+		for tagID := range r.store.taskTags[taskID] {
+			if tag := r.store.tags[tagID]; tag.UserId == userID && tag.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sortByDueAt упорядочивает задачи по сроку, сохраняя порядок по id при равенстве.
+func sortByDueAt(tasks []model.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].DueAt.Before(*tasks[j].DueAt) })
+}
+
+// insertTask присваивает задаче id и сохраняет её со значениями по умолчанию,
+// как это делает таблица tasks. Вызывать под блокировкой на запись.
+func insertTask(s *Store, task *model.Task) {
+	s.nextTaskID++
+	task.Id = int(s.nextTaskID)
+
+	now := time.Now()
+	stored := *task
+	stored.Status = model.StatusPending
+	stored.StatusChangedAt = &now
+	stored.TagIDs = nil
+	stored.Recurrence = ""
+	if stored.Priority == "" {
+		stored.Priority = model.PriorityNormal
+	}
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	s.tasks[s.nextTaskID] = stored
+}
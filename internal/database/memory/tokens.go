@@ -0,0 +1,207 @@
+package memory
+
+import (
+	"go.mood/internal/model"
+	"time"
+)
+
+// TokenRepository хранит refresh-токены и отозванные access-токены в памяти.
+type TokenRepository struct {
+	store *Store
+}
+
+// NewTokenRepository создает новый экземпляр TokenRepository.
+func NewTokenRepository(store *Store) *TokenRepository {
+	return &TokenRepository{store: store}
+}
+
+// GetRefreshTokenByHash получает refresh-токен по SHA-256 от его значения.
+func (r *TokenRepository) GetRefreshTokenByHash(hash string) (model.RefreshToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, t := range r.store.refreshTokens {
+		if t.TokenHash == hash {
+			return t, nil
+		}
+	}
+	return model.RefreshToken{}, model.ErrUnknownRefreshToken
+}
+
+// RotateRefreshToken отзывает токен oldID и сохраняет следующий токен цепочки; сессия
+// цепочки продлевается до срока нового токена. Если oldID уже отозван (токен использован повторно), возвращает model.ErrRefreshTokenReused.
+func (r *TokenRepository) RotateRefreshToken(oldID int64, next *model.RefreshToken, now time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	old, ok := r.store.refreshTokens[oldID]
+	if !ok || old.RevokedAt != nil {
+		return model.ErrRefreshTokenReused
+	}
+	old.RevokedAt = &now
+	r.store.refreshTokens[oldID] = old
+	r.insertRefreshToken(next)
+	if s, ok := r.store.sessions[next.FamilyID]; ok {
+		s.LastSeenAt = now
+		s.ExpiresAt = next.ExpiresAt
+		r.store.sessions[next.FamilyID] = s
+	}
+	return nil
+}
+
+// RevokeRefreshTokenFamily отзывает все refresh-токены цепочки и access-токены, выданные
+// вместе с ними, и завершает сессию цепочки.
+func (r *TokenRepository) RevokeRefreshTokenFamily(familyID string, now time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.revokeWhere(func(t model.RefreshToken) bool { return t.FamilyID == familyID }, now)
+	return nil
+}
+
+// RevokeRefreshTokensByUserID отзывает все refresh-токены пользователя и выданные вместе с ними
+// access-токены и завершает все его сессии.
+func (r *TokenRepository) RevokeRefreshTokensByUserID(userID int64, now time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.revokeWhere(func(t model.RefreshToken) bool { return t.UserId == userID }, now)
+	return nil
+}
+
+// RevokeOtherRefreshTokens отзывает refresh-токены пользователя и выданные вместе с ними
+// access-токены и завершает сессии во всех цепочках, кроме keepFamilyID (текущей сессии).
+func (r *TokenRepository) RevokeOtherRefreshTokens(userID int64, keepFamilyID string, now time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.revokeWhere(func(t model.RefreshToken) bool { return t.UserId == userID && t.FamilyID != keepFamilyID }, now)
+	return nil
+}
+
+// GetRefreshTokenFamilyByAccessJTI получает цепочку refresh-токенов, с которой был выдан access-токен jti.
+func (r *TokenRepository) GetRefreshTokenFamilyByAccessJTI(jti string) (string, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, t := range r.store.refreshTokens {
+		if t.AccessJTI == jti {
+			return t.FamilyID, nil
+		}
+	}
+	return "", model.ErrUnknownRefreshToken
+}
+
+// RevokeAccessToken запоминает jti отозванного access-токена до момента expiresAt.
+// Заодно удаляются записи об уже истёкших токенах.
+func (r *TokenRepository) RevokeAccessToken(jti string, expiresAt, now time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, exp := range r.store.revokedTokens {
+		if exp.Before(now) {
+			delete(r.store.revokedTokens, id)
+		}
+	}
+	r.store.revokedTokens[jti] = expiresAt
+	return nil
+}
+
+// IsAccessTokenRevoked сообщает, отозван ли access-токен с данным jti.
+func (r *TokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	_, ok := r.store.revokedTokens[jti]
+	return ok, nil
+}
+
+// CreateUserToken сохраняет одноразовый токен; ранее выданные неиспользованные
+// токены пользователя с тем же назначением становятся недействительными.
+func (r *TokenRepository) CreateUserToken(t *model.UserToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, old := range r.store.userTokens {
+		if old.UserId == t.UserId && old.Purpose == t.Purpose && old.UsedAt == nil {
+			usedAt := t.CreatedAt
+			old.UsedAt = &usedAt
+			r.store.userTokens[id] = old
+		}
+	}
+	r.store.nextUserTokenID++
+	t.Id = r.store.nextUserTokenID
+	r.store.userTokens[t.Id] = *t
+	return nil
+}
+
+// ConsumeUserToken отмечает токен использованным и возвращает его. Неизвестный, чужого
+// назначения, истёкший или уже использованный токен — model.ErrInvalidUserToken.
+func (r *TokenRepository) ConsumeUserToken(hash string, purpose model.TokenPurpose, now time.Time) (model.UserToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	id, t, ok := r.findUserToken(hash, purpose, now)
+	if !ok {
+		return model.UserToken{}, model.ErrInvalidUserToken
+	}
+	t.UsedAt = &now
+	r.store.userTokens[id] = t
+	return t, nil
+}
+
+// GetUserToken возвращает действующий токен, не отмечая его использованным.
+func (r *TokenRepository) GetUserToken(hash string, purpose model.TokenPurpose, now time.Time) (model.UserToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	_, t, ok := r.findUserToken(hash, purpose, now)
+	if !ok {
+		return model.UserToken{}, model.ErrInvalidUserToken
+	}
+	return t, nil
+}
+
+// findUserToken ищет неиспользованный и не истёкший токен назначения purpose.
+// Вызывать под блокировкой.
+func (r *TokenRepository) findUserToken(hash string, purpose model.TokenPurpose, now time.Time) (int64, model.UserToken, bool) {
+	for id, t := range r.store.userTokens {
+		if t.TokenHash != hash {
+			continue
+		}
+		if t.Purpose != purpose || t.UsedAt != nil || !t.ExpiresAt.After(now) {
+			break
+		}
+		return id, t, true
+	}
+	return 0, model.UserToken{}, false
+}
+
+// revokeWhere отзывает подходящие refresh-токены, запоминает jti ещё действующих
+// access-токенов, выданных вместе с ними, и завершает сессии их цепочек.
+// Вызывать под блокировкой на запись.
+func (r *TokenRepository) revokeWhere(match func(t model.RefreshToken) bool, now time.Time) {
+	for id, t := range r.store.refreshTokens {
+		if !match(t) {
+			continue
+		}
+		if t.AccessExpiresAt.After(now) {
+			r.store.revokedTokens[t.AccessJTI] = t.AccessExpiresAt
+		}
+		if t.RevokedAt == nil {
+			t.RevokedAt = &now
+			r.store.refreshTokens[id] = t
+		}
+		if s, ok := r.store.sessions[t.FamilyID]; ok && s.RevokedAt == nil {
+			s.RevokedAt = &now
+			r.store.sessions[t.FamilyID] = s
+		}
+	}
+}
+
+// insertRefreshToken присваивает токену id и сохраняет его. Вызывать под блокировкой на запись.
+func (r *TokenRepository) insertRefreshToken(t *model.RefreshToken) {
+	r.store.nextRefreshTokenID++
+	t.Id = r.store.nextRefreshTokenID
+	r.store.refreshTokens[t.Id] = *t
+}
@@ -0,0 +1,346 @@
+package memory
+
+import (
+	"fmt"
+	"go.mood/internal/model"
+	"strings"
+	"time"
+)
+
+// UserRepository хранит пользователей в памяти.
+type UserRepository struct {
+	store *Store
+}
+
+// NewUserRepository создает новый экземпляр UserRepository.
+func NewUserRepository(store *Store) *UserRepository {
+	return &UserRepository{store: store}
+}
+
+// CreateUserAndInitialTask создает пользователя и его первую задачу атомарно.
+func (r *UserRepository) CreateUserAndInitialTask(user *model.User, task *model.Task) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.insertUser(user); err != nil {
+		return fmt.Errorf("ошибка создания пользователя в транзакции: %w", err)
+	}
+	task.UserId = int64(user.Id)
+	insertTask(r.store, task)
+	return nil
+}
+
+// GetUserByUsername получает пользователя по имени.
+func (r *UserRepository) GetUserByUsername(username string) (model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, u := range r.store.sortedUsers() {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return model.User{}, fmt.Errorf("пользователь не найден")
+}
+
+// CreateUser создает нового пользователя. Занятый email — model.ErrEmailTaken.
+func (r *UserRepository) CreateUser(user *model.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.insertUser(user); err != nil {
+		return fmt.Errorf("ошибка создания пользователя: %w", err)
+	}
+	return nil
+}
+
+// ListUsers возвращает пользователей по фильтру в порядке id, начиная строго после filter.AfterID.
+func (r *UserRepository) ListUsers(filter model.UserFilter) ([]model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	search := strings.ToLower(filter.Search)
+	var users []model.User
+	for _, u := range r.store.sortedUsers() {
+		switch {
+		case u.Id <= filter.AfterID,
+			search != "" && !strings.Contains(strings.ToLower(u.Username), search) && !strings.Contains(strings.ToLower(u.Email), search),
+			filter.Role != "" && u.Role != filter.Role,
+			filter.Suspended != nil && u.Suspended() != *filter.Suspended,
+			filter.CreatedFrom != nil && u.CreateTime.Before(*filter.CreatedFrom),
+			filter.CreatedTo != nil && !u.CreateTime.Before(*filter.CreatedTo):
+			continue
+		}
+		users = append(users, u)
+		if filter.Limit > 0 && len(users) == filter.Limit {
+			break
+		}
+	}
+	return users, nil
+}
+
+// DeleteUserByID удаляет пользователя, все его задачи, метки, проекты, серии, токены, настройки 2FA и API-ключи.
+func (r *UserRepository) DeleteUserByID(id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.users, id)
+	for taskID, t := range r.store.tasks {
+		if t.UserId == id {
+			r.store.deleteTask(taskID)
+		}
+	}
+	for tagID, tag := range r.store.tags {
+		if tag.UserId == id {
+			delete(r.store.tags, tagID)
+		}
+	}
+	for projectID, p := range r.store.projects {
+		if p.UserId == id {
+			delete(r.store.projects, projectID)
+		}
+	}
+	for seriesID, series := range r.store.series {
+		if series.UserId == id {
+			delete(r.store.series, seriesID)
+		}
+	}
+	for tokenID, t := range r.store.refreshTokens {
+		if t.UserId == id {
+			delete(r.store.refreshTokens, tokenID)
+		}
+	}
+	for tokenID, t := range r.store.userTokens {
+		if t.UserId == id {
+			delete(r.store.userTokens, tokenID)
+		}
+	}
+	for sessionID, s := range r.store.sessions {
+		if s.UserId == id {
+			delete(r.store.sessions, sessionID)
+		}
+	}
+	delete(r.store.mfa, id)
+	for codeID, c := range r.store.recoveryCodes {
+		if c.UserId == id {
+			delete(r.store.recoveryCodes, codeID)
+		}
+	}
+	for keyID, key := range r.store.apiKeys {
+		if key.UserId == id {
+			delete(r.store.apiKeys, keyID)
+		}
+	}
+	for identityID, identity := range r.store.identities {
+		if identity.UserId == id {
+			delete(r.store.identities, identityID)
+		}
+	}
+	for codeID, c := range r.store.oauthCodes {
+		if c.UserId == id {
+			delete(r.store.oauthCodes, codeID)
+		}
+	}
+	for clientID, c := range r.store.oauthClients {
+		if c.CreatedBy != nil && *c.CreatedBy == id {
+			c.CreatedBy = nil
+			r.store.oauthClients[clientID] = c
+		}
+	}
+	return nil
+}
+
+// GetUserByID получает пользователя по его ID.
+func (r *UserRepository) GetUserByID(id int64) (model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return user, model.ErrUnknownUser
+	}
+	return user, nil
+}
+
+// GetUserByEmail получает пользователя по email.
+func (r *UserRepository) GetUserByEmail(email string) (model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, u := range r.store.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return model.User{}, fmt.Errorf("пользователь не найден")
+}
+
+// UpdatePassword заменяет хеш пароля пользователя.
+func (r *UserRepository) UpdatePassword(id int64, passwordHash string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return fmt.Errorf("пользователь с id %d не найден", id)
+	}
+	user.PasswordHash = passwordHash
+	r.store.users[id] = user
+	return nil
+}
+
+// MarkEmailVerified отмечает email пользователя подтверждённым, если это ещё не сделано.
+func (r *UserRepository) MarkEmailVerified(id int64, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return fmt.Errorf("пользователь с id %d не найден", id)
+	}
+	if user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &at
+		r.store.users[id] = user
+	}
+	return nil
+}
+
+// IncrementFailedLogins увеличивает счётчик неудачных попыток входа и возвращает новое значение.
+func (r *UserRepository) IncrementFailedLogins(id int64) (int, error) {
+	var count int
+	err := r.updateUser(id, func(user *model.User) {
+		user.FailedLoginCount++
+		count = user.FailedLoginCount
+	})
+	return count, err
+}
+
+// LockUser запрещает вход пользователю до момента until.
+func (r *UserRepository) LockUser(id int64, until time.Time) error {
+	return r.updateUser(id, func(user *model.User) {
+		user.LockedUntil = &until
+	})
+}
+
+// RecordLogin запоминает время успешного входа и сбрасывает счётчик неудачных попыток.
+func (r *UserRepository) RecordLogin(id int64, at time.Time) error {
+	return r.updateUser(id, func(user *model.User) {
+		user.LastLoginAt = &at
+		user.FailedLoginCount = 0
+		user.LockedUntil = nil
+	})
+}
+
+// UnlockUser снимает блокировку входа и сбрасывает счётчик неудачных попыток.
+func (r *UserRepository) UnlockUser(id int64) error {
+	return r.updateUser(id, func(user *model.User) {
+		user.FailedLoginCount = 0
+		user.LockedUntil = nil
+	})
+}
+
+// SuspendUser приостанавливает аккаунт пользователя с момента at.
+func (r *UserRepository) SuspendUser(id int64, at time.Time) error {
+	return r.updateUser(id, func(user *model.User) {
+		user.SuspendedAt = &at
+	})
+}
+
+// ReactivateUser снимает приостановку аккаунта.
+func (r *UserRepository) ReactivateUser(id int64) error {
+	return r.updateUser(id, func(user *model.User) {
+		user.SuspendedAt = nil
+	})
+}
+
+// UpdateRole назначает пользователю роль.
+func (r *UserRepository) UpdateRole(id int64, role string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.roles[role]; !ok {
+		return model.ErrUnknownRole
+	}
+	user, ok := r.store.users[id]
+	if !ok {
+		return fmt.Errorf("пользователь с id %d не найден", id)
+	}
+	user.Role = role
+	r.store.users[id] = user
+	return nil
+}
+
+// UpdateUsername меняет имя пользователя.
+func (r *UserRepository) UpdateUsername(id int64, username string) error {
+	return r.updateUser(id, func(user *model.User) {
+		user.Username = username
+	})
+}
+
+// UpdateEmail меняет email пользователя и отметку о его подтверждении.
+func (r *UserRepository) UpdateEmail(id int64, email string, verifiedAt *time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, u := range r.store.users {
+		if u.Email == email && int64(u.Id) != id {
+			return model.ErrEmailTaken
+		}
+	}
+	user, ok := r.store.users[id]
+	if !ok {
+		return fmt.Errorf("пользователь с id %d не найден", id)
+	}
+	user.Email = email
+	user.EmailVerifiedAt = verifiedAt
+	r.store.users[id] = user
+	return nil
+}
+
+// CountUsersByRole считает пользователей с ролью role.
+func (r *UserRepository) CountUsersByRole(role string) (int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	count := 0
+	for _, u := range r.store.users {
+		if u.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// updateUser изменяет сохранённого пользователя под блокировкой на запись.
+func (r *UserRepository) updateUser(id int64, change func(user *model.User)) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return fmt.Errorf("пользователь с id %d не найден", id)
+	}
+	change(&user)
+	r.store.users[id] = user
+	return nil
+}
+
+// insertUser проверяет уникальность email и сохраняет пользователя.
+// Вызывать под блокировкой на запись.
+func (r *UserRepository) insertUser(user *model.User) error {
+	for _, u := range r.store.users {
+		if u.Email == user.Email {
+			return model.ErrEmailTaken
+		}
+	}
+
+	r.store.nextUserID++
+	user.Id = int(r.store.nextUserID)
+	if user.Role == "" {
+		user.Role = string(model.RoleUser)
+	}
+	stored := *user
+	stored.CreateTime = time.Now()
+	r.store.users[r.store.nextUserID] = stored
+	return nil
+}
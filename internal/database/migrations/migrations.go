@@ -0,0 +1,287 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql/*.sql
+var migrationFiles embed.FS
+
+// lockKey — ключ advisory-блокировки, чтобы два процесса не мигрировали БД одновременно.
+const lockKey int64 = 7305142025
+
+const (
+	createMigrationsTableQuery = `CREATE TABLE IF NOT EXISTS schema_migrations(
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`
+	getAppliedMigrationsQuery = `SELECT version, applied_at FROM schema_migrations ORDER BY version`
+	insertMigrationQuery      = `INSERT INTO schema_migrations (version, name) VALUES($1, $2)`
+	deleteMigrationQuery      = `DELETE FROM schema_migrations WHERE version = $1`
+)
+
+// fileNamePattern — формат имени файла миграции: 0001_name.up.sql / 0001_name.down.sql.
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration — одна версия схемы с SQL для применения и отката.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus — состояние миграции в конкретной БД.
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Migrator применяет и откатывает миграции, учитывая версии в таблице schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator создает новый экземпляр Migrator со встроенными миграциями.
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// loadMigrations читает файлы миграций и сортирует их по версии.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога миграций: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("некорректное имя файла миграции: %s", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("некорректная версия миграции %s: %w", entry.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, "sql/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения миграции %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("у миграции %d разные имена: %s и %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("у миграции %d должны быть up и down файлы", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest возвращает номер последней известной миграции.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up применяет все ещё не применённые миграции.
+func (m *Migrator) Up() error {
+	return m.To(m.Latest())
+}
+
+// Down откатывает последнюю применённую миграцию.
+func (m *Migrator) Down() error {
+	return m.withLock(func(conn *sql.Conn) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				return revert(conn, m.migrations[i])
+			}
+		}
+		return errors.New("нет применённых миграций для отката")
+	})
+}
+
+// To приводит схему к указанной версии: применяет миграции до неё включительно
+// и откатывает все, что новее. Версия 0 означает полный откат.
+func (m *Migrator) To(version int64) error {
+	if version != 0 && !m.known(version) {
+		return fmt.Errorf("миграция с версией %d не найдена", version)
+	}
+
+	return m.withLock(func(conn *sql.Conn) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; ok && mg.Version > version {
+				if err := revert(conn, mg); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; !ok && mg.Version <= version {
+				if err := apply(conn, mg); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status возвращает список всех миграций с отметкой, применены ли они.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(func(conn *sql.Conn) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			st := MigrationStatus{Version: mg.Version, Name: mg.Name}
+			if at, ok := applied[mg.Version]; ok {
+				st.Applied = true
+				st.AppliedAt = &at
+			}
+			statuses = append(statuses, st)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+func (m *Migrator) known(version int64) bool {
+	for _, mg := range m.migrations {
+		if mg.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// withLock выполняет fn на отдельном соединении под advisory-блокировкой.
+// Блокировка освобождается при выходе, даже если fn вернула ошибку.
+func (m *Migrator) withLock(fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка получения соединения: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("ошибка получения блокировки миграций: %w", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTableQuery); err != nil {
+		return fmt.Errorf("ошибка создания таблицы schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func appliedVersions(conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(context.Background(), getAppliedMigrationsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения применённых миграций: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("ошибка чтения миграции из строки: %w", err)
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// apply выполняет up-скрипт и записывает версию в одной транзакции.
+func apply(conn *sql.Conn, mg Migration) error {
+	return inTx(conn, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(mg.Up); err != nil {
+			return fmt.Errorf("ошибка применения миграции %d_%s: %w", mg.Version, mg.Name, err)
+		}
+		if _, err := tx.Exec(insertMigrationQuery, mg.Version, mg.Name); err != nil {
+			return fmt.Errorf("ошибка записи версии %d: %w", mg.Version, err)
+		}
+		return nil
+	})
+}
+
+// revert выполняет down-скрипт и удаляет версию в одной транзакции.
+func revert(conn *sql.Conn, mg Migration) error {
+	return inTx(conn, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(mg.Down); err != nil {
+			return fmt.Errorf("ошибка отката миграции %d_%s: %w", mg.Version, mg.Name, err)
+		}
+		if _, err := tx.Exec(deleteMigrationQuery, mg.Version); err != nil {
+			return fmt.Errorf("ошибка удаления версии %d: %w", mg.Version, err)
+		}
+		return nil
+	})
+}
+
+func inTx(conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS tasks_user_id_due_at_idx;
+
+ALTER TABLE tasks
+	DROP COLUMN completed_at,
+	DROP COLUMN priority,
+	DROP COLUMN due_at;
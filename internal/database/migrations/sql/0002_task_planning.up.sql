@@ -0,0 +1,9 @@
+ALTER TABLE tasks
+	ADD COLUMN due_at TIMESTAMP,
+	ADD COLUMN priority VARCHAR(20) NOT NULL DEFAULT 'normal'
+		CHECK (priority IN ('low', 'normal', 'high', 'urgent')),
+	ADD COLUMN completed_at TIMESTAMP;
+
+UPDATE tasks SET completed_at = updated_at WHERE status = 'true';
+
+CREATE INDEX tasks_user_id_due_at_idx ON tasks (user_id, due_at) WHERE completed_at IS NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE tasks
+	ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (to_tsvector('simple', task)) STORED;
+
+CREATE INDEX tasks_search_vector_idx ON tasks USING GIN (search_vector);
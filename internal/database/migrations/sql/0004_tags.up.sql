@@ -0,0 +1,16 @@
+CREATE TABLE tags(
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(50) NOT NULL,
+	color VARCHAR(7) NOT NULL DEFAULT '#808080',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, name)
+);
+
+CREATE TABLE task_tags(
+	task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	PRIMARY KEY (task_id, tag_id)
+);
+
+CREATE INDEX task_tags_tag_id_idx ON task_tags (tag_id);
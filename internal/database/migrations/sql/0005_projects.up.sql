@@ -0,0 +1,16 @@
+CREATE TABLE projects(
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	color VARCHAR(7) NOT NULL DEFAULT '#808080',
+	archived BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX projects_user_id_idx ON projects (user_id);
+
+ALTER TABLE tasks ADD COLUMN project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL;
+
+CREATE INDEX tasks_project_id_idx ON tasks (project_id);
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN parent_id INTEGER REFERENCES tasks(id) ON DELETE CASCADE;
+
+CREATE INDEX tasks_parent_id_idx ON tasks (parent_id);
@@ -0,0 +1,3 @@
+ALTER TABLE tasks DROP COLUMN series_id;
+
+DROP TABLE IF EXISTS task_series;
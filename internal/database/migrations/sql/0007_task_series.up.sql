@@ -0,0 +1,13 @@
+CREATE TABLE task_series(
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	rrule TEXT NOT NULL,
+	occurrences INTEGER NOT NULL DEFAULT 1,
+	stopped BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE tasks ADD COLUMN series_id INTEGER REFERENCES task_series(id) ON DELETE SET NULL;
+
+CREATE INDEX tasks_series_id_idx ON tasks (series_id);
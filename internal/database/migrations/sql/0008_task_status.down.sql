@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS tasks_user_id_due_at_idx;
+CREATE INDEX tasks_user_id_due_at_idx ON tasks (user_id, due_at) WHERE completed_at IS NULL;
+
+ALTER TABLE tasks
+	DROP COLUMN status_changed_at,
+	DROP CONSTRAINT tasks_status_check,
+	ALTER COLUMN status DROP NOT NULL,
+	ALTER COLUMN status TYPE VARCHAR(50);
@@ -0,0 +1,21 @@
+-- Раньше статус записывался как строка "true"/"false"; приводим к рабочему процессу
+UPDATE tasks SET status = CASE
+	WHEN status = 'true' OR completed_at IS NOT NULL THEN 'done'
+	WHEN status IN ('pending', 'in_progress', 'blocked', 'done', 'cancelled') THEN status
+	ELSE 'pending'
+END;
+
+UPDATE tasks SET completed_at = updated_at WHERE status = 'done' AND completed_at IS NULL;
+UPDATE tasks SET completed_at = NULL WHERE status <> 'done';
+
+ALTER TABLE tasks
+	ALTER COLUMN status TYPE VARCHAR(20),
+	ALTER COLUMN status SET NOT NULL,
+	ADD CONSTRAINT tasks_status_check CHECK (status IN ('pending', 'in_progress', 'blocked', 'done', 'cancelled')),
+	ADD COLUMN status_changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE tasks SET status_changed_at = COALESCE(completed_at, updated_at, created_at);
+
+-- Срочные списки строятся по открытым задачам, отменённые в них не попадают
+DROP INDEX IF EXISTS tasks_user_id_due_at_idx;
+CREATE INDEX tasks_user_id_due_at_idx ON tasks (user_id, due_at) WHERE status IN ('pending', 'in_progress', 'blocked');
@@ -0,0 +1,20 @@
+CREATE TABLE refresh_tokens(
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	family_id VARCHAR(32) NOT NULL,
+	access_jti VARCHAR(32) NOT NULL,
+	access_expires_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+-- Отозванные access-токены хранятся до истечения их срока
+CREATE TABLE revoked_tokens(
+	jti VARCHAR(32) PRIMARY KEY,
+	expires_at TIMESTAMP NOT NULL
+);
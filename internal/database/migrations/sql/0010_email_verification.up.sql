@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Одноразовые токены сброса пароля и подтверждения email; хранится только SHA-256
+CREATE TABLE user_tokens(
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('password_reset', 'email_verify')),
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	used_at TIMESTAMP
+);
+
+CREATE INDEX user_tokens_user_id_purpose_idx ON user_tokens (user_id, purpose);
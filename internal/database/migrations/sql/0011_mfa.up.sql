@@ -0,0 +1,19 @@
+-- Секрет TOTP хранится как есть: он нужен для вычисления кодов.
+-- last_counter — последний принятый шаг времени, защищает от повторного ввода кода.
+CREATE TABLE user_mfa(
+	user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	secret VARCHAR(64) NOT NULL,
+	confirmed_at TIMESTAMP,
+	last_counter BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Одноразовые коды восстановления хранятся в виде bcrypt-хешей
+CREATE TABLE recovery_codes(
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	code_hash TEXT NOT NULL,
+	used_at TIMESTAMP
+);
+
+CREATE INDEX recovery_codes_user_id_idx ON recovery_codes (user_id);
@@ -0,0 +1,16 @@
+-- Персональные API-ключи (pat_...). Хранится SHA-256 от ключа и его видимое начало,
+-- по которому пользователь узнаёт ключ в списке. Пустой scopes — все права пользователя.
+CREATE TABLE api_keys(
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL,
+	prefix VARCHAR(16) NOT NULL,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
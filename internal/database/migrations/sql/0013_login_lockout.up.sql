@@ -0,0 +1,4 @@
+-- Защита входа от перебора: счётчик неудачных попыток подряд и блокировка до locked_until
+ALTER TABLE users ADD COLUMN last_login_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN failed_login_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP;
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+ALTER TABLE users ALTER COLUMN role DROP NOT NULL;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,20 @@
+-- Роли как наборы прав. users.role ссылается на роль; встроенные роли user и admin
+-- повторяют прежнее поведение и не меняются через API
+CREATE TABLE roles(
+	name VARCHAR(50) PRIMARY KEY,
+	description TEXT NOT NULL DEFAULT '',
+	permissions TEXT[] NOT NULL DEFAULT '{}',
+	built_in BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO roles (name, description, permissions, built_in) VALUES
+	('user', 'Обычный пользователь', '{}', TRUE),
+	('admin', 'Администратор', '{users.read,users.delete,users.unlock,roles.read,roles.manage,tasks.read_any}', TRUE);
+
+-- Роли, которые уже встречаются у пользователей, сохраняются без прав
+UPDATE users SET role = 'user' WHERE role IS NULL;
+INSERT INTO roles (name) SELECT DISTINCT role FROM users WHERE role NOT IN (SELECT name FROM roles);
+
+ALTER TABLE users ALTER COLUMN role SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name);
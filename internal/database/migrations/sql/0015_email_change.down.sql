@@ -0,0 +1,5 @@
+DELETE FROM user_tokens WHERE purpose = 'email_change';
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+	CHECK (purpose IN ('password_reset', 'email_verify'));
+ALTER TABLE user_tokens DROP COLUMN IF EXISTS new_email;
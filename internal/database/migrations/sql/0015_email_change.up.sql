@@ -0,0 +1,5 @@
+-- Смена email: новый адрес хранится в одноразовом токене до перехода по ссылке из письма
+ALTER TABLE user_tokens ADD COLUMN new_email VARCHAR(100);
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+	CHECK (purpose IN ('password_reset', 'email_verify', 'email_change'));
@@ -0,0 +1,3 @@
+UPDATE roles SET permissions = array_remove(permissions, 'users.suspend');
+
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
@@ -0,0 +1,6 @@
+-- Приостановка аккаунтов администратором: вход и запросы с токенами и API-ключами
+-- такого пользователя отклоняются, данные сохраняются
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+
+UPDATE roles SET permissions = array_append(permissions, 'users.suspend')
+WHERE name = 'admin' AND NOT 'users.suspend' = ANY(permissions);
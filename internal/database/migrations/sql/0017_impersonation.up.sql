@@ -0,0 +1,3 @@
+-- Право входить от имени пользователя для поддержки
+UPDATE roles SET permissions = array_append(permissions, 'users.impersonate')
+WHERE name = 'admin' AND NOT 'users.impersonate' = ANY(permissions);
@@ -0,0 +1,13 @@
+-- Учётные записи у внешних провайдеров OpenID Connect: пользователь находится по провайдеру и sub
+CREATE TABLE identities(
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	provider VARCHAR(50) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	email VARCHAR(100) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	last_login_at TIMESTAMP,
+	UNIQUE (provider, subject)
+);
+
+CREATE INDEX identities_user_id_idx ON identities (user_id);
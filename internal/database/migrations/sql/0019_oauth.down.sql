@@ -0,0 +1,5 @@
+UPDATE roles SET permissions = array_remove(permissions, 'clients.manage');
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS scopes;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS client_id;
+DROP TABLE IF EXISTS oauth_codes;
+DROP TABLE IF EXISTS oauth_clients;
@@ -0,0 +1,36 @@
+-- Сторонние приложения, которым пользователи дают доступ к своим данным по OAuth2.
+-- secret_hash пуст у публичных клиентов (мобильные и настольные приложения)
+CREATE TABLE oauth_clients(
+	id VARCHAR(32) PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	secret_hash VARCHAR(64) NOT NULL DEFAULT '',
+	redirect_uris TEXT[] NOT NULL,
+	scopes TEXT[] NOT NULL,
+	created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Одноразовые коды авторизации; family_id — цепочка refresh-токенов, выданная в обмен на код,
+-- чтобы отозвать её при повторном предъявлении кода
+CREATE TABLE oauth_codes(
+	id SERIAL PRIMARY KEY,
+	code_hash VARCHAR(64) NOT NULL UNIQUE,
+	client_id VARCHAR(32) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	redirect_uri TEXT NOT NULL,
+	scopes TEXT[] NOT NULL,
+	code_challenge VARCHAR(128) NOT NULL,
+	family_id VARCHAR(32),
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	used_at TIMESTAMP
+);
+
+-- Refresh-токены приложений: кому выданы и с какими правами
+ALTER TABLE refresh_tokens ADD COLUMN client_id VARCHAR(32) REFERENCES oauth_clients(id) ON DELETE CASCADE;
+ALTER TABLE refresh_tokens ADD COLUMN scopes TEXT[] NOT NULL DEFAULT '{}';
+CREATE INDEX refresh_tokens_client_id_idx ON refresh_tokens (client_id);
+
+-- Право регистрировать приложения
+UPDATE roles SET permissions = array_append(permissions, 'clients.manage')
+WHERE name = 'admin' AND NOT 'clients.manage' = ANY(permissions);
@@ -0,0 +1,22 @@
+-- Сессии: вход пользователя на устройстве или доступ приложения OAuth2.
+-- id совпадает с family_id цепочки refresh-токенов и передаётся в access-токенах в claim sid
+CREATE TABLE sessions(
+	id VARCHAR(32) PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	client_id VARCHAR(32) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+	user_agent VARCHAR(512) NOT NULL DEFAULT '',
+	ip VARCHAR(64) NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	last_seen_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+-- Действующие цепочки, начатые до появления сессий, становятся сессиями без сведений об устройстве
+INSERT INTO sessions (id, user_id, client_id, created_at, last_seen_at, expires_at)
+SELECT family_id, MIN(user_id), MIN(client_id), MIN(created_at), MAX(created_at), MAX(expires_at)
+FROM refresh_tokens
+GROUP BY family_id
+HAVING bool_or(revoked_at IS NULL AND expires_at > NOW());
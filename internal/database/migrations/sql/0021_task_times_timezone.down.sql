@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+	ALTER COLUMN due_at TYPE TIMESTAMP USING due_at AT TIME ZONE 'UTC',
+	ALTER COLUMN completed_at TYPE TIMESTAMP;
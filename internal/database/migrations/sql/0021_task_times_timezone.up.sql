@@ -0,0 +1,7 @@
+-- Срок и время выполнения задачи хранятся с часовым поясом: в TIMESTAMP lib/pq записывает
+-- время без смещения, и срок, присланный в UTC, сравнивался бы с местным временем сервера.
+-- due_at приходит от клиентов в RFC 3339 и до этой миграции хранился по часам UTC,
+-- completed_at заполнялся NOW() и хранит время в часовом поясе соединения.
+ALTER TABLE tasks
+	ALTER COLUMN due_at TYPE TIMESTAMPTZ USING due_at AT TIME ZONE 'UTC',
+	ALTER COLUMN completed_at TYPE TIMESTAMPTZ;
@@ -0,0 +1,105 @@
+package queries
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"github.com/lib/pq"
+	"go.mood/internal/model"
+	"time"
+)
+
+//go:embed sql/apikey/create.sql
+var createAPIKeyQuery string
+
+//go:embed sql/apikey/get_by_user_id.sql
+var getAPIKeysByUserIDQuery string
+
+//go:embed sql/apikey/get_by_hash.sql
+var getAPIKeyByHashQuery string
+
+//go:embed sql/apikey/revoke_with_owner.sql
+var revokeAPIKeyWithOwnerQuery string
+
+//go:embed sql/apikey/touch.sql
+var touchAPIKeyQuery string
+
+// APIKeyQueries содержит методы для работы с персональными API-ключами в БД.
+type APIKeyQueries struct {
+	db *sql.DB
+}
+
+// NewAPIKeyQueries создает новый экземпляр APIKeyQueries.
+func NewAPIKeyQueries(db *sql.DB) *APIKeyQueries {
+	return &APIKeyQueries{db: db}
+}
+
+// CreateAPIKey сохраняет новый API-ключ.
+func (q *APIKeyQueries) CreateAPIKey(key *model.APIKey) error {
+	err := q.db.QueryRow(createAPIKeyQuery, key.UserId, key.Name, key.Prefix, key.TokenHash,
+		pq.Array(model.ScopeStrings(key.Scopes)), key.ExpiresAt, key.CreatedAt).Scan(&key.Id)
+	if err != nil {
+		return fmt.Errorf("ошибка создания API-ключа: %v", err)
+	}
+	return nil
+}
+
+// GetAPIKeysByUserID получает неотозванные API-ключи пользователя.
+func (q *APIKeyQueries) GetAPIKeysByUserID(userID int64) ([]model.APIKey, error) {
+	rows, err := q.db.Query(getAPIKeysByUserIDQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения API-ключей: %v", err)
+	}
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+	for rows.Next() {
+		var key model.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, fmt.Errorf("ошибка чтения API-ключа: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// GetAPIKeyByHash получает API-ключ по SHA-256 от его значения.
+func (q *APIKeyQueries) GetAPIKeyByHash(hash string) (model.APIKey, error) {
+	var key model.APIKey
+	if err := scanAPIKey(q.db.QueryRow(getAPIKeyByHashQuery, hash), &key); err != nil {
+		if err == sql.ErrNoRows {
+			return key, model.ErrUnknownAPIKey
+		}
+		return key, fmt.Errorf("ошибка получения API-ключа: %v", err)
+	}
+	return key, nil
+}
+
+// RevokeAPIKeyWithOwner отзывает API-ключ, если он принадлежит ownerID.
+func (q *APIKeyQueries) RevokeAPIKeyWithOwner(id, ownerID int64, now time.Time) error {
+	res, err := q.db.Exec(revokeAPIKeyWithOwnerQuery, id, ownerID, now)
+	if err != nil {
+		return fmt.Errorf("ошибка отзыва API-ключа: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrUnknownAPIKey
+	}
+	return nil
+}
+
+// TouchAPIKey запоминает время использования ключа. Чтобы не писать в БД
+// на каждый запрос, время обновляется не чаще раза в минуту.
+func (q *APIKeyQueries) TouchAPIKey(id int64, now time.Time) error {
+	if _, err := q.db.Exec(touchAPIKeyQuery, id, now); err != nil {
+		return fmt.Errorf("ошибка обновления API-ключа: %v", err)
+	}
+	return nil
+}
+
+func scanAPIKey(row rowScanner, key *model.APIKey) error {
+	var scopes []string
+	err := row.Scan(&key.Id, &key.UserId, &key.Name, &key.Prefix, &key.TokenHash, pq.Array(&scopes),
+		&key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt, &key.RevokedAt)
+	key.Scopes = model.ParseScopes(scopes)
+	return err
+}
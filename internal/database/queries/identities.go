@@ -0,0 +1,90 @@
+package queries
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"go.mood/internal/model"
+	"time"
+)
+
+//go:embed sql/identity/get.sql
+var getIdentityQuery string
+
+//go:embed sql/identity/create.sql
+var createIdentityQuery string
+
+//go:embed sql/identity/touch.sql
+var touchIdentityQuery string
+
+// IdentityQueries содержит методы для работы с учётными записями внешних провайдеров в БД.
+type IdentityQueries struct {
+	db *sql.DB
+}
+
+// NewIdentityQueries создает новый экземпляр IdentityQueries.
+func NewIdentityQueries(db *sql.DB) *IdentityQueries {
+	return &IdentityQueries{db: db}
+}
+
+// GetIdentity получает учётную запись по провайдеру и sub; model.ErrUnknownIdentity, если её нет.
+func (q *IdentityQueries) GetIdentity(provider, subject string) (model.Identity, error) {
+	var identity model.Identity
+	err := q.db.QueryRow(getIdentityQuery, provider, subject).Scan(&identity.Id, &identity.UserId, &identity.Provider,
+		&identity.Subject, &identity.Email, &identity.CreatedAt, &identity.LastLoginAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return identity, model.ErrUnknownIdentity
+		}
+		return identity, fmt.Errorf("ошибка получения внешней учётной записи: %v", err)
+	}
+	return identity, nil
+}
+
+// CreateIdentity привязывает учётную запись провайдера к identity.UserId.
+func (q *IdentityQueries) CreateIdentity(identity *model.Identity) error {
+	err := q.db.QueryRow(createIdentityQuery, identity.UserId, identity.Provider, identity.Subject, identity.Email).
+		Scan(&identity.Id, &identity.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrIdentityLinked
+		}
+		return fmt.Errorf("ошибка привязки внешней учётной записи: %v", err)
+	}
+	return nil
+}
+
+// CreateUserWithIdentity в одной транзакции создаёт пользователя и привязывает к нему учётную запись провайдера.
+func (q *IdentityQueries) CreateUserWithIdentity(user *model.User, identity *model.Identity) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow(createUserQuery, user.Username, user.Email, user.PasswordHash, user.Role).Scan(&user.Id); err != nil {
+		return fmt.Errorf("ошибка создания пользователя в транзакции: %w", err)
+	}
+	identity.UserId = int64(user.Id)
+	err = tx.QueryRow(createIdentityQuery, identity.UserId, identity.Provider, identity.Subject, identity.Email).
+		Scan(&identity.Id, &identity.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrIdentityLinked
+		}
+		return fmt.Errorf("ошибка привязки внешней учётной записи в транзакции: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// TouchIdentity запоминает время входа через учётную запись провайдера.
+func (q *IdentityQueries) TouchIdentity(id int64, at time.Time) error {
+	if _, err := q.db.Exec(touchIdentityQuery, id, at); err != nil {
+		return fmt.Errorf("ошибка обновления внешней учётной записи: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package queries
+
+import (
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"go.mood/internal/model"
+	"time"
+)
+
+//go:embed sql/mfa/get.sql
+var getMFAQuery string
+
+//go:embed sql/mfa/save_secret.sql
+var saveMFASecretQuery string
+
+//go:embed sql/mfa/confirm.sql
+var confirmMFAQuery string
+
+//go:embed sql/mfa/use_counter.sql
+var useTOTPCounterQuery string
+
+//go:embed sql/mfa/delete.sql
+var deleteMFAQuery string
+
+//go:embed sql/mfa/create_recovery_code.sql
+var createRecoveryCodeQuery string
+
+//go:embed sql/mfa/get_recovery_codes.sql
+var getRecoveryCodesQuery string
+
+//go:embed sql/mfa/use_recovery_code.sql
+var useRecoveryCodeQuery string
+
+//go:embed sql/mfa/delete_recovery_codes.sql
+var deleteRecoveryCodesQuery string
+
+// MFAQueries содержит методы для работы с TOTP и кодами восстановления в БД.
+type MFAQueries struct {
+	db *sql.DB
+}
+
+// NewMFAQueries создает новый экземпляр MFAQueries.
+func NewMFAQueries(db *sql.DB) *MFAQueries {
+	return &MFAQueries{db: db}
+}
+
+// GetMFA получает настройки TOTP пользователя; model.ErrMFANotEnrolled, если их нет.
+func (q *MFAQueries) GetMFA(userID int64) (model.MFA, error) {
+	var m model.MFA
+	err := q.db.QueryRow(getMFAQuery, userID).Scan(&m.UserId, &m.Secret, &m.ConfirmedAt, &m.LastCounter)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return m, model.ErrMFANotEnrolled
+		}
+		return m, fmt.Errorf("ошибка получения настроек 2FA: %v", err)
+	}
+	return m, nil
+}
+
+// SaveMFASecret сохраняет новый неподтверждённый секрет. Подтверждённый секрет не меняется.
+func (q *MFAQueries) SaveMFASecret(userID int64, secret string) error {
+	if _, err := q.db.Exec(saveMFASecretQuery, userID, secret); err != nil {
+		return fmt.Errorf("ошибка сохранения секрета 2FA: %v", err)
+	}
+	return nil
+}
+
+// ConfirmMFA включает TOTP, запоминает шаг подтверждающего кода и сохраняет коды восстановления.
+func (q *MFAQueries) ConfirmMFA(userID, counter int64, codeHashes []string, at time.Time) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(confirmMFAQuery, userID, counter, at)
+	if err != nil {
+		return fmt.Errorf("ошибка подтверждения 2FA: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("2FA уже подтверждена или не подключалась")
+	}
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// UseTOTPCounter запоминает шаг принятого кода. false — код этого или более
+// позднего шага уже использовался.
+func (q *MFAQueries) UseTOTPCounter(userID, counter int64) (bool, error) {
+	res, err := q.db.Exec(useTOTPCounterQuery, userID, counter)
+	if err != nil {
+		return false, fmt.Errorf("ошибка сохранения шага TOTP: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GetRecoveryCodes получает неиспользованные коды восстановления пользователя.
+func (q *MFAQueries) GetRecoveryCodes(userID int64) ([]model.RecoveryCode, error) {
+	rows, err := q.db.Query(getRecoveryCodesQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения кодов восстановления: %v", err)
+	}
+	defer rows.Close()
+
+	var codes []model.RecoveryCode
+	for rows.Next() {
+		var c model.RecoveryCode
+		if err := rows.Scan(&c.Id, &c.UserId, &c.CodeHash, &c.UsedAt); err != nil {
+			return nil, fmt.Errorf("ошибка чтения кода восстановления: %v", err)
+		}
+		codes = append(codes, c)
+	}
+	return codes, rows.Err()
+}
+
+// UseRecoveryCode отмечает код использованным. false — код уже был использован.
+func (q *MFAQueries) UseRecoveryCode(id int64, at time.Time) (bool, error) {
+	res, err := q.db.Exec(useRecoveryCodeQuery, id, at)
+	if err != nil {
+		return false, fmt.Errorf("ошибка использования кода восстановления: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ReplaceRecoveryCodes заменяет все коды восстановления пользователя новыми.
+func (q *MFAQueries) ReplaceRecoveryCodes(userID int64, codeHashes []string) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// DeleteMFA отключает TOTP и удаляет коды восстановления пользователя.
+func (q *MFAQueries) DeleteMFA(userID int64) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(deleteRecoveryCodesQuery, userID); err != nil {
+		return fmt.Errorf("ошибка удаления кодов восстановления: %v", err)
+	}
+	if _, err := tx.Exec(deleteMFAQuery, userID); err != nil {
+		return fmt.Errorf("ошибка отключения 2FA: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int64, codeHashes []string) error {
+	if _, err := tx.Exec(deleteRecoveryCodesQuery, userID); err != nil {
+		return fmt.Errorf("ошибка удаления кодов восстановления: %v", err)
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(createRecoveryCodeQuery, userID, hash); err != nil {
+			return fmt.Errorf("ошибка создания кода восстановления: %v", err)
+		}
+	}
+	return nil
+}
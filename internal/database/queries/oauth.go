@@ -0,0 +1,154 @@
+package queries
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"github.com/lib/pq"
+	"go.mood/internal/model"
+	"time"
+)
+
+//go:embed sql/oauth/create_client.sql
+var createOAuthClientQuery string
+
+//go:embed sql/oauth/get_clients.sql
+var getOAuthClientsQuery string
+
+//go:embed sql/oauth/get_client.sql
+var getOAuthClientQuery string
+
+//go:embed sql/oauth/delete_client.sql
+var deleteOAuthClientQuery string
+
+//go:embed sql/oauth/revoke_client_access.sql
+var revokeClientAccessTokensQuery string
+
+//go:embed sql/oauth/create_code.sql
+var createOAuthCodeQuery string
+
+//go:embed sql/oauth/consume_code.sql
+var consumeOAuthCodeQuery string
+
+//go:embed sql/oauth/get_used_code_family.sql
+var getUsedOAuthCodeFamilyQuery string
+
+// OAuthQueries содержит методы для работы с приложениями OAuth2 и кодами авторизации в БД.
+type OAuthQueries struct {
+	db *sql.DB
+}
+
+// NewOAuthQueries создает новый экземпляр OAuthQueries.
+func NewOAuthQueries(db *sql.DB) *OAuthQueries {
+	return &OAuthQueries{db: db}
+}
+
+// CreateClient сохраняет новое приложение.
+func (q *OAuthQueries) CreateClient(c *model.OAuthClient) error {
+	_, err := q.db.Exec(createOAuthClientQuery, c.Id, c.Name, c.SecretHash, pq.Array(c.RedirectURIs),
+		pq.Array(model.ScopeStrings(c.Scopes)), c.CreatedBy, c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания приложения: %v", err)
+	}
+	return nil
+}
+
+// GetClients получает все приложения в порядке регистрации.
+func (q *OAuthQueries) GetClients() ([]model.OAuthClient, error) {
+	rows, err := q.db.Query(getOAuthClientsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения приложений: %v", err)
+	}
+	defer rows.Close()
+
+	clients := []model.OAuthClient{}
+	for rows.Next() {
+		var c model.OAuthClient
+		if err := scanOAuthClient(rows, &c); err != nil {
+			return nil, fmt.Errorf("ошибка чтения приложения: %v", err)
+		}
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+// GetClient получает приложение по client_id; model.ErrUnknownOAuthClient, если его нет.
+func (q *OAuthQueries) GetClient(id string) (model.OAuthClient, error) {
+	var c model.OAuthClient
+	if err := scanOAuthClient(q.db.QueryRow(getOAuthClientQuery, id), &c); err != nil {
+		if err == sql.ErrNoRows {
+			return c, model.ErrUnknownOAuthClient
+		}
+		return c, fmt.Errorf("ошибка получения приложения: %v", err)
+	}
+	return c, nil
+}
+
+// DeleteClient удаляет приложение вместе с его кодами и refresh-токенами и отзывает
+// ещё действующие access-токены, выданные приложению.
+func (q *OAuthQueries) DeleteClient(id string, now time.Time) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(revokeClientAccessTokensQuery, id, now); err != nil {
+		return fmt.Errorf("ошибка отзыва access-токенов приложения: %v", err)
+	}
+	res, err := tx.Exec(deleteOAuthClientQuery, id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления приложения: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrUnknownOAuthClient
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// CreateCode сохраняет код авторизации.
+func (q *OAuthQueries) CreateCode(c *model.OAuthCode) error {
+	err := q.db.QueryRow(createOAuthCodeQuery, c.CodeHash, c.ClientID, c.UserId, c.RedirectURI,
+		pq.Array(model.ScopeStrings(c.Scopes)), c.CodeChallenge, c.ExpiresAt, c.CreatedAt).Scan(&c.Id)
+	if err != nil {
+		return fmt.Errorf("ошибка создания кода авторизации: %v", err)
+	}
+	return nil
+}
+
+// ConsumeCode отмечает код использованным и запоминает familyID — цепочку refresh-токенов,
+// выданную в обмен на него. Неизвестный код — model.ErrInvalidOAuthCode; уже использованный —
+// model.ErrOAuthCodeReused вместе с кодом, в котором заполнен FamilyID прошлого обмена.
+func (q *OAuthQueries) ConsumeCode(hash, familyID string, now time.Time) (model.OAuthCode, error) {
+	var c model.OAuthCode
+	var scopes []string
+	err := q.db.QueryRow(consumeOAuthCodeQuery, hash, familyID, now).Scan(&c.Id, &c.CodeHash, &c.ClientID, &c.UserId,
+		&c.RedirectURI, pq.Array(&scopes), &c.CodeChallenge, &c.FamilyID, &c.ExpiresAt, &c.CreatedAt, &c.UsedAt)
+	c.Scopes = model.ParseScopes(scopes)
+	if err == nil {
+		return c, nil
+	}
+	if err != sql.ErrNoRows {
+		return c, fmt.Errorf("ошибка использования кода авторизации: %v", err)
+	}
+
+	var usedFamilyID string
+	if err := q.db.QueryRow(getUsedOAuthCodeFamilyQuery, hash).Scan(&usedFamilyID); err != nil {
+		if err == sql.ErrNoRows {
+			return model.OAuthCode{}, model.ErrInvalidOAuthCode
+		}
+		return model.OAuthCode{}, fmt.Errorf("ошибка получения кода авторизации: %v", err)
+	}
+	return model.OAuthCode{FamilyID: usedFamilyID}, model.ErrOAuthCodeReused
+}
+
+func scanOAuthClient(row rowScanner, c *model.OAuthClient) error {
+	var scopes []string
+	err := row.Scan(&c.Id, &c.Name, &c.SecretHash, pq.Array(&c.RedirectURIs), pq.Array(&scopes), &c.CreatedBy, &c.CreatedAt)
+	c.Scopes = model.ParseScopes(scopes)
+	return err
+}
@@ -0,0 +1,120 @@
+package queries
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"go.mood/internal/model"
+)
+
+//go:embed sql/project/create.sql
+var createProjectQuery string
+
+//go:embed sql/project/get_by_user_id.sql
+var getProjectsByUserIDQuery string
+
+//go:embed sql/project/get_by_id_with_owner.sql
+var getProjectByIDWithOwnerQuery string
+
+//go:embed sql/project/update_by_id_with_owner.sql
+var updateProjectByIDWithOwnerQuery string
+
+//go:embed sql/project/set_archived.sql
+var setProjectArchivedQuery string
+
+//go:embed sql/project/delete_by_id_with_owner.sql
+var deleteProjectByIDWithOwnerQuery string
+
+// ProjectQueries содержит методы для работы с проектами в БД.
+type ProjectQueries struct {
+	db *sql.DB
+}
+
+// NewProjectQueries создает новый экземпляр ProjectQueries.
+func NewProjectQueries(db *sql.DB) *ProjectQueries {
+	return &ProjectQueries{db: db}
+}
+
+// CreateProject создает проект пользователя.
+func (q *ProjectQueries) CreateProject(p *model.Project) error {
+	row := q.db.QueryRow(createProjectQuery, p.UserId, p.Name, p.Description, p.Color)
+	if err := row.Scan(&p.Id, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return fmt.Errorf("ошибка создания проекта: %v", err)
+	}
+	return nil
+}
+
+// GetProjectsByUserID получает проекты пользователя; архивные — только если withArchived.
+func (q *ProjectQueries) GetProjectsByUserID(userID int64, withArchived bool) ([]model.Project, error) {
+	rows, err := q.db.Query(getProjectsByUserIDQuery, userID, withArchived)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения проектов: %v", err)
+	}
+	defer rows.Close()
+
+	var projects []model.Project
+	for rows.Next() {
+		var p model.Project
+		if err := scanProject(rows, &p); err != nil {
+			return nil, fmt.Errorf("ошибка чтения проекта из строки: %v", err)
+		}
+		projects = append(projects, p)
+	}
+	return projects, nil
+}
+
+// GetProjectByIDWithOwner получает проект, только если он принадлежит ownerID.
+func (q *ProjectQueries) GetProjectByIDWithOwner(id, ownerID int64) (model.Project, error) {
+	var p model.Project
+	if err := scanProject(q.db.QueryRow(getProjectByIDWithOwnerQuery, id, ownerID), &p); err != nil {
+		if err == sql.ErrNoRows {
+			return p, model.ErrUnknownProject
+		}
+		return p, fmt.Errorf("ошибка получения проекта: %v", err)
+	}
+	return p, nil
+}
+
+// UpdateProjectByIDWithOwner меняет имя, описание и цвет проекта, если он принадлежит ownerID.
+func (q *ProjectQueries) UpdateProjectByIDWithOwner(id int64, p *model.Project, ownerID int64) error {
+	res, err := q.db.Exec(updateProjectByIDWithOwnerQuery, p.Name, p.Description, p.Color, id, ownerID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления проекта: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return model.ErrUnknownProject
+	}
+	return nil
+}
+
+// SetProjectArchived архивирует или возвращает из архива проект ownerID.
+func (q *ProjectQueries) SetProjectArchived(id, ownerID int64, archived bool) error {
+	res, err := q.db.Exec(setProjectArchivedQuery, archived, id, ownerID)
+	if err != nil {
+		return fmt.Errorf("ошибка архивации проекта: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return model.ErrUnknownProject
+	}
+	return nil
+}
+
+// DeleteProjectByIDWithOwner удаляет проект ownerID. Задачи проекта остаются
+// без проекта (ON DELETE SET NULL в tasks.project_id).
+func (q *ProjectQueries) DeleteProjectByIDWithOwner(id, ownerID int64) error {
+	res, err := q.db.Exec(deleteProjectByIDWithOwnerQuery, id, ownerID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления проекта: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return model.ErrUnknownProject
+	}
+	return nil
+}
+
+func scanProject(row rowScanner, p *model.Project) error {
+	return row.Scan(&p.Id, &p.UserId, &p.Name, &p.Description, &p.Color, &p.Archived, &p.CreatedAt, &p.UpdatedAt)
+}
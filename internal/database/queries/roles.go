@@ -0,0 +1,134 @@
+package queries
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"github.com/lib/pq"
+	"go.mood/internal/model"
+)
+
+//go:embed sql/role/get_all.sql
+var getAllRolesQuery string
+
+//go:embed sql/role/get_by_name.sql
+var getRoleByNameQuery string
+
+//go:embed sql/role/create.sql
+var createRoleQuery string
+
+//go:embed sql/role/update.sql
+var updateRoleQuery string
+
+//go:embed sql/role/delete.sql
+var deleteRoleQuery string
+
+//go:embed sql/role/get_user_permissions.sql
+var getUserPermissionsQuery string
+
+// RoleQueries содержит методы для работы с ролями в БД.
+type RoleQueries struct {
+	db *sql.DB
+}
+
+// NewRoleQueries создает новый экземпляр RoleQueries.
+func NewRoleQueries(db *sql.DB) *RoleQueries {
+	return &RoleQueries{db: db}
+}
+
+// GetRoles получает все роли: сначала встроенные, затем остальные по имени.
+func (q *RoleQueries) GetRoles() ([]model.RoleDefinition, error) {
+	rows, err := q.db.Query(getAllRolesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения ролей: %v", err)
+	}
+	defer rows.Close()
+
+	roles := []model.RoleDefinition{}
+	for rows.Next() {
+		var role model.RoleDefinition
+		if err := scanRole(rows, &role); err != nil {
+			return nil, fmt.Errorf("ошибка чтения роли: %v", err)
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// GetRole получает роль по имени.
+func (q *RoleQueries) GetRole(name string) (model.RoleDefinition, error) {
+	var role model.RoleDefinition
+	if err := scanRole(q.db.QueryRow(getRoleByNameQuery, name), &role); err != nil {
+		if err == sql.ErrNoRows {
+			return role, model.ErrUnknownRole
+		}
+		return role, fmt.Errorf("ошибка получения роли: %v", err)
+	}
+	return role, nil
+}
+
+// CreateRole сохраняет новую роль; занятое имя — model.ErrRoleExists.
+func (q *RoleQueries) CreateRole(role *model.RoleDefinition) error {
+	err := q.db.QueryRow(createRoleQuery, role.Name, role.Description,
+		pq.Array(model.PermissionStrings(role.Permissions))).Scan(&role.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return model.ErrRoleExists
+		}
+		return fmt.Errorf("ошибка создания роли: %v", err)
+	}
+	role.BuiltIn = false
+	return nil
+}
+
+// UpdateRole заменяет описание и права роли. Встроенные роли не меняются.
+func (q *RoleQueries) UpdateRole(name, description string, permissions []model.Permission) error {
+	res, err := q.db.Exec(updateRoleQuery, name, description, pq.Array(model.PermissionStrings(permissions)))
+	if err != nil {
+		return fmt.Errorf("ошибка изменения роли: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrUnknownRole
+	}
+	return nil
+}
+
+// DeleteRole удаляет роль, если она не встроенная и не назначена пользователям.
+func (q *RoleQueries) DeleteRole(name string) error {
+	res, err := q.db.Exec(deleteRoleQuery, name)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления роли: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		role, err := q.GetRole(name)
+		if err != nil {
+			return err
+		}
+		if role.BuiltIn {
+			return model.ErrUnknownRole
+		}
+		return model.ErrRoleInUse
+	}
+	return nil
+}
+
+// GetUserPermissions получает права текущей роли пользователя.
+func (q *RoleQueries) GetUserPermissions(userID int64) ([]model.Permission, error) {
+	var perms []string
+	if err := q.db.QueryRow(getUserPermissionsQuery, userID).Scan(pq.Array(&perms)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("пользователь с id %d не найден", userID)
+		}
+		return nil, fmt.Errorf("ошибка получения прав пользователя: %v", err)
+	}
+	return model.ParsePermissions(perms), nil
+}
+
+func scanRole(row rowScanner, role *model.RoleDefinition) error {
+	var perms []string
+	if err := row.Scan(&role.Name, &role.Description, pq.Array(&perms), &role.BuiltIn, &role.CreatedAt); err != nil {
+		return err
+	}
+	role.Permissions = model.ParsePermissions(perms)
+	return nil
+}
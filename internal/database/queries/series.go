@@ -0,0 +1,94 @@
+package queries
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"go.mood/internal/model"
+)
+
+//go:embed sql/series/create.sql
+var createSeriesQuery string
+
+//go:embed sql/series/get_by_id_with_owner.sql
+var getSeriesByIDWithOwnerQuery string
+
+//go:embed sql/series/get_for_update.sql
+var getSeriesForUpdateQuery string
+
+//go:embed sql/series/increment_occurrences.sql
+var incrementSeriesOccurrencesQuery string
+
+//go:embed sql/series/update_by_id_with_owner.sql
+var updateSeriesByIDWithOwnerQuery string
+
+//go:embed sql/series/update_pending_tasks.sql
+var updateSeriesPendingTasksQuery string
+
+//go:embed sql/series/stop.sql
+var stopSeriesQuery string
+
+// SeriesQueries содержит методы для работы с сериями повторяющихся задач в БД.
+type SeriesQueries struct {
+	db *sql.DB
+}
+
+// NewSeriesQueries создает новый экземпляр SeriesQueries.
+func NewSeriesQueries(db *sql.DB) *SeriesQueries {
+	return &SeriesQueries{db: db}
+}
+
+// GetSeriesByIDWithOwner получает серию, только если она принадлежит ownerID.
+func (q *SeriesQueries) GetSeriesByIDWithOwner(id, ownerID int64) (model.TaskSeries, error) {
+	var s model.TaskSeries
+	if err := scanSeries(q.db.QueryRow(getSeriesByIDWithOwnerQuery, id, ownerID), &s); err != nil {
+		if err == sql.ErrNoRows {
+			return s, model.ErrUnknownSeries
+		}
+		return s, fmt.Errorf("ошибка получения серии: %v", err)
+	}
+	return s, nil
+}
+
+// UpdateSeriesByIDWithOwner меняет правило серии ownerID и в той же транзакции
+// применяет текст и приоритет ко всем её невыполненным задачам.
+func (q *SeriesQueries) UpdateSeriesByIDWithOwner(id int64, update model.SeriesUpdate, ownerID int64) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(updateSeriesByIDWithOwnerQuery, update.RRule, id, ownerID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления серии: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrUnknownSeries
+	}
+	if _, err := tx.Exec(updateSeriesPendingTasksQuery, update.Task, update.Priority, id, ownerID); err != nil {
+		return fmt.Errorf("ошибка обновления задач серии: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// StopSeries останавливает серию ownerID: задачи остаются, но новые повторения не создаются.
+func (q *SeriesQueries) StopSeries(id, ownerID int64) error {
+	res, err := q.db.Exec(stopSeriesQuery, id, ownerID)
+	if err != nil {
+		return fmt.Errorf("ошибка остановки серии: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return model.ErrUnknownSeries
+	}
+	return nil
+}
+
+func scanSeries(row rowScanner, s *model.TaskSeries) error {
+	return row.Scan(&s.Id, &s.UserId, &s.RRule, &s.Occurrences, &s.Stopped, &s.CreatedAt, &s.UpdatedAt)
+}
@@ -0,0 +1,98 @@
+package queries
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"go.mood/internal/model"
+	"time"
+)
+
+//go:embed sql/session/create.sql
+var createSessionQuery string
+
+//go:embed sql/session/get.sql
+var getSessionQuery string
+
+//go:embed sql/session/get_by_user_id.sql
+var getSessionsByUserIDQuery string
+
+//go:embed sql/session/touch.sql
+var touchSessionQuery string
+
+// SessionQueries содержит методы для работы с сессиями пользователей в БД.
+type SessionQueries struct {
+	db *sql.DB
+}
+
+// NewSessionQueries создает новый экземпляр SessionQueries.
+func NewSessionQueries(db *sql.DB) *SessionQueries {
+	return &SessionQueries{db: db}
+}
+
+// CreateSession в одной транзакции сохраняет сессию и первый refresh-токен её цепочки.
+func (q *SessionQueries) CreateSession(s *model.Session, first *model.RefreshToken) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(createSessionQuery, s.Id, s.UserId, s.ClientID, s.UserAgent, s.IP, s.CreatedAt, s.LastSeenAt, s.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания сессии: %v", err)
+	}
+	if err := insertRefreshToken(tx, first); err != nil {
+		return fmt.Errorf("ошибка создания refresh-токена: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// GetSession получает сессию по id; model.ErrUnknownSession, если её нет.
+func (q *SessionQueries) GetSession(id string) (model.Session, error) {
+	var s model.Session
+	if err := scanSession(q.db.QueryRow(getSessionQuery, id), &s); err != nil {
+		if err == sql.ErrNoRows {
+			return s, model.ErrUnknownSession
+		}
+		return s, fmt.Errorf("ошибка получения сессии: %v", err)
+	}
+	return s, nil
+}
+
+// GetSessionsByUserID получает действующие на момент now сессии пользователя,
+// начиная с последней активной.
+func (q *SessionQueries) GetSessionsByUserID(userID int64, now time.Time) ([]model.Session, error) {
+	rows, err := q.db.Query(getSessionsByUserIDQuery, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения сессий: %v", err)
+	}
+	defer rows.Close()
+
+	sessions := []model.Session{}
+	for rows.Next() {
+		var s model.Session
+		if err := scanSession(rows, &s); err != nil {
+			return nil, fmt.Errorf("ошибка чтения сессии: %v", err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// TouchSession запоминает время последнего запроса в сессии.
+func (q *SessionQueries) TouchSession(id string, now time.Time) error {
+	if _, err := q.db.Exec(touchSessionQuery, id, now); err != nil {
+		return fmt.Errorf("ошибка обновления сессии: %v", err)
+	}
+	return nil
+}
+
+func scanSession(row rowScanner, s *model.Session) error {
+	return row.Scan(&s.Id, &s.UserId, &s.ClientID, &s.ClientName, &s.UserAgent, &s.IP,
+		&s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt)
+}
@@ -0,0 +1 @@
+INSERT INTO api_keys (user_id, name, prefix, token_hash, scopes, expires_at, created_at) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id
\ No newline at end of file
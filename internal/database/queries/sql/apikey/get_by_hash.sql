@@ -0,0 +1 @@
+SELECT id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, created_at, revoked_at FROM api_keys WHERE token_hash = $1
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, created_at, revoked_at FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL ORDER BY id
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE api_keys SET revoked_at = $3 WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
\ No newline at end of file
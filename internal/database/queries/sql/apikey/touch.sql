@@ -0,0 +1,2 @@
+UPDATE api_keys SET last_used_at = $2
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2 - INTERVAL '1 minute')
\ No newline at end of file
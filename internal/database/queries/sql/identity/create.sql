@@ -0,0 +1 @@
+INSERT INTO identities (user_id, provider, subject, email) VALUES($1, $2, $3, $4) RETURNING id, created_at
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT id, user_id, provider, subject, email, created_at, last_login_at FROM identities WHERE provider = $1 AND subject = $2
\ No newline at end of file
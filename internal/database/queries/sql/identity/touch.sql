@@ -0,0 +1 @@
+UPDATE identities SET last_login_at = $2 WHERE id = $1
\ No newline at end of file
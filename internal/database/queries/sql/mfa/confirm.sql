@@ -0,0 +1 @@
+UPDATE user_mfa SET confirmed_at = $3, last_counter = $2 WHERE user_id = $1 AND confirmed_at IS NULL
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM user_mfa WHERE user_id = $1
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT user_id, secret, confirmed_at, last_counter FROM user_mfa WHERE user_id = $1
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT id, user_id, code_hash, used_at FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL ORDER BY id
\ No newline at end of file
@@ -0,0 +1,3 @@
+INSERT INTO user_mfa (user_id, secret) VALUES($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_counter = 0, created_at = CURRENT_TIMESTAMP
+WHERE user_mfa.confirmed_at IS NULL
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE user_mfa SET last_counter = $2 WHERE user_id = $1 AND last_counter < $2
\ No newline at end of file
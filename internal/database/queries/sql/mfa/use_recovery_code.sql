@@ -0,0 +1 @@
+UPDATE recovery_codes SET used_at = $2 WHERE id = $1 AND used_at IS NULL
\ No newline at end of file
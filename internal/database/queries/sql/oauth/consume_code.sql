@@ -0,0 +1,3 @@
+UPDATE oauth_codes SET used_at = $3, family_id = $2
+WHERE code_hash = $1 AND used_at IS NULL
+RETURNING id, code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, family_id, expires_at, created_at, used_at
\ No newline at end of file
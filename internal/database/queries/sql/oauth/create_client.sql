@@ -0,0 +1 @@
+INSERT INTO oauth_clients (id, name, secret_hash, redirect_uris, scopes, created_by, created_at) VALUES($1, $2, $3, $4, $5, $6, $7)
\ No newline at end of file
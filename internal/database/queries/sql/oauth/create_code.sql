@@ -0,0 +1,2 @@
+INSERT INTO oauth_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at, created_at)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM oauth_clients WHERE id = $1
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT id, name, secret_hash, redirect_uris, scopes, created_by, created_at FROM oauth_clients WHERE id = $1
\ No newline at end of file
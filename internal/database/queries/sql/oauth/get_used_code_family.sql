@@ -0,0 +1 @@
+SELECT COALESCE(family_id, '') FROM oauth_codes WHERE code_hash = $1 AND used_at IS NOT NULL
\ No newline at end of file
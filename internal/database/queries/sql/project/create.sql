@@ -0,0 +1 @@
+INSERT INTO projects (user_id, name, description, color) VALUES($1, $2, $3, $4) RETURNING id, created_at, updated_at
\ No newline at end of file
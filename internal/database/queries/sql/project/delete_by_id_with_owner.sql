@@ -0,0 +1 @@
+DELETE FROM projects WHERE id = $1 AND user_id = $2
\ No newline at end of file
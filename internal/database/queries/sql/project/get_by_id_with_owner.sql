@@ -0,0 +1 @@
+SELECT id, user_id, name, description, color, archived, created_at, updated_at FROM projects WHERE id = $1 AND user_id = $2
\ No newline at end of file
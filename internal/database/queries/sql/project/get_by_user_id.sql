@@ -0,0 +1 @@
+SELECT id, user_id, name, description, color, archived, created_at, updated_at FROM projects WHERE user_id = $1 AND ($2 OR NOT archived) ORDER BY name, id
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE projects SET archived = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND user_id = $3
\ No newline at end of file
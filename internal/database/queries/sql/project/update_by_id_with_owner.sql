@@ -0,0 +1 @@
+UPDATE projects SET name = $1, description = $2, color = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4 AND user_id = $5
\ No newline at end of file
@@ -0,0 +1 @@
+INSERT INTO roles (name, description, permissions) VALUES ($1, $2, $3) ON CONFLICT (name) DO NOTHING RETURNING created_at
\ No newline at end of file
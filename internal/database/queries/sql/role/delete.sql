@@ -0,0 +1 @@
+DELETE FROM roles WHERE name = $1 AND NOT built_in AND NOT EXISTS (SELECT 1 FROM users WHERE role = $1)
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT name, description, permissions, built_in, created_at FROM roles ORDER BY built_in DESC, name
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT name, description, permissions, built_in, created_at FROM roles WHERE name = $1
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT r.permissions FROM users u JOIN roles r ON r.name = u.role WHERE u.id = $1
\ No newline at end of file
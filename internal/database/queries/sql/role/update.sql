@@ -0,0 +1 @@
+UPDATE roles SET description = $2, permissions = $3 WHERE name = $1 AND NOT built_in
\ No newline at end of file
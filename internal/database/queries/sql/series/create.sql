@@ -0,0 +1 @@
+INSERT INTO task_series (user_id, rrule) VALUES($1, $2) RETURNING id
\ No newline at end of file
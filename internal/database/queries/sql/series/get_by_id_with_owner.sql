@@ -0,0 +1 @@
+SELECT id, user_id, rrule, occurrences, stopped, created_at, updated_at FROM task_series WHERE id = $1 AND user_id = $2
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE task_series SET occurrences = occurrences + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE task_series SET stopped = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2
\ No newline at end of file
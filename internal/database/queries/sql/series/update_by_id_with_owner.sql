@@ -0,0 +1 @@
+UPDATE task_series SET rrule = COALESCE(NULLIF($1, ''), rrule), updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND user_id = $3
\ No newline at end of file
@@ -0,0 +1,2 @@
+UPDATE tasks SET task = COALESCE(NULLIF($1, ''), task), priority = COALESCE(NULLIF($2, ''), priority), updated_at = CURRENT_TIMESTAMP
+WHERE series_id = $3 AND user_id = $4 AND status IN ('pending', 'in_progress', 'blocked')
\ No newline at end of file
@@ -0,0 +1,2 @@
+INSERT INTO sessions (id, user_id, client_id, user_agent, ip, created_at, last_seen_at, expires_at)
+VALUES($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8)
\ No newline at end of file
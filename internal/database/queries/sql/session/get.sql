@@ -0,0 +1,3 @@
+SELECT s.id, s.user_id, COALESCE(s.client_id, ''), COALESCE(c.name, ''), s.user_agent, s.ip, s.created_at, s.last_seen_at, s.expires_at, s.revoked_at
+FROM sessions s LEFT JOIN oauth_clients c ON c.id = s.client_id
+WHERE s.id = $1
\ No newline at end of file
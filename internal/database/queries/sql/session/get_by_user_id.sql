@@ -0,0 +1,4 @@
+SELECT s.id, s.user_id, COALESCE(s.client_id, ''), COALESCE(c.name, ''), s.user_agent, s.ip, s.created_at, s.last_seen_at, s.expires_at, s.revoked_at
+FROM sessions s LEFT JOIN oauth_clients c ON c.id = s.client_id
+WHERE s.user_id = $1 AND s.revoked_at IS NULL AND s.expires_at > $2
+ORDER BY s.last_seen_at DESC, s.id
\ No newline at end of file
@@ -0,0 +1,3 @@
+INSERT INTO task_tags (task_id, tag_id)
+SELECT $1, id FROM tags WHERE id = ANY($2) AND user_id = $3
+ON CONFLICT DO NOTHING
\ No newline at end of file
@@ -0,0 +1 @@
+INSERT INTO task_tags (task_id, tag_id) SELECT $1, tag_id FROM task_tags WHERE task_id = $2
\ No newline at end of file
@@ -0,0 +1 @@
+INSERT INTO tags (user_id, name, color) VALUES($1, $2, $3) RETURNING id, created_at
\ No newline at end of file
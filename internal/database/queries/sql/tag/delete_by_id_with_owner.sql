@@ -0,0 +1 @@
+DELETE FROM tags WHERE id = $1 AND user_id = $2
\ No newline at end of file
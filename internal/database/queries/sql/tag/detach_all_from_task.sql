@@ -0,0 +1 @@
+DELETE FROM task_tags WHERE task_id = $1
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT id, user_id, name, color, created_at FROM tags WHERE id = $1 AND user_id = $2
\ No newline at end of file
@@ -0,0 +1,5 @@
+SELECT tt.task_id, g.id, g.user_id, g.name, g.color, g.created_at
+FROM task_tags tt
+JOIN tags g ON g.id = tt.tag_id
+WHERE tt.task_id = ANY($1)
+ORDER BY g.name
\ No newline at end of file
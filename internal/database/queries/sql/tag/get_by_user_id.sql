@@ -0,0 +1 @@
+SELECT id, user_id, name, color, created_at FROM tags WHERE user_id = $1 ORDER BY name
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE tags SET name = $1, color = $2 WHERE id = $3 AND user_id = $4
\ No newline at end of file
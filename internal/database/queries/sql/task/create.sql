@@ -1 +1 @@
-INSERT INTO tasks (user_id, task) VALUES($1, $2) RETURNING id
\ No newline at end of file
+INSERT INTO tasks (user_id, task, due_at, priority, project_id, parent_id, series_id) VALUES($1, $2, $3, COALESCE(NULLIF($4, ''), 'normal'), $5, $6, $7) RETURNING id
\ No newline at end of file
@@ -1 +1,2 @@
-DELETE FROM tasks WHERE id = $1 AND user_id = $2
\ No newline at end of file
+DELETE FROM tasks WHERE id = $1 AND user_id = $2
+	AND ($3::boolean OR NOT EXISTS (SELECT 1 FROM tasks WHERE parent_id = $1))
\ No newline at end of file
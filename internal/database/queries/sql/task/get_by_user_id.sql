@@ -1 +1 @@
-SELECT id, user_id, task, status, created_at, updated_at FROM tasks WHERE user_id = $1
\ No newline at end of file
+SELECT id, user_id, task, status, due_at, priority, completed_at, status_changed_at, project_id, parent_id, series_id, created_at, updated_at FROM tasks WHERE user_id = $1
\ No newline at end of file
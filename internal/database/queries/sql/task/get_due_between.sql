@@ -0,0 +1,3 @@
+SELECT id, user_id, task, status, due_at, priority, completed_at, status_changed_at, project_id, parent_id, series_id, created_at, updated_at FROM tasks
+WHERE user_id = $1 AND status IN ('pending', 'in_progress', 'blocked') AND due_at >= $2 AND due_at < $3
+ORDER BY due_at
\ No newline at end of file
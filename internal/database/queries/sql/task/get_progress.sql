@@ -0,0 +1 @@
+SELECT parent_id, COUNT(*) FILTER (WHERE status <> 'cancelled'), COUNT(*) FILTER (WHERE status = 'done') FROM tasks WHERE parent_id = ANY($1) GROUP BY parent_id
\ No newline at end of file
@@ -0,0 +1,8 @@
+WITH RECURSIVE subtree AS (
+	SELECT id FROM tasks WHERE parent_id = $1 AND user_id = $2
+	UNION ALL
+	SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id
+)
+SELECT id, user_id, task, status, due_at, priority, completed_at, status_changed_at, project_id, parent_id, series_id, created_at, updated_at FROM tasks
+WHERE id IN (SELECT id FROM subtree)
+ORDER BY id
\ No newline at end of file
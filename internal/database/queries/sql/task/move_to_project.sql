@@ -0,0 +1,3 @@
+UPDATE tasks SET project_id = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2 AND user_id = $3
+	AND ($1::integer IS NULL OR EXISTS (SELECT 1 FROM projects WHERE id = $1 AND user_id = $3))
\ No newline at end of file
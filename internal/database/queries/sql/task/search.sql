@@ -0,0 +1,9 @@
+SELECT id, user_id, task, status, due_at, priority, completed_at, status_changed_at, project_id, parent_id, series_id, created_at, updated_at,
+	ts_rank(search_vector, query) AS rank,
+	ts_headline('simple',
+		replace(replace(replace(replace(replace(task, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '''', '&#39;'), '"', '&#34;'),
+		query, 'StartSel=<b>, StopSel=</b>, MaxWords=30, MinWords=10, MaxFragments=2') AS snippet
+FROM tasks, to_tsquery('simple', $2) AS query
+WHERE user_id = $1 AND search_vector @@ query
+ORDER BY rank DESC, id DESC
+LIMIT $3
\ No newline at end of file
@@ -1 +1 @@
-UPDATE tasks SET task = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND user_id = $3
\ No newline at end of file
+UPDATE tasks SET task = $1, due_at = $2, priority = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4 AND user_id = $5
\ No newline at end of file
@@ -1 +1,5 @@
-UPDATE tasks SET status = $1, updated_at = NOW() WHERE id = $2 AND user_id = $3
\ No newline at end of file
+UPDATE tasks SET status = $1,
+	completed_at = CASE WHEN $1 = 'done' THEN COALESCE(completed_at, NOW()) ELSE NULL END,
+	status_changed_at = CASE WHEN status <> $1 THEN NOW() ELSE status_changed_at END,
+	updated_at = NOW()
+WHERE id = $2 AND user_id = $3
\ No newline at end of file
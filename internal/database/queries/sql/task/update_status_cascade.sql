@@ -0,0 +1,10 @@
+WITH RECURSIVE subtree AS (
+	SELECT id FROM tasks WHERE id = $2 AND user_id = $3
+	UNION ALL
+	SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id
+)
+UPDATE tasks SET status = $1,
+	completed_at = CASE WHEN $1 = 'done' THEN COALESCE(completed_at, NOW()) ELSE NULL END,
+	status_changed_at = CASE WHEN status <> $1 THEN NOW() ELSE status_changed_at END,
+	updated_at = NOW()
+WHERE id IN (SELECT id FROM subtree) AND (id = $2 OR status = ANY($4))
\ No newline at end of file
@@ -0,0 +1,3 @@
+UPDATE user_tokens SET used_at = $3
+WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+RETURNING id, user_id, purpose, token_hash, COALESCE(new_email, ''), expires_at, created_at, used_at
\ No newline at end of file
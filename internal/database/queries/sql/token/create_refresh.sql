@@ -0,0 +1,2 @@
+INSERT INTO refresh_tokens (user_id, token_hash, family_id, access_jti, access_expires_at, expires_at, created_at, client_id, scopes)
+VALUES($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9) RETURNING id
\ No newline at end of file
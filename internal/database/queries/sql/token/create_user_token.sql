@@ -0,0 +1 @@
+INSERT INTO user_tokens (user_id, purpose, token_hash, new_email, expires_at, created_at) VALUES($1, $2, $3, NULLIF($4, ''), $5, $6) RETURNING id
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE sessions SET last_seen_at = $2, expires_at = $3 WHERE id = $1
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT family_id FROM refresh_tokens WHERE access_jti = $1
\ No newline at end of file
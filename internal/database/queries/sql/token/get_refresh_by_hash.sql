@@ -0,0 +1 @@
+SELECT id, user_id, token_hash, family_id, access_jti, access_expires_at, expires_at, created_at, revoked_at, COALESCE(client_id, ''), scopes FROM refresh_tokens WHERE token_hash = $1
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE user_tokens SET used_at = $3 WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
\ No newline at end of file
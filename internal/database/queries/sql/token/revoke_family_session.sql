@@ -0,0 +1 @@
+UPDATE sessions SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL
\ No newline at end of file
@@ -0,0 +1,3 @@
+INSERT INTO revoked_tokens (jti, expires_at)
+SELECT access_jti, access_expires_at FROM refresh_tokens WHERE user_id = $1 AND access_expires_at > $2
+ON CONFLICT DO NOTHING
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT COUNT(*) FROM users WHERE role = $1
\ No newline at end of file
@@ -1 +0,0 @@
-SELECT id, user_name, email, password_hash, role, created_at FROM users
\ No newline at end of file
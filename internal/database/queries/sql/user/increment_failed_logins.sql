@@ -0,0 +1 @@
+UPDATE users SET failed_login_count = failed_login_count + 1 WHERE id = $1 RETURNING failed_login_count
\ No newline at end of file
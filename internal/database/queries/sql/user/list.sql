@@ -0,0 +1 @@
+SELECT id, user_name, email, password_hash, role, created_at, email_verified_at, last_login_at, failed_login_count, locked_until, suspended_at FROM users
\ No newline at end of file
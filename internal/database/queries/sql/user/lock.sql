@@ -0,0 +1 @@
+UPDATE users SET locked_until = $2 WHERE id = $1
\ No newline at end of file
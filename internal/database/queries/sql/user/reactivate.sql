@@ -0,0 +1 @@
+UPDATE users SET suspended_at = NULL WHERE id = $1
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE users SET last_login_at = $2, failed_login_count = 0, locked_until = NULL WHERE id = $1
\ No newline at end of file
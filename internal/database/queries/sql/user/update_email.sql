@@ -0,0 +1 @@
+UPDATE users SET email = $2, email_verified_at = $3 WHERE id = $1
\ No newline at end of file
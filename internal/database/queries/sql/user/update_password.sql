@@ -0,0 +1 @@
+UPDATE users SET password_hash = $2 WHERE id = $1
\ No newline at end of file
@@ -0,0 +1,181 @@
+package queries
+
+import (
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"go.mood/internal/model"
+)
+
+//go:embed sql/tag/create.sql
+var createTagQuery string
+
+//go:embed sql/tag/get_by_user_id.sql
+var getTagsByUserIDQuery string
+
+//go:embed sql/tag/get_by_id_with_owner.sql
+var getTagByIDWithOwnerQuery string
+
+//go:embed sql/tag/update_by_id_with_owner.sql
+var updateTagByIDWithOwnerQuery string
+
+//go:embed sql/tag/delete_by_id_with_owner.sql
+var deleteTagByIDWithOwnerQuery string
+
+//go:embed sql/tag/get_by_task_ids.sql
+var getTagsByTaskIDsQuery string
+
+//go:embed sql/tag/detach_all_from_task.sql
+var detachAllTagsFromTaskQuery string
+
+//go:embed sql/tag/attach_to_task.sql
+var attachTagsToTaskQuery string
+
+//go:embed sql/tag/copy_to_task.sql
+var copyTaskTagsQuery string
+
+// uniqueViolation — код ошибки PostgreSQL при нарушении ограничения UNIQUE.
+const uniqueViolation = "23505"
+
+// TagQueries содержит методы для работы с метками в БД.
+type TagQueries struct {
+	db *sql.DB
+}
+
+// NewTagQueries создает новый экземпляр TagQueries.
+func NewTagQueries(db *sql.DB) *TagQueries {
+	return &TagQueries{db: db}
+}
+
+// CreateTag создает метку пользователя.
+func (q *TagQueries) CreateTag(tag *model.Tag) error {
+	if err := q.db.QueryRow(createTagQuery, tag.UserId, tag.Name, tag.Color).Scan(&tag.Id, &tag.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrTagNameTaken
+		}
+		return fmt.Errorf("ошибка создания метки: %v", err)
+	}
+	return nil
+}
+
+// GetTagsByUserID получает все метки пользователя, упорядоченные по имени.
+func (q *TagQueries) GetTagsByUserID(userID int64) ([]model.Tag, error) {
+	rows, err := q.db.Query(getTagsByUserIDQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения меток: %v", err)
+	}
+	defer rows.Close()
+
+	var tags []model.Tag
+	for rows.Next() {
+		var tag model.Tag
+		if err := rows.Scan(&tag.Id, &tag.UserId, &tag.Name, &tag.Color, &tag.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка чтения метки из строки: %v", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// GetTagByIDWithOwner получает метку, только если она принадлежит ownerID.
+func (q *TagQueries) GetTagByIDWithOwner(id, ownerID int64) (model.Tag, error) {
+	var tag model.Tag
+	err := q.db.QueryRow(getTagByIDWithOwnerQuery, id, ownerID).
+		Scan(&tag.Id, &tag.UserId, &tag.Name, &tag.Color, &tag.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return tag, model.ErrUnknownTag
+		}
+		return tag, fmt.Errorf("ошибка получения метки: %v", err)
+	}
+	return tag, nil
+}
+
+// UpdateTagByIDWithOwner меняет имя и цвет метки, если она принадлежит ownerID.
+func (q *TagQueries) UpdateTagByIDWithOwner(id int64, tag *model.Tag, ownerID int64) error {
+	res, err := q.db.Exec(updateTagByIDWithOwnerQuery, tag.Name, tag.Color, id, ownerID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrTagNameTaken
+		}
+		return fmt.Errorf("ошибка обновления метки: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return model.ErrUnknownTag
+	}
+	return nil
+}
+
+// DeleteTagByIDWithOwner удаляет метку, если она принадлежит ownerID.
+// Связи с задачами удаляются каскадно (ON DELETE CASCADE в task_tags).
+func (q *TagQueries) DeleteTagByIDWithOwner(id, ownerID int64) error {
+	res, err := q.db.Exec(deleteTagByIDWithOwnerQuery, id, ownerID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления метки: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return model.ErrUnknownTag
+	}
+	return nil
+}
+
+// GetTagsByTaskIDs получает метки для списка задач: task_id -> метки.
+func (q *TagQueries) GetTagsByTaskIDs(taskIDs []int64) (map[int64][]model.Tag, error) {
+	rows, err := q.db.Query(getTagsByTaskIDsQuery, pq.Array(taskIDs))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения меток задач: %v", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[int64][]model.Tag)
+	for rows.Next() {
+		var taskID int64
+		var tag model.Tag
+		if err := rows.Scan(&taskID, &tag.Id, &tag.UserId, &tag.Name, &tag.Color, &tag.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка чтения метки из строки: %v", err)
+		}
+		tags[taskID] = append(tags[taskID], tag)
+	}
+	return tags, nil
+}
+
+// replaceTaskTags заменяет метки задачи на tagIDs в рамках транзакции tx.
+// Если хотя бы одна метка не принадлежит ownerID, возвращает model.ErrUnknownTag.
+func replaceTaskTags(tx *sql.Tx, taskID int64, tagIDs []int64, ownerID int64) error {
+	if _, err := tx.Exec(detachAllTagsFromTaskQuery, taskID); err != nil {
+		return fmt.Errorf("ошибка отвязки меток: %w", err)
+	}
+	ids := uniqueIDs(tagIDs)
+	if len(ids) == 0 {
+		return nil
+	}
+	res, err := tx.Exec(attachTagsToTaskQuery, taskID, pq.Array(ids), ownerID)
+	if err != nil {
+		return fmt.Errorf("ошибка привязки меток: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n != int64(len(ids)) {
+		return model.ErrUnknownTag
+	}
+	return nil
+}
+
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
@@ -5,7 +5,10 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"go.mood/internal/model"
+	"strings"
+	"time"
 )
 
 //go:embed sql/task/get_all.sql
@@ -29,6 +32,54 @@ var deleteTaskByIDWithOwnerQuery string
 //go:embed sql/task/update_status.sql
 var updateTaskStatusQuery string
 
+//go:embed sql/task/get_due_between.sql
+var getTasksDueBetweenQuery string
+
+//go:embed sql/task/get_overdue.sql
+var getOverdueTasksQuery string
+
+//go:embed sql/task/list.sql
+var listTasksQuery string
+
+//go:embed sql/task/search.sql
+var searchTasksQuery string
+
+//go:embed sql/task/move_to_project.sql
+var moveTaskToProjectQuery string
+
+//go:embed sql/task/get_subtree.sql
+var getSubtreeQuery string
+
+//go:embed sql/task/get_progress.sql
+var getProgressByTaskIDsQuery string
+
+//go:embed sql/task/update_status_cascade.sql
+var updateTaskStatusCascadeQuery string
+
+//go:embed sql/task/get_for_update.sql
+var getTaskForUpdateQuery string
+
+// taskSortColumns сопоставляет поле сортировки с SQL-выражением и типом,
+// к которому приводится значение курсора. Выражения совпадают с model.Task.SortKey.
+var taskSortColumns = map[string]struct{ expr, cast string }{
+	"id":         {"id", "bigint"},
+	"created_at": {"created_at", "timestamp"},
+	"updated_at": {"updated_at", "timestamp"},
+	"due_at":     {"COALESCE(due_at, 'infinity'::timestamp)", "timestamp"},
+	"priority":   {"CASE priority WHEN 'low' THEN 0 WHEN 'normal' THEN 1 WHEN 'high' THEN 2 ELSE 3 END", "integer"},
+}
+
+// rowScanner — общий интерфейс *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTask читает задачу в порядке колонок, общих для всех SELECT по tasks.
+func scanTask(row rowScanner, task *model.Task) error {
+	return row.Scan(&task.Id, &task.UserId, &task.Task, &task.Status, &task.DueAt, &task.Priority,
+		&task.CompletedAt, &task.StatusChangedAt, &task.ProjectID, &task.ParentID, &task.SeriesID, &task.CreatedAt, &task.UpdatedAt)
+}
+
 // TaskQueries содержит методы для работы с задачами в БД.
 type TaskQueries struct {
 	db *sql.DB
@@ -55,7 +106,7 @@ func (q *TaskQueries) CreateTasksInBulk(tasks []*model.Task) error {
 
 	// 3. Выполняем каждую операцию в рамках транзакции
 	for _, task := range tasks {
-		row := tx.QueryRow(createTaskQuery, task.UserId, task.Task)
+		row := tx.QueryRow(createTaskQuery, task.UserId, task.Task, task.DueAt, task.Priority, task.ProjectID, task.ParentID, task.SeriesID)
 		if err := row.Scan(&task.Id); err != nil {
 			return fmt.Errorf("ошибка создания задачи в транзакции: %w", err)
 		}
@@ -79,7 +130,7 @@ func (q *TaskQueries) GetAllTasks() ([]model.Task, error) {
 	var tasks []model.Task
 	for rows.Next() {
 		var task model.Task
-		if err := rows.Scan(&task.Id, &task.UserId, &task.Task, &task.Status, &task.CreatedAt, &task.UpdatedAt); err != nil {
+		if err := scanTask(rows, &task); err != nil {
 			return nil, fmt.Errorf("ошибка чтения задачи из строки: %v", err)
 		}
 		tasks = append(tasks, task)
@@ -97,7 +148,7 @@ func (q *TaskQueries) GetTasksByUserID(userID int64) ([]model.Task, error) {
 	var tasks []model.Task
 	for rows.Next() {
 		var task model.Task
-		if err := rows.Scan(&task.Id, &task.UserId, &task.Task, &task.Status, &task.CreatedAt, &task.UpdatedAt); err != nil {
+		if err := scanTask(rows, &task); err != nil {
 			return nil, fmt.Errorf("ошибка чтения задачи из строки: %v", err)
 		}
 		tasks = append(tasks, task)
@@ -109,7 +160,7 @@ func (q *TaskQueries) GetTaskByID(id int64) (model.Task, error) {
 	row := q.db.QueryRow(getTaskByIDQuery, id)
 	var task model.Task
 
-	if err := row.Scan(&task.Id, &task.UserId, &task.Task, &task.Status, &task.CreatedAt, &task.UpdatedAt); err != nil {
+	if err := scanTask(row, &task); err != nil {
 		if err == sql.ErrNoRows {
 			return task, fmt.Errorf("задача с id %d не найдена", id)
 		}
@@ -118,16 +169,57 @@ func (q *TaskQueries) GetTaskByID(id int64) (model.Task, error) {
 	return task, nil
 }
 
+// CreateTask создает задачу. Если задан task.Recurrence, в той же транзакции
+// создаётся серия повторений и задача становится её первым повторением.
 func (q *TaskQueries) CreateTask(task *model.Task) error {
-	row := q.db.QueryRow(createTaskQuery, task.UserId, task.Task)
+	if task.TagIDs == nil && task.Recurrence == "" {
+		row := q.db.QueryRow(createTaskQuery, task.UserId, task.Task, task.DueAt, task.Priority, task.ProjectID, task.ParentID, task.SeriesID)
+		if err := row.Scan(&task.Id); err != nil {
+			return fmt.Errorf("ошибка создания задачи: %v", err)
+		}
+		return nil
+	}
+
+	// Задача и её метки сохраняются вместе или не сохраняются вовсе
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if task.Recurrence != "" {
+		var seriesID int64
+		if err := tx.QueryRow(createSeriesQuery, task.UserId, task.Recurrence).Scan(&seriesID); err != nil {
+			return fmt.Errorf("ошибка создания серии: %v", err)
+		}
+		task.SeriesID = &seriesID
+	}
+	row := tx.QueryRow(createTaskQuery, task.UserId, task.Task, task.DueAt, task.Priority, task.ProjectID, task.ParentID, task.SeriesID)
 	if err := row.Scan(&task.Id); err != nil {
 		return fmt.Errorf("ошибка создания задачи: %v", err)
 	}
+	if task.TagIDs != nil {
+		if err := replaceTaskTags(tx, int64(task.Id), task.TagIDs, task.UserId); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
 	return nil
 }
 
+// UpdateTaskByIDWithOwner обновляет задачу, если она принадлежит ownerID.
+// Если task.TagIDs не nil, метки задачи заменяются в той же транзакции.
 func (q *TaskQueries) UpdateTaskByIDWithOwner(id int64, task *model.Task, ownerID int64) error {
-	res, err := q.db.Exec(updateTaskByIDWithOwnerQuery, task.Task, id, ownerID)
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(updateTaskByIDWithOwnerQuery, task.Task, task.DueAt, task.Priority, id, ownerID)
 	if err != nil {
 		return fmt.Errorf("ошибка обновления: %v", err)
 	}
@@ -135,29 +227,303 @@ func (q *TaskQueries) UpdateTaskByIDWithOwner(id int64, task *model.Task, ownerI
 	if n == 0 {
 		return fmt.Errorf("задача не найдена или вы не являетесь владельцем")
 	}
+	if task.TagIDs != nil {
+		if err := replaceTaskTags(tx, id, task.TagIDs, ownerID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
 	return nil
 }
 
-func (q *TaskQueries) DeleteTaskByIDWithOwner(id int64, ownerID int64) error {
-	res, err := q.db.Exec(deleteTaskByIDWithOwnerQuery, id, ownerID)
+// DeleteTaskByIDWithOwner удаляет задачу, если она принадлежит ownerID. Подзадачи удаляются
+// каскадно (ON DELETE CASCADE); без cascade задача с подзадачами не удаляется — model.ErrTaskHasSubtasks.
+func (q *TaskQueries) DeleteTaskByIDWithOwner(id int64, ownerID int64, cascade bool) error {
+	res, err := q.db.Exec(deleteTaskByIDWithOwnerQuery, id, ownerID, cascade)
 	if err != nil {
 		return fmt.Errorf("ошибка удаления: %v", err)
 	}
 	n, _ := res.RowsAffected()
 	if n == 0 {
+		// Своя задача без cascade не удаляется, только если у неё есть подзадачи
+		if task, err := q.GetTaskByID(id); !cascade && err == nil && task.UserId == ownerID {
+			return model.ErrTaskHasSubtasks
+		}
 		return fmt.Errorf("задача не найдена или вы не являетесь владельцем")
 	}
 	return nil
 }
 
-func (q *TaskQueries) UpdateTaskStatus(taskID int64, userID int64, status bool) error {
-	res, err := q.db.Exec(updateTaskStatusQuery, status, taskID, userID)
+// UpdateTaskStatus меняет статус задачи userID; с cascade — и тех её подзадач на любой глубине,
+// для которых такой переход разрешён. Недопустимый переход самой задачи возвращает
+// model.ErrInvalidTransition: он проверяется по заблокированной строке, чтобы два параллельных
+// запроса не провели задачу в обход конечного автомата статусов.
+// Если выполняется задача серии, в той же транзакции создаётся её следующее повторение.
+func (q *TaskQueries) UpdateTaskStatus(taskID int64, userID int64, status model.TaskStatus, cascade bool) error {
+	tx, err := q.db.Begin()
 	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Блокируем задачу, чтобы параллельное выполнение не создало два повторения
+	var task model.Task
+	if err := scanTask(tx.QueryRow(getTaskForUpdateQuery, taskID, userID), &task); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("задача не найдена или вы не владелец")
+		}
 		return err
 	}
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
-		return errors.New("задача не найдена или вы не владелец")
+	if !task.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s → %s", model.ErrInvalidTransition, task.Status, status)
+	}
+
+	if cascade {
+		allowed := model.StatusesAllowedTo(status)
+		from := make([]string, len(allowed))
+		for i, st := range allowed {
+			from[i] = string(st)
+		}
+		_, err = tx.Exec(updateTaskStatusCascadeQuery, status, taskID, userID, pq.Array(from))
+	} else {
+		_, err = tx.Exec(updateTaskStatusQuery, status, taskID, userID)
+	}
+	if err != nil {
+		return err
+	}
+	if status == model.StatusDone && task.Status != model.StatusDone && task.SeriesID != nil && task.DueAt != nil {
+		if err := createNextOccurrence(tx, &task); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// createNextOccurrence создаёт следующую задачу серии после выполненной задачи done,
+// копируя её текст, приоритет, проект, родителя и метки. Если серия остановлена
+// или закончилась, ничего не делает.
+func createNextOccurrence(tx *sql.Tx, done *model.Task) error {
+	var series model.TaskSeries
+	if err := scanSeries(tx.QueryRow(getSeriesForUpdateQuery, *done.SeriesID), &series); err != nil {
+		return fmt.Errorf("ошибка получения серии: %v", err)
+	}
+	due, ok := series.NextOccurrence(*done.DueAt)
+	if !ok {
+		return nil
+	}
+
+	var nextID int64
+	row := tx.QueryRow(createTaskQuery, done.UserId, done.Task, due, done.Priority, done.ProjectID, done.ParentID, done.SeriesID)
+	if err := row.Scan(&nextID); err != nil {
+		return fmt.Errorf("ошибка создания повторения задачи: %v", err)
+	}
+	if _, err := tx.Exec(copyTaskTagsQuery, nextID, done.Id); err != nil {
+		return fmt.Errorf("ошибка копирования меток: %v", err)
+	}
+	if _, err := tx.Exec(incrementSeriesOccurrencesQuery, series.Id); err != nil {
+		return fmt.Errorf("ошибка обновления серии: %v", err)
+	}
+	return nil
+}
+
+// GetTasksDueBetween возвращает невыполненные задачи пользователя со сроком в [from, to).
+func (q *TaskQueries) GetTasksDueBetween(userID int64, from, to time.Time) ([]model.Task, error) {
+	return q.queryTasks(getTasksDueBetweenQuery, userID, from, to)
+}
+
+// GetOverdueTasks возвращает невыполненные задачи пользователя, срок которых раньше now.
+func (q *TaskQueries) GetOverdueTasks(userID int64, now time.Time) ([]model.Task, error) {
+	return q.queryTasks(getOverdueTasksQuery, userID, now)
+}
+
+// ListTasks возвращает задачи пользователя по фильтру, отсортированные по
+// (filter.SortBy, id), начиная строго после filter.After.
+func (q *TaskQueries) ListTasks(filter model.TaskFilter) ([]model.Task, error) {
+	sortCol, ok := taskSortColumns[filter.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("недопустимое поле сортировки: %s", filter.SortBy)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(listTasksQuery)
+	args := []any{filter.UserID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Status != "" {
+		sb.WriteString(" AND status = " + arg(filter.Status))
+	}
+	if filter.Priority != "" {
+		sb.WriteString(" AND priority = " + arg(filter.Priority))
+	}
+	if filter.CreatedFrom != nil {
+		sb.WriteString(" AND created_at >= " + arg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		sb.WriteString(" AND created_at < " + arg(*filter.CreatedTo))
+	}
+	if filter.UpdatedFrom != nil {
+		sb.WriteString(" AND updated_at >= " + arg(*filter.UpdatedFrom))
+	}
+	if filter.UpdatedTo != nil {
+		sb.WriteString(" AND updated_at < " + arg(*filter.UpdatedTo))
+	}
+	if filter.Search != "" {
+		sb.WriteString(` AND task ILIKE '%' || ` + arg(escapeLike(filter.Search)) + ` || '%'`)
+	}
+	if len(filter.Tags) > 0 {
+		// Задача должна иметь все перечисленные метки
+		fmt.Fprintf(&sb, ` AND id IN (SELECT tt.task_id FROM task_tags tt JOIN tags g ON g.id = tt.tag_id
+	WHERE g.user_id = $1 AND g.name = ANY(%s) GROUP BY tt.task_id HAVING COUNT(DISTINCT g.name) = %s)`,
+			arg(pq.Array(filter.Tags)), arg(len(uniqueStrings(filter.Tags))))
+	}
+
+	if filter.SeriesID != nil {
+		sb.WriteString(" AND series_id = " + arg(*filter.SeriesID))
+	}
+
+	if filter.ProjectID != nil {
+		sb.WriteString(" AND project_id = " + arg(*filter.ProjectID))
+	} else if !filter.WithArchive {
+		sb.WriteString(" AND (project_id IS NULL OR project_id NOT IN (SELECT id FROM projects WHERE user_id = $1 AND archived))")
+	}
+
+	direction, cmp := "ASC", ">"
+	if filter.Desc {
+		direction, cmp = "DESC", "<"
+	}
+	if filter.After != nil {
+		fmt.Fprintf(&sb, " AND (%s, id) %s (%s::%s, %s)",
+			sortCol.expr, cmp, arg(filter.After.Value), sortCol.cast, arg(filter.After.ID))
+	}
+	fmt.Fprintf(&sb, " ORDER BY %s %s, id %s", sortCol.expr, direction, direction)
+	if filter.Limit > 0 {
+		sb.WriteString(" LIMIT " + arg(filter.Limit))
+	}
+
+	return q.queryTasks(sb.String(), args...)
+}
+
+// SearchTasks ищет задачи пользователя полнотекстовым поиском по tasks.search_vector
+// и возвращает их по убыванию релевантности.
+func (q *TaskQueries) SearchTasks(userID int64, query model.SearchQuery, limit int) ([]model.TaskSearchResult, error) {
+	rows, err := q.db.Query(searchTasksQuery, userID, toTSQuery(query), limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска задач: %v", err)
+	}
+	defer rows.Close()
+
+	var results []model.TaskSearchResult
+	for rows.Next() {
+		var res model.TaskSearchResult
+		t := &res.Task
+		if err := rows.Scan(&t.Id, &t.UserId, &t.Task, &t.Status, &t.DueAt, &t.Priority,
+			&t.CompletedAt, &t.StatusChangedAt, &t.ProjectID, &t.ParentID, &t.SeriesID, &t.CreatedAt, &t.UpdatedAt, &res.Rank, &res.Snippet); err != nil {
+			return nil, fmt.Errorf("ошибка чтения задачи из строки: %v", err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// MoveTaskToProject переносит задачу ownerID в проект projectID (nil — убрать из проекта).
+// Проект тоже должен принадлежать ownerID.
+func (q *TaskQueries) MoveTaskToProject(taskID, ownerID int64, projectID *int64) error {
+	res, err := q.db.Exec(moveTaskToProjectQuery, projectID, taskID, ownerID)
+	if err != nil {
+		return fmt.Errorf("ошибка переноса задачи: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return fmt.Errorf("задача или проект не найдены, либо вы не являетесь владельцем")
 	}
 	return nil
 }
+
+// GetSubtasks возвращает все подзадачи задачи ownerID на любой глубине, упорядоченные по id.
+func (q *TaskQueries) GetSubtasks(taskID, ownerID int64) ([]model.Task, error) {
+	return q.queryTasks(getSubtreeQuery, taskID, ownerID)
+}
+
+// GetProgressByTaskIDs считает прогресс по прямым подзадачам: task_id -> прогресс.
+// Задачи без подзадач в результат не попадают.
+func (q *TaskQueries) GetProgressByTaskIDs(taskIDs []int64) (map[int64]model.Progress, error) {
+	rows, err := q.db.Query(getProgressByTaskIDsQuery, pq.Array(taskIDs))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения прогресса задач: %v", err)
+	}
+	defer rows.Close()
+
+	progress := make(map[int64]model.Progress)
+	for rows.Next() {
+		var taskID int64
+		var total, done int
+		if err := rows.Scan(&taskID, &total, &done); err != nil {
+			return nil, fmt.Errorf("ошибка чтения прогресса из строки: %v", err)
+		}
+		progress[taskID] = *model.NewProgress(total, done)
+	}
+	return progress, nil
+}
+
+// toTSQuery собирает строку для to_tsquery: термы объединяются через &,
+// слова фразы — через <->, префикс помечается :*. Слова содержат только
+// буквы и цифры, поэтому экранирование не требуется.
+func toTSQuery(query model.SearchQuery) string {
+	terms := make([]string, 0, len(query.Terms))
+	for _, term := range query.Terms {
+		t := strings.Join(term.Words, " <-> ")
+		if term.Prefix {
+			t += ":*"
+		}
+		if len(term.Words) > 1 {
+			t = "(" + t + ")"
+		}
+		terms = append(terms, t)
+	}
+	return strings.Join(terms, " & ")
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE, чтобы искать подстроку буквально.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (q *TaskQueries) queryTasks(query string, args ...any) ([]model.Task, error) {
+	rows, err := q.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения задач: %v", err)
+	}
+	defer rows.Close()
+
+	var tasks []model.Task
+	for rows.Next() {
+		var task model.Task
+		if err := scanTask(rows, &task); err != nil {
+			return nil, fmt.Errorf("ошибка чтения задачи из строки: %v", err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
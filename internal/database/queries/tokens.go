@@ -0,0 +1,267 @@
+package queries
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"github.com/lib/pq"
+	"go.mood/internal/model"
+	"time"
+)
+
+//go:embed sql/token/create_refresh.sql
+var createRefreshTokenQuery string
+
+//go:embed sql/token/get_refresh_by_hash.sql
+var getRefreshTokenByHashQuery string
+
+//go:embed sql/token/revoke_refresh.sql
+var revokeRefreshTokenQuery string
+
+//go:embed sql/token/revoke_family.sql
+var revokeRefreshTokenFamilyQuery string
+
+//go:embed sql/token/revoke_family_access.sql
+var revokeFamilyAccessTokensQuery string
+
+//go:embed sql/token/revoke_by_user_id.sql
+var revokeRefreshTokensByUserIDQuery string
+
+//go:embed sql/token/revoke_user_access.sql
+var revokeUserAccessTokensQuery string
+
+//go:embed sql/token/revoke_access.sql
+var revokeAccessTokenQuery string
+
+//go:embed sql/token/delete_expired_revoked.sql
+var deleteExpiredRevokedTokensQuery string
+
+//go:embed sql/token/is_access_revoked.sql
+var isAccessTokenRevokedQuery string
+
+//go:embed sql/token/create_user_token.sql
+var createUserTokenQuery string
+
+//go:embed sql/token/invalidate_user_tokens.sql
+var invalidateUserTokensQuery string
+
+//go:embed sql/token/get_user_token.sql
+var getUserTokenQuery string
+
+//go:embed sql/token/consume_user_token.sql
+var consumeUserTokenQuery string
+
+//go:embed sql/token/get_family_by_access_jti.sql
+var getFamilyByAccessJTIQuery string
+
+//go:embed sql/token/revoke_other_access.sql
+var revokeOtherAccessTokensQuery string
+
+//go:embed sql/token/revoke_others.sql
+var revokeOtherRefreshTokensQuery string
+
+//go:embed sql/token/revoke_family_session.sql
+var revokeFamilySessionQuery string
+
+//go:embed sql/token/revoke_user_sessions.sql
+var revokeUserSessionsQuery string
+
+//go:embed sql/token/revoke_other_sessions.sql
+var revokeOtherSessionsQuery string
+
+//go:embed sql/token/extend_session.sql
+var extendSessionQuery string
+
+// TokenQueries содержит методы для работы с refresh-токенами и отзывом access-токенов в БД.
+type TokenQueries struct {
+	db *sql.DB
+}
+
+// NewTokenQueries создает новый экземпляр TokenQueries.
+func NewTokenQueries(db *sql.DB) *TokenQueries {
+	return &TokenQueries{db: db}
+}
+
+// GetRefreshTokenByHash получает refresh-токен по SHA-256 от его значения.
+func (q *TokenQueries) GetRefreshTokenByHash(hash string) (model.RefreshToken, error) {
+	var t model.RefreshToken
+	var scopes []string
+	err := q.db.QueryRow(getRefreshTokenByHashQuery, hash).Scan(&t.Id, &t.UserId, &t.TokenHash, &t.FamilyID,
+		&t.AccessJTI, &t.AccessExpiresAt, &t.ExpiresAt, &t.CreatedAt, &t.RevokedAt, &t.ClientID, pq.Array(&scopes))
+	t.Scopes = model.ParseScopes(scopes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return t, model.ErrUnknownRefreshToken
+		}
+		return t, fmt.Errorf("ошибка получения refresh-токена: %v", err)
+	}
+	return t, nil
+}
+
+// RotateRefreshToken отзывает токен oldID и сохраняет следующий токен цепочки в одной транзакции;
+// сессия цепочки продлевается до срока нового токена. Если oldID уже отозван (токен использован повторно), возвращает model.ErrRefreshTokenReused.
+func (q *TokenQueries) RotateRefreshToken(oldID int64, next *model.RefreshToken, now time.Time) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(revokeRefreshTokenQuery, oldID, now)
+	if err != nil {
+		return fmt.Errorf("ошибка отзыва refresh-токена: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrRefreshTokenReused
+	}
+	if err := insertRefreshToken(tx, next); err != nil {
+		return fmt.Errorf("ошибка создания refresh-токена: %v", err)
+	}
+	if _, err := tx.Exec(extendSessionQuery, next.FamilyID, now, next.ExpiresAt); err != nil {
+		return fmt.Errorf("ошибка продления сессии: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// RevokeRefreshTokenFamily отзывает все refresh-токены цепочки и access-токены, выданные
+// вместе с ними, и завершает сессию цепочки.
+func (q *TokenQueries) RevokeRefreshTokenFamily(familyID string, now time.Time) error {
+	return q.revokeAll(revokeFamilyAccessTokensQuery, revokeRefreshTokenFamilyQuery, revokeFamilySessionQuery, familyID, now)
+}
+
+// RevokeOtherRefreshTokens отзывает refresh-токены пользователя и выданные вместе с ними
+// access-токены и завершает сессии во всех цепочках, кроме keepFamilyID (текущей сессии).
+func (q *TokenQueries) RevokeOtherRefreshTokens(userID int64, keepFamilyID string, now time.Time) error {
+	return q.revokeAll(revokeOtherAccessTokensQuery, revokeOtherRefreshTokensQuery, revokeOtherSessionsQuery, userID, now, keepFamilyID)
+}
+
+// GetRefreshTokenFamilyByAccessJTI получает цепочку refresh-токенов, с которой был выдан access-токен jti.
+func (q *TokenQueries) GetRefreshTokenFamilyByAccessJTI(jti string) (string, error) {
+	var familyID string
+	if err := q.db.QueryRow(getFamilyByAccessJTIQuery, jti).Scan(&familyID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", model.ErrUnknownRefreshToken
+		}
+		return "", fmt.Errorf("ошибка получения refresh-токена: %v", err)
+	}
+	return familyID, nil
+}
+
+// RevokeRefreshTokensByUserID отзывает все refresh-токены пользователя и выданные вместе с ними
+// access-токены и завершает все его сессии.
+func (q *TokenQueries) RevokeRefreshTokensByUserID(userID int64, now time.Time) error {
+	return q.revokeAll(revokeUserAccessTokensQuery, revokeRefreshTokensByUserIDQuery, revokeUserSessionsQuery, userID, now)
+}
+
+// RevokeAccessToken запоминает jti отозванного access-токена до момента expiresAt.
+// Заодно удаляются записи об уже истёкших токенах.
+func (q *TokenQueries) RevokeAccessToken(jti string, expiresAt, now time.Time) error {
+	if _, err := q.db.Exec(deleteExpiredRevokedTokensQuery, now); err != nil {
+		return fmt.Errorf("ошибка очистки отозванных токенов: %v", err)
+	}
+	if _, err := q.db.Exec(revokeAccessTokenQuery, jti, expiresAt); err != nil {
+		return fmt.Errorf("ошибка отзыва токена: %v", err)
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked сообщает, отозван ли access-токен с данным jti.
+func (q *TokenQueries) IsAccessTokenRevoked(jti string) (bool, error) {
+	var revoked bool
+	if err := q.db.QueryRow(isAccessTokenRevokedQuery, jti).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("ошибка проверки отзыва токена: %v", err)
+	}
+	return revoked, nil
+}
+
+// CreateUserToken сохраняет одноразовый токен; ранее выданные неиспользованные
+// токены пользователя с тем же назначением становятся недействительными.
+func (q *TokenQueries) CreateUserToken(t *model.UserToken) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(invalidateUserTokensQuery, t.UserId, t.Purpose, t.CreatedAt); err != nil {
+		return fmt.Errorf("ошибка отзыва старых токенов: %v", err)
+	}
+	if err := tx.QueryRow(createUserTokenQuery, t.UserId, t.Purpose, t.TokenHash, t.NewEmail, t.ExpiresAt, t.CreatedAt).Scan(&t.Id); err != nil {
+		return fmt.Errorf("ошибка создания токена: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// GetUserToken возвращает действующий токен, не отмечая его использованным.
+// Неизвестный, чужого назначения, истёкший или уже использованный токен — model.ErrInvalidUserToken.
+func (q *TokenQueries) GetUserToken(hash string, purpose model.TokenPurpose, now time.Time) (model.UserToken, error) {
+	t, err := q.userToken(getUserTokenQuery, hash, purpose, now)
+	if err != nil && err != model.ErrInvalidUserToken {
+		return t, fmt.Errorf("ошибка получения токена: %v", err)
+	}
+	return t, err
+}
+
+// ConsumeUserToken отмечает токен использованным и возвращает его. Неизвестный, чужого
+// назначения, истёкший или уже использованный токен — model.ErrInvalidUserToken.
+func (q *TokenQueries) ConsumeUserToken(hash string, purpose model.TokenPurpose, now time.Time) (model.UserToken, error) {
+	t, err := q.userToken(consumeUserTokenQuery, hash, purpose, now)
+	if err != nil && err != model.ErrInvalidUserToken {
+		return t, fmt.Errorf("ошибка использования токена: %v", err)
+	}
+	return t, err
+}
+
+// userToken выполняет запрос, возвращающий один токен из письма.
+func (q *TokenQueries) userToken(query, hash string, purpose model.TokenPurpose, now time.Time) (model.UserToken, error) {
+	var t model.UserToken
+	err := q.db.QueryRow(query, hash, purpose, now).Scan(&t.Id, &t.UserId, &t.Purpose, &t.TokenHash,
+		&t.NewEmail, &t.ExpiresAt, &t.CreatedAt, &t.UsedAt)
+	if err == sql.ErrNoRows {
+		return t, model.ErrInvalidUserToken
+	}
+	return t, err
+}
+
+// revokeAll в одной транзакции запоминает jti access-токенов, отзывает refresh-токены
+// и завершает их сессии. Все запросы получают одинаковые параметры args.
+func (q *TokenQueries) revokeAll(accessQuery, refreshQuery, sessionQuery string, args ...any) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(accessQuery, args...); err != nil {
+		return fmt.Errorf("ошибка отзыва access-токенов: %v", err)
+	}
+	if _, err := tx.Exec(refreshQuery, args...); err != nil {
+		return fmt.Errorf("ошибка отзыва refresh-токенов: %v", err)
+	}
+	if _, err := tx.Exec(sessionQuery, args...); err != nil {
+		return fmt.Errorf("ошибка завершения сессий: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// queryRower — общий интерфейс *sql.DB и *sql.Tx для одиночных запросов.
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func insertRefreshToken(db queryRower, t *model.RefreshToken) error {
+	return db.QueryRow(createRefreshTokenQuery, t.UserId, t.TokenHash, t.FamilyID, t.AccessJTI,
+		t.AccessExpiresAt, t.ExpiresAt, t.CreatedAt, t.ClientID, pq.Array(model.ScopeStrings(t.Scopes))).Scan(&t.Id)
+}
@@ -5,6 +5,8 @@ import (
 	_ "embed"
 	"fmt"
 	"go.mood/internal/model"
+	"strings"
+	"time"
 )
 
 //go:embed sql/user/get_by_username.sql
@@ -13,8 +15,8 @@ var getUserByUsernameQuery string
 //go:embed sql/user/create.sql
 var createUserQuery string
 
-//go:embed sql/user/get_all.sql
-var getAllUsersQuery string
+//go:embed sql/user/list.sql
+var listUsersQuery string
 
 //go:embed sql/user/delete_by_id.sql
 var deleteUserByIDQuery string
@@ -22,6 +24,45 @@ var deleteUserByIDQuery string
 //go:embed sql/user/get_by_id.sql
 var getUserByIDQuery string
 
+//go:embed sql/user/get_by_email.sql
+var getUserByEmailQuery string
+
+//go:embed sql/user/update_password.sql
+var updatePasswordQuery string
+
+//go:embed sql/user/mark_email_verified.sql
+var markEmailVerifiedQuery string
+
+//go:embed sql/user/increment_failed_logins.sql
+var incrementFailedLoginsQuery string
+
+//go:embed sql/user/lock.sql
+var lockUserQuery string
+
+//go:embed sql/user/record_login.sql
+var recordLoginQuery string
+
+//go:embed sql/user/unlock.sql
+var unlockUserQuery string
+
+//go:embed sql/user/update_role.sql
+var updateUserRoleQuery string
+
+//go:embed sql/user/update_username.sql
+var updateUsernameQuery string
+
+//go:embed sql/user/update_email.sql
+var updateEmailQuery string
+
+//go:embed sql/user/count_by_role.sql
+var countUsersByRoleQuery string
+
+//go:embed sql/user/suspend.sql
+var suspendUserQuery string
+
+//go:embed sql/user/reactivate.sql
+var reactivateUserQuery string
+
 ////go:embed sql/task/create.sql
 //var createTaskQuery string
 
@@ -52,13 +93,16 @@ func (q *UserQueries) CreateUserAndInitialTask(user *model.User, task *model.Tas
 	// Первая операция в транзакции: создание пользователя.
 	// Используем tx.QueryRow вместо q.db.QueryRow.
 	if err := tx.QueryRow(createUserQuery, user.Username, user.Email, user.PasswordHash, user.Role).Scan(&user.Id); err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrEmailTaken
+		}
 		return fmt.Errorf("ошибка создания пользователя в транзакции: %w", err)
 	}
 
 	// Вторая операция в транзакции: создание задачи для этого пользователя.
 	// Используем tx.Exec вместо q.db.Exec.
 	// Мы используем user.Id, который только что получили от базы данных.
-	if _, err := tx.Exec(createTaskQuery, user.Id, task.Task); err != nil {
+	if _, err := tx.Exec(createTaskQuery, user.Id, task.Task, task.DueAt, task.Priority, task.ProjectID, task.ParentID, task.SeriesID); err != nil {
 		return fmt.Errorf("ошибка создания задачи в транзакции: %w", err)
 	}
 
@@ -75,7 +119,7 @@ func (q *UserQueries) CreateUserAndInitialTask(user *model.User, task *model.Tas
 func (q *UserQueries) GetUserByUsername(username string) (model.User, error) {
 	row := q.db.QueryRow(getUserByUsernameQuery, username)
 	var user model.User
-	if err := row.Scan(&user.Id, &user.Username, &user.Email, &user.PasswordHash, &user.Role, &user.CreateTime); err != nil {
+	if err := scanUser(row, &user); err != nil {
 		if err == sql.ErrNoRows {
 			return user, fmt.Errorf("пользователь не найден")
 		}
@@ -84,17 +128,54 @@ func (q *UserQueries) GetUserByUsername(username string) (model.User, error) {
 	return user, nil
 }
 
-// CreateUser создает нового пользователя в БД.
+// CreateUser создает нового пользователя в БД. Занятый email — model.ErrEmailTaken.
 func (q *UserQueries) CreateUser(user *model.User) error {
 	if err := q.db.QueryRow(createUserQuery, user.Username, user.Email, user.PasswordHash, user.Role).Scan(&user.Id); err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrEmailTaken
+		}
 		return fmt.Errorf("ошибка создания пользователя: %v", err)
 	}
 	return nil
 }
 
-// GetAllUsers получает всех пользователей.
-func (q *UserQueries) GetAllUsers() ([]model.User, error) {
-	rows, err := q.db.Query(getAllUsersQuery)
+// ListUsers возвращает пользователей по фильтру в порядке id, начиная строго после filter.AfterID.
+func (q *UserQueries) ListUsers(filter model.UserFilter) ([]model.User, error) {
+	var sb strings.Builder
+	sb.WriteString(listUsersQuery)
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	sb.WriteString(" WHERE id > " + arg(filter.AfterID))
+	if filter.Search != "" {
+		pattern := arg(escapeLike(filter.Search))
+		fmt.Fprintf(&sb, ` AND (user_name ILIKE '%%' || %s || '%%' OR email ILIKE '%%' || %s || '%%')`, pattern, pattern)
+	}
+	if filter.Role != "" {
+		sb.WriteString(" AND role = " + arg(filter.Role))
+	}
+	if filter.Suspended != nil {
+		if *filter.Suspended {
+			sb.WriteString(" AND suspended_at IS NOT NULL")
+		} else {
+			sb.WriteString(" AND suspended_at IS NULL")
+		}
+	}
+	if filter.CreatedFrom != nil {
+		sb.WriteString(" AND created_at >= " + arg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		sb.WriteString(" AND created_at < " + arg(*filter.CreatedTo))
+	}
+	sb.WriteString(" ORDER BY id")
+	if filter.Limit > 0 {
+		sb.WriteString(" LIMIT " + arg(filter.Limit))
+	}
+
+	rows, err := q.db.Query(sb.String(), args...)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка получения списка пользователей: %v", err)
 	}
@@ -103,7 +184,7 @@ func (q *UserQueries) GetAllUsers() ([]model.User, error) {
 	var users []model.User
 	for rows.Next() {
 		var user model.User
-		if err := rows.Scan(&user.Id, &user.Username, &user.Email, &user.PasswordHash, &user.Role, &user.CreateTime); err != nil {
+		if err := scanUser(rows, &user); err != nil {
 			return nil, fmt.Errorf("ошибка : %v", err)
 		}
 		users = append(users, user)
@@ -124,11 +205,132 @@ func (q *UserQueries) GetUserByID(id int64) (model.User, error) {
 	row := q.db.QueryRow(getUserByIDQuery, id)
 
 	var user model.User
-	if err := row.Scan(&user.Id, &user.Username, &user.Email, &user.PasswordHash, &user.Role, &user.CreateTime); err != nil {
+	if err := scanUser(row, &user); err != nil {
 		if err == sql.ErrNoRows {
-			return user, fmt.Errorf("пользователь с id %d не найден", id)
+			return user, model.ErrUnknownUser
 		}
 		return user, fmt.Errorf("ошибка получения пользователя: %v", err)
 	}
 	return user, nil
 }
+
+// GetUserByEmail получает пользователя по email.
+func (q *UserQueries) GetUserByEmail(email string) (model.User, error) {
+	var user model.User
+	if err := scanUser(q.db.QueryRow(getUserByEmailQuery, email), &user); err != nil {
+		if err == sql.ErrNoRows {
+			return user, fmt.Errorf("пользователь не найден")
+		}
+		return user, fmt.Errorf("ошибка получения пользователя: %v", err)
+	}
+	return user, nil
+}
+
+// UpdatePassword заменяет хеш пароля пользователя.
+func (q *UserQueries) UpdatePassword(id int64, passwordHash string) error {
+	if _, err := q.db.Exec(updatePasswordQuery, id, passwordHash); err != nil {
+		return fmt.Errorf("ошибка обновления пароля: %v", err)
+	}
+	return nil
+}
+
+// MarkEmailVerified отмечает email пользователя подтверждённым, если это ещё не сделано.
+func (q *UserQueries) MarkEmailVerified(id int64, at time.Time) error {
+	if _, err := q.db.Exec(markEmailVerifiedQuery, id, at); err != nil {
+		return fmt.Errorf("ошибка подтверждения email: %v", err)
+	}
+	return nil
+}
+
+// IncrementFailedLogins увеличивает счётчик неудачных попыток входа и возвращает новое значение.
+func (q *UserQueries) IncrementFailedLogins(id int64) (int, error) {
+	var count int
+	if err := q.db.QueryRow(incrementFailedLoginsQuery, id).Scan(&count); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("пользователь с id %d не найден", id)
+		}
+		return 0, fmt.Errorf("ошибка учёта неудачного входа: %v", err)
+	}
+	return count, nil
+}
+
+// LockUser запрещает вход пользователю до момента until.
+func (q *UserQueries) LockUser(id int64, until time.Time) error {
+	if _, err := q.db.Exec(lockUserQuery, id, until); err != nil {
+		return fmt.Errorf("ошибка блокировки пользователя: %v", err)
+	}
+	return nil
+}
+
+// RecordLogin запоминает время успешного входа и сбрасывает счётчик неудачных попыток.
+func (q *UserQueries) RecordLogin(id int64, at time.Time) error {
+	if _, err := q.db.Exec(recordLoginQuery, id, at); err != nil {
+		return fmt.Errorf("ошибка учёта входа: %v", err)
+	}
+	return nil
+}
+
+// UnlockUser снимает блокировку входа и сбрасывает счётчик неудачных попыток.
+func (q *UserQueries) UnlockUser(id int64) error {
+	if _, err := q.db.Exec(unlockUserQuery, id); err != nil {
+		return fmt.Errorf("ошибка разблокировки пользователя: %v", err)
+	}
+	return nil
+}
+
+// UpdateRole назначает пользователю роль.
+func (q *UserQueries) UpdateRole(id int64, role string) error {
+	if _, err := q.db.Exec(updateUserRoleQuery, id, role); err != nil {
+		return fmt.Errorf("ошибка назначения роли: %v", err)
+	}
+	return nil
+}
+
+// UpdateUsername меняет имя пользователя.
+func (q *UserQueries) UpdateUsername(id int64, username string) error {
+	if _, err := q.db.Exec(updateUsernameQuery, id, username); err != nil {
+		return fmt.Errorf("ошибка изменения имени пользователя: %v", err)
+	}
+	return nil
+}
+
+// UpdateEmail меняет email пользователя и отметку о его подтверждении.
+func (q *UserQueries) UpdateEmail(id int64, email string, verifiedAt *time.Time) error {
+	if _, err := q.db.Exec(updateEmailQuery, id, email, verifiedAt); err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrEmailTaken
+		}
+		return fmt.Errorf("ошибка изменения email: %v", err)
+	}
+	return nil
+}
+
+// CountUsersByRole считает пользователей с ролью role.
+func (q *UserQueries) CountUsersByRole(role string) (int, error) {
+	var count int
+	if err := q.db.QueryRow(countUsersByRoleQuery, role).Scan(&count); err != nil {
+		return 0, fmt.Errorf("ошибка подсчёта пользователей: %v", err)
+	}
+	return count, nil
+}
+
+// SuspendUser приостанавливает аккаунт пользователя с момента at.
+func (q *UserQueries) SuspendUser(id int64, at time.Time) error {
+	if _, err := q.db.Exec(suspendUserQuery, id, at); err != nil {
+		return fmt.Errorf("ошибка приостановки пользователя: %v", err)
+	}
+	return nil
+}
+
+// ReactivateUser снимает приостановку аккаунта.
+func (q *UserQueries) ReactivateUser(id int64) error {
+	if _, err := q.db.Exec(reactivateUserQuery, id); err != nil {
+		return fmt.Errorf("ошибка восстановления пользователя: %v", err)
+	}
+	return nil
+}
+
+func scanUser(row rowScanner, user *model.User) error {
+	return row.Scan(&user.Id, &user.Username, &user.Email, &user.PasswordHash, &user.Role, &user.CreateTime, &user.EmailVerifiedAt,
+		&user.LastLoginAt, &user.FailedLoginCount, &user.LockedUntil, &user.SuspendedAt)
+}
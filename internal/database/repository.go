@@ -0,0 +1,151 @@
+package database
+
+import (
+	"go.mood/internal/model"
+	"time"
+)
+
+// UserRepository — хранилище пользователей.
+type UserRepository interface {
+	CreateUserAndInitialTask(user *model.User, task *model.Task) error
+	GetUserByUsername(username string) (model.User, error)
+	CreateUser(user *model.User) error
+	ListUsers(filter model.UserFilter) ([]model.User, error)
+	DeleteUserByID(id int64) error
+	GetUserByID(id int64) (model.User, error)
+	GetUserByEmail(email string) (model.User, error)
+	UpdatePassword(id int64, passwordHash string) error
+	MarkEmailVerified(id int64, at time.Time) error
+	IncrementFailedLogins(id int64) (int, error)
+	LockUser(id int64, until time.Time) error
+	RecordLogin(id int64, at time.Time) error
+	UnlockUser(id int64) error
+	UpdateRole(id int64, role string) error
+	UpdateUsername(id int64, username string) error
+	UpdateEmail(id int64, email string, verifiedAt *time.Time) error
+	CountUsersByRole(role string) (int, error)
+	SuspendUser(id int64, at time.Time) error
+	ReactivateUser(id int64) error
+}
+
+// TaskRepository — хранилище задач.
+type TaskRepository interface {
+	CreateTasksInBulk(tasks []*model.Task) error
+	GetAllTasks() ([]model.Task, error)
+	GetTasksByUserID(userID int64) ([]model.Task, error)
+	GetTaskByID(id int64) (model.Task, error)
+	CreateTask(task *model.Task) error
+	UpdateTaskByIDWithOwner(id int64, task *model.Task, ownerID int64) error
+	DeleteTaskByIDWithOwner(id int64, ownerID int64, cascade bool) error
+	UpdateTaskStatus(taskID int64, userID int64, status model.TaskStatus, cascade bool) error
+	GetTasksDueBetween(userID int64, from, to time.Time) ([]model.Task, error)
+	GetOverdueTasks(userID int64, now time.Time) ([]model.Task, error)
+	ListTasks(filter model.TaskFilter) ([]model.Task, error)
+	SearchTasks(userID int64, query model.SearchQuery, limit int) ([]model.TaskSearchResult, error)
+	MoveTaskToProject(taskID, ownerID int64, projectID *int64) error
+	GetSubtasks(taskID, ownerID int64) ([]model.Task, error)
+	GetProgressByTaskIDs(taskIDs []int64) (map[int64]model.Progress, error)
+}
+
+// TagRepository — хранилище меток задач.
+type TagRepository interface {
+	CreateTag(tag *model.Tag) error
+	GetTagsByUserID(userID int64) ([]model.Tag, error)
+	GetTagByIDWithOwner(id, ownerID int64) (model.Tag, error)
+	UpdateTagByIDWithOwner(id int64, tag *model.Tag, ownerID int64) error
+	DeleteTagByIDWithOwner(id, ownerID int64) error
+	GetTagsByTaskIDs(taskIDs []int64) (map[int64][]model.Tag, error)
+}
+
+// ProjectRepository — хранилище проектов, группирующих задачи.
+type ProjectRepository interface {
+	CreateProject(p *model.Project) error
+	GetProjectsByUserID(userID int64, withArchived bool) ([]model.Project, error)
+	GetProjectByIDWithOwner(id, ownerID int64) (model.Project, error)
+	UpdateProjectByIDWithOwner(id int64, p *model.Project, ownerID int64) error
+	SetProjectArchived(id, ownerID int64, archived bool) error
+	DeleteProjectByIDWithOwner(id, ownerID int64) error
+}
+
+// SeriesRepository — хранилище серий повторяющихся задач. Серия создаётся
+// вместе с первой задачей в TaskRepository.CreateTask.
+type SeriesRepository interface {
+	GetSeriesByIDWithOwner(id, ownerID int64) (model.TaskSeries, error)
+	UpdateSeriesByIDWithOwner(id int64, update model.SeriesUpdate, ownerID int64) error
+	StopSeries(id, ownerID int64) error
+}
+
+// TokenRepository — хранилище refresh-токенов, отозванных access-токенов (по jti)
+// и одноразовых токенов из писем.
+type TokenRepository interface {
+	GetRefreshTokenByHash(hash string) (model.RefreshToken, error)
+	RotateRefreshToken(oldID int64, next *model.RefreshToken, now time.Time) error
+	RevokeRefreshTokenFamily(familyID string, now time.Time) error
+	RevokeRefreshTokensByUserID(userID int64, now time.Time) error
+	RevokeAccessToken(jti string, expiresAt, now time.Time) error
+	IsAccessTokenRevoked(jti string) (bool, error)
+	CreateUserToken(t *model.UserToken) error
+	GetUserToken(hash string, purpose model.TokenPurpose, now time.Time) (model.UserToken, error)
+	ConsumeUserToken(hash string, purpose model.TokenPurpose, now time.Time) (model.UserToken, error)
+	GetRefreshTokenFamilyByAccessJTI(jti string) (string, error)
+	RevokeOtherRefreshTokens(userID int64, keepFamilyID string, now time.Time) error
+}
+
+// MFARepository — хранилище настроек TOTP и кодов восстановления.
+type MFARepository interface {
+	GetMFA(userID int64) (model.MFA, error)
+	SaveMFASecret(userID int64, secret string) error
+	ConfirmMFA(userID, counter int64, codeHashes []string, at time.Time) error
+	UseTOTPCounter(userID, counter int64) (bool, error)
+	GetRecoveryCodes(userID int64) ([]model.RecoveryCode, error)
+	UseRecoveryCode(id int64, at time.Time) (bool, error)
+	ReplaceRecoveryCodes(userID int64, codeHashes []string) error
+	DeleteMFA(userID int64) error
+}
+
+// APIKeyRepository — хранилище персональных API-ключей.
+type APIKeyRepository interface {
+	CreateAPIKey(key *model.APIKey) error
+	GetAPIKeysByUserID(userID int64) ([]model.APIKey, error)
+	GetAPIKeyByHash(hash string) (model.APIKey, error)
+	RevokeAPIKeyWithOwner(id, ownerID int64, now time.Time) error
+	TouchAPIKey(id int64, now time.Time) error
+}
+
+// RoleRepository — хранилище ролей и их прав.
+type RoleRepository interface {
+	GetRoles() ([]model.RoleDefinition, error)
+	GetRole(name string) (model.RoleDefinition, error)
+	CreateRole(role *model.RoleDefinition) error
+	UpdateRole(name, description string, permissions []model.Permission) error
+	DeleteRole(name string) error
+	GetUserPermissions(userID int64) ([]model.Permission, error)
+}
+
+// SessionRepository — хранилище сессий: входов пользователей на устройствах и доступов приложений.
+// Сессия завершается вместе со своей цепочкой refresh-токенов (см. TokenRepository).
+type SessionRepository interface {
+	CreateSession(session *model.Session, first *model.RefreshToken) error
+	GetSession(id string) (model.Session, error)
+	GetSessionsByUserID(userID int64, now time.Time) ([]model.Session, error)
+	TouchSession(id string, now time.Time) error
+}
+
+// IdentityRepository — хранилище учётных записей внешних провайдеров OpenID Connect.
+type IdentityRepository interface {
+	GetIdentity(provider, subject string) (model.Identity, error)
+	CreateIdentity(identity *model.Identity) error
+	CreateUserWithIdentity(user *model.User, identity *model.Identity) error
+	TouchIdentity(id int64, at time.Time) error
+}
+
+// OAuthRepository — хранилище сторонних приложений OAuth2 и кодов авторизации.
+// Refresh-токены приложений лежат в TokenRepository вместе с остальными.
+type OAuthRepository interface {
+	CreateClient(c *model.OAuthClient) error
+	GetClients() ([]model.OAuthClient, error)
+	GetClient(id string) (model.OAuthClient, error)
+	DeleteClient(id string, now time.Time) error
+	CreateCode(c *model.OAuthCode) error
+	ConsumeCode(hash, familyID string, now time.Time) (model.OAuthCode, error)
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go.mood/internal/model"
+	"go.mood/internal/service"
+	"go.mood/pkg"
+	"net/http"
+)
+
+// ForgotPasswordHandler — отправка письма для сброса пароля
+func (h *Handlers) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	var in struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Email == "" {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("нужно передать email"))
+		return
+	}
+
+	if err := h.service.AccountService.RequestPasswordReset(in.Email); err != nil {
+		logError("Ошибка отправки письма для сброса пароля: %v", err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось отправить письмо"))
+		return
+	}
+
+	// Ответ одинаковый для известных и неизвестных адресов
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Если этот email зарегистрирован, на него отправлено письмо"})
+}
+
+// ResetPasswordHandler — установка нового пароля по токену из письма
+func (h *Handlers) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	var in struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Token == "" || in.Password == "" {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("нужно передать token и password"))
+		return
+	}
+
+	err := h.service.AccountService.ResetPassword(in.Token, in.Password)
+	var invalid *service.ValidationError
+	switch {
+	case errors.Is(err, model.ErrInvalidUserToken):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	case errors.As(err, &invalid):
+		pkg.WriteJSONResponse(w, http.StatusUnprocessableEntity, invalid)
+		return
+	case err != nil:
+		logError("Ошибка сброса пароля: %v", err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось сменить пароль"))
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Пароль изменён, войдите заново"})
+}
+
+// VerifyEmailHandler — подтверждение email по ссылке из письма (?token=...)
+func (h *Handlers) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("нужно передать token"))
+		return
+	}
+
+	err := h.service.AccountService.VerifyEmail(token)
+	switch {
+	case errors.Is(err, model.ErrInvalidUserToken):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		logError("Ошибка подтверждения email: %v", err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось подтвердить email"))
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Email подтверждён"})
+}
+
+// ResendVerificationHandler — повторная отправка письма для подтверждения email
+func (h *Handlers) ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	var in struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Email == "" {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("нужно передать email"))
+		return
+	}
+
+	if err := h.service.AccountService.ResendEmailVerification(in.Email); err != nil {
+		logError("Ошибка отправки письма для подтверждения email: %v", err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось отправить письмо"))
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Если этот email зарегистрирован и не подтверждён, на него отправлено письмо"})
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go.mood/internal/middleware"
+	"go.mood/internal/model"
+	"go.mood/internal/service"
+	"go.mood/pkg"
+	"net/http"
+)
+
+// GetAPIKeysHandler — API-ключи текущего пользователя (без самих ключей)
+func (h *Handlers) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	keys, err := h.service.APIKeyService.GetAPIKeys(userID)
+	if err != nil {
+		writeAPIKeyError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, keys)
+}
+
+// CreateAPIKeyHandler — создание API-ключа; ключ возвращается только в этом ответе
+func (h *Handlers) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	var input model.NewAPIKey
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных"))
+		return
+	}
+
+	key, err := h.service.APIKeyService.CreateAPIKey(userID, &input)
+	if err != nil {
+		writeAPIKeyError(w, userID, err)
+		return
+	}
+	logInfo("Пользователь %d создал API-ключ %s", userID, key.Prefix)
+	pkg.WriteJSONResponse(w, http.StatusCreated, key)
+}
+
+// RevokeAPIKeyHandler — отзыв API-ключа
+func (h *Handlers) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("неверный ID ключа"))
+		return
+	}
+
+	if err := h.service.APIKeyService.RevokeAPIKey(id, userID); err != nil {
+		writeAPIKeyError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "API-ключ отозван"})
+}
+
+// writeAPIKeyError пишет ответ с подходящим статусом для ошибок API-ключей.
+func writeAPIKeyError(w http.ResponseWriter, userID int64, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAPIKey):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+	case errors.Is(err, model.ErrUnknownAPIKey):
+		pkg.WriteJSONResponse(w, http.StatusNotFound, model.ErrUnknownAPIKey)
+	default:
+		logError("Ошибка работы с API-ключами пользователя %d: %v", userID, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+	}
+}
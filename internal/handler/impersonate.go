@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"go.mood/internal/middleware"
+	"go.mood/internal/service"
+	"go.mood/pkg"
+	"net/http"
+)
+
+// ImpersonateHandler — короткоживущий токен, с которым администратор видит сервис глазами пользователя
+func (h *Handlers) ImpersonateHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("неверный ID пользователя"))
+		return
+	}
+
+	actorID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	token, err := h.service.UserService.Impersonate(id, actorID)
+	if err != nil {
+		if errors.Is(err, service.ErrImpersonateSelf) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		if errors.Is(err, service.ErrAccountSuspended) {
+			pkg.WriteJSONResponse(w, http.StatusConflict, err)
+			return
+		}
+		writeUserError(w, err)
+		return
+	}
+	logWarn("Администратор %d вошёл от имени пользователя %d", actorID, id)
+	pkg.WriteJSONResponse(w, http.StatusOK, token)
+}
+
+// auditImpersonation пишет в лог каждый запрос, выполненный администратором от имени пользователя.
+func auditImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if actorID, ok := middleware.GetActorID(r.Context()); ok {
+			userID, _ := middleware.GetUserID(r.Context())
+			logWarn("Имперсонация: администратор %d от имени пользователя %d: %s %s", actorID, userID, r.Method, r.URL.RequestURI())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
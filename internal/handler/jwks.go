@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"encoding/json"
+	"go.mood/pkg"
+	"net/http"
+)
+
+// JWKSHandler — открытые ключи подписи JWT (RFC 7517), чтобы другие сервисы могли проверять наши токены.
+// Ответ отдаётся без общего конверта: клиенты JWKS ждут объект {"keys": [...]}.
+func (h *Handlers) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Новый ключ публикуется при перечитывании каталога, поэтому кэшируем ненадолго
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(h.service.TokenService.JWKS()); err != nil {
+		logError("Ошибка при отдаче JWKS: %v", err)
+	}
+}
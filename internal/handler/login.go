@@ -3,9 +3,15 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"go.mood/internal/middleware"
 	"go.mood/internal/model"
+	"go.mood/internal/service"
 	"go.mood/pkg"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 // RegisterHandler — регистрация нового пользователя
@@ -22,15 +28,27 @@ func (h *Handlers) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Вся логика перенесена в сервис
 	user, err := h.service.UserService.RegisterUser(&input)
-	if err != nil {
-		pkg.WriteJSONResponse(w, http.StatusInternalServerError, err)
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		pkg.WriteJSONResponse(w, http.StatusUnprocessableEntity, invalid)
+		return
+	case err != nil:
+		logError("Ошибка регистрации пользователя: %v", err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось зарегистрировать пользователя"))
 		return
 	}
 
-	pkg.WriteJSONResponse(w, http.StatusCreated, user)
+	// Регистрация не должна падать из-за почты — письмо можно запросить повторно
+	if err := h.service.AccountService.SendEmailVerification(*user); err != nil {
+		logWarn("Не удалось отправить письмо подтверждения пользователю %d: %v", user.Id, err)
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusCreated, user.View())
 }
 
-// LoginHandler — аутентификация и выдача JWT
+// LoginHandler — аутентификация и выдача access- и refresh-токенов
+// (или mfa_token, если у пользователя подключена 2FA)
 func (h *Handlers) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
 		return
@@ -47,13 +65,112 @@ func (h *Handlers) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Вся логика перенесена в сервис
-	token, err := h.service.UserService.LoginUser(in.Username, in.Password)
+	tokens, challenge, err := h.service.UserService.LoginUser(in.Username, in.Password, deviceInfo(r))
+	if writeLockedError(w, err) {
+		logWarn("Вход пользователя %q временно заблокирован: %v", in.Username, err)
+		return
+	}
+	if errors.Is(err, service.ErrEmailNotVerified) || errors.Is(err, service.ErrAccountSuspended) {
+		pkg.WriteJSONResponse(w, http.StatusForbidden, err)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, err)
+		return
+	}
 	if err != nil {
+		logError("Ошибка входа пользователя %q: %v", in.Username, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось выполнить вход"))
+		return
+	}
+
+	// С 2FA токены выдаются только после POST /login/mfa
+	if challenge != nil {
+		pkg.WriteJSONResponse(w, http.StatusOK, challenge)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, tokens)
+}
+
+// RefreshTokenHandler — обмен refresh-токена на новую пару токенов
+func (h *Handlers) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	var in struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.RefreshToken == "" {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("нужно передать refresh_token"))
+		return
+	}
+
+	tokens, err := h.service.TokenService.RefreshTokens(in.RefreshToken)
+	switch {
+	case errors.Is(err, model.ErrRefreshTokenReused):
+		logWarn("Повторное использование refresh-токена, цепочка отозвана")
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, err)
+		return
+	case errors.Is(err, service.ErrInvalidRefreshToken):
 		pkg.WriteJSONResponse(w, http.StatusUnauthorized, err)
 		return
+	case errors.Is(err, service.ErrAccountSuspended):
+		pkg.WriteJSONResponse(w, http.StatusForbidden, err)
+		return
+	case err != nil:
+		logError("Ошибка обновления токена: %v", err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось обновить токен"))
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, tokens)
+}
+
+// LogoutHandler — завершение текущей сессии; с refresh_token — и его цепочки,
+// с all=true — всех сессий пользователя
+func (h *Handlers) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	var in struct {
+		RefreshToken string `json:"refresh_token"`
+		All          bool   `json:"all"`
+	}
+	// Тело необязательно: без него отзывается только текущий access-токен
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("неверный формат данных"))
+			return
+		}
+	}
+
+	// Администратор, вошедший от имени пользователя, завершает только свой токен
+	if _, ok := middleware.GetActorID(r.Context()); ok && (in.All || in.RefreshToken != "") {
+		pkg.WriteJSONResponse(w, http.StatusForbidden, errors.New("при входе от имени пользователя можно отозвать только текущий токен"))
+		return
 	}
 
-	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"token": token})
+	ctx := r.Context()
+	err = h.service.TokenService.Logout(userID, middleware.GetTokenID(ctx), middleware.GetSessionID(ctx), in.RefreshToken, in.All)
+	switch {
+	case errors.Is(err, service.ErrInvalidRefreshToken):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		logError("Ошибка выхода пользователя %d: %v", userID, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось выйти"))
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Выход выполнен"})
 }
 
 //
@@ -144,3 +261,41 @@ func (h *Handlers) LoginHandler(w http.ResponseWriter, r *http.Request) {
 //
 //	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"token": signed})
 //}
+
+// writeLockedError отвечает 429 с Retry-After, если вход временно заблокирован.
+// Возвращает false для остальных ошибок.
+func writeLockedError(w http.ResponseWriter, err error) bool {
+	var locked *service.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	pkg.WriteJSONResponse(w, http.StatusTooManyRequests, err)
+	return true
+}
+
+// deviceInfo возвращает сведения об устройстве для новой сессии.
+func deviceInfo(r *http.Request) model.DeviceInfo {
+	return model.DeviceInfo{UserAgent: r.UserAgent(), IP: clientIP(r)}
+}
+
+// clientIP возвращает IP клиента. Сервер слушает только localhost, поэтому запрос
+// с loopback-адреса пришёл через обратный прокси — тогда берётся адрес, который
+// прокси передал в X-Real-IP или дописал последним в X-Forwarded-For.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return host
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		parts := strings.Split(forwarded, ",")
+		return strings.TrimSpace(parts[len(parts)-1])
+	}
+	return host
+}
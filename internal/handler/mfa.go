@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go.mood/internal/middleware"
+	"go.mood/internal/model"
+	"go.mood/internal/service"
+	"go.mood/pkg"
+	"net/http"
+)
+
+// LoginMFAHandler — второй шаг входа: обмен mfa_token и кода 2FA на токены
+func (h *Handlers) LoginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	var in struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.MFAToken == "" || in.Code == "" {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("нужно передать mfa_token и code"))
+		return
+	}
+
+	tokens, err := h.service.MFAService.VerifyLogin(in.MFAToken, in.Code, deviceInfo(r))
+	if writeLockedError(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrInvalidMFACode) || errors.Is(err, service.ErrInvalidMFAToken) {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, err)
+		return
+	}
+	if err != nil {
+		writeMFAError(w, 0, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, tokens)
+}
+
+// GetMFAStatusHandler — подключена ли 2FA и сколько осталось кодов восстановления
+func (h *Handlers) GetMFAStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	status, err := h.service.MFAService.GetMFAStatus(userID)
+	if err != nil {
+		writeMFAError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, status)
+}
+
+// EnrollMFAHandler — выдача секрета TOTP и ссылки otpauth:// для приложения
+func (h *Handlers) EnrollMFAHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	enrollment, err := h.service.MFAService.EnrollMFA(userID)
+	if err != nil {
+		writeMFAError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, enrollment)
+}
+
+// ConfirmMFAHandler — включение 2FA первым кодом из приложения
+func (h *Handlers) ConfirmMFAHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	userID, code, ok := decodeMFACode(w, r)
+	if !ok {
+		return
+	}
+
+	confirmation, err := h.service.MFAService.ConfirmMFA(userID, code, deviceInfo(r))
+	if err != nil {
+		writeMFAError(w, userID, err)
+		return
+	}
+	logInfo("Пользователь %d подключил 2FA", userID)
+	pkg.WriteJSONResponse(w, http.StatusOK, confirmation)
+}
+
+// DisableMFAHandler — отключение 2FA по коду TOTP или коду восстановления
+func (h *Handlers) DisableMFAHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	userID, code, ok := decodeMFACode(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.MFAService.DisableMFA(userID, code); err != nil {
+		writeMFAError(w, userID, err)
+		return
+	}
+	logInfo("Пользователь %d отключил 2FA", userID)
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Двухфакторная аутентификация отключена"})
+}
+
+// RegenerateRecoveryCodesHandler — новые коды восстановления взамен старых
+func (h *Handlers) RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	userID, code, ok := decodeMFACode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.service.MFAService.RegenerateRecoveryCodes(userID, code)
+	if err != nil {
+		writeMFAError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string][]string{"recovery_codes": codes})
+}
+
+// decodeMFACode достаёт пользователя из контекста и код из тела {"code": "..."}.
+// При ошибке ответ уже записан и возвращается false.
+func decodeMFACode(w http.ResponseWriter, r *http.Request) (int64, string, bool) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return 0, "", false
+	}
+
+	var in struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Code == "" {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("нужно передать code"))
+		return 0, "", false
+	}
+	return userID, in.Code, true
+}
+
+// writeMFAError пишет ответ с подходящим статусом для ошибок 2FA.
+func writeMFAError(w http.ResponseWriter, userID int64, err error) {
+	switch {
+	case errors.Is(err, service.ErrAccountSuspended):
+		pkg.WriteJSONResponse(w, http.StatusForbidden, err)
+	case errors.Is(err, service.ErrInvalidMFACode), errors.Is(err, model.ErrMFANotEnrolled):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		pkg.WriteJSONResponse(w, http.StatusConflict, err)
+	default:
+		logError("Ошибка 2FA пользователя %d: %v", userID, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("ошибка двухфакторной аутентификации"))
+	}
+}
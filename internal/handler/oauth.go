@@ -0,0 +1,305 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"go.mood/internal/middleware"
+	"go.mood/internal/model"
+	"go.mood/internal/service"
+	"go.mood/pkg"
+	"net/http"
+	"net/url"
+)
+
+// authorizeDecision — решение пользователя на экране согласия.
+type authorizeDecision struct {
+	model.AuthorizationRequest
+	Approve bool `json:"approve"`
+}
+
+// GetOAuthAuthorizeHandler — проверка запроса авторизации приложения и данные для
+// экрана согласия (параметры запроса — как в RFC 6749, п. 4.1.1)
+func (h *Handlers) GetOAuthAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := model.AuthorizationRequest{
+		ResponseType:        q.Get("response_type"),
+		ClientID:            q.Get("client_id"),
+		RedirectURI:         q.Get("redirect_uri"),
+		Scope:               q.Get("scope"),
+		State:               q.Get("state"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+	}
+	screen, err := h.service.OAuthService.PrepareAuthorization(&req)
+	if err != nil {
+		writeAuthorizeError(w, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, screen)
+}
+
+// OAuthAuthorizeHandler — решение пользователя: адрес, на который вернуть его в приложение
+// (с кодом авторизации или с error=access_denied)
+func (h *Handlers) OAuthAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	var input authorizeDecision
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных"))
+		return
+	}
+
+	redirect, err := h.service.OAuthService.Authorize(userID, &input.AuthorizationRequest, input.Approve)
+	if err != nil {
+		writeAuthorizeError(w, err)
+		return
+	}
+	if input.Approve {
+		logInfo("Пользователь %d разрешил доступ приложению %s", userID, input.ClientID)
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"redirect_to": redirect})
+}
+
+// OAuthTokenHandler — эндпоинт токенов OAuth2 (RFC 6749, п. 3.2): обмен кода авторизации
+// и refresh-токена приложения на новую пару токенов
+func (h *Handlers) OAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+	client, ok := h.authenticateOAuthClient(w, r)
+	if !ok {
+		return
+	}
+
+	var (
+		resp *model.OAuthTokenResponse
+		err  error
+	)
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		resp, err = h.service.OAuthService.ExchangeCode(client, r.PostForm.Get("code"), r.PostForm.Get("redirect_uri"), r.PostForm.Get("code_verifier"), deviceInfo(r))
+	case "refresh_token":
+		resp, err = h.service.OAuthService.RefreshGrant(client, r.PostForm.Get("refresh_token"), r.PostForm.Get("scope"))
+	default:
+		err = service.ErrUnsupportedGrantType
+	}
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	writeOAuthJSON(w, http.StatusOK, resp)
+}
+
+// OAuthIntrospectHandler — интроспекция токена приложения (RFC 7662)
+func (h *Handlers) OAuthIntrospectHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+	client, ok := h.authenticateOAuthClient(w, r)
+	if !ok {
+		return
+	}
+
+	info, err := h.service.OAuthService.Introspect(client, r.PostForm.Get("token"), r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	writeOAuthJSON(w, http.StatusOK, info)
+}
+
+// OAuthRevokeHandler — отзыв токена приложения (RFC 7009). Неизвестный токен — не ошибка:
+// ответ 200 в любом случае, чтобы по нему нельзя было проверять токены
+func (h *Handlers) OAuthRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+	client, ok := h.authenticateOAuthClient(w, r)
+	if !ok {
+		return
+	}
+
+	if token := r.PostForm.Get("token"); token != "" {
+		if err := h.service.OAuthService.Revoke(client, token); err != nil {
+			writeOAuthError(w, err)
+			return
+		}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// OAuthMetadataHandler — метаданные сервера авторизации (RFC 8414)
+func (h *Handlers) OAuthMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	if err := json.NewEncoder(w).Encode(h.service.OAuthService.Metadata()); err != nil {
+		logError("Ошибка при отдаче метаданных OAuth2: %v", err)
+	}
+}
+
+// GetOAuthClientsHandler — зарегистрированные приложения (без секретов)
+func (h *Handlers) GetOAuthClientsHandler(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.service.OAuthService.GetClients()
+	if err != nil {
+		writeOAuthClientError(w, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, clients)
+}
+
+// CreateOAuthClientHandler — регистрация приложения; секрет возвращается только в этом ответе
+func (h *Handlers) CreateOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	var input model.NewOAuthClient
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных"))
+		return
+	}
+
+	client, err := h.service.OAuthService.CreateClient(userID, &input)
+	if err != nil {
+		writeOAuthClientError(w, err)
+		return
+	}
+	logInfo("Администратор %d зарегистрировал приложение %s (%s)", userID, client.Id, client.Name)
+	pkg.WriteJSONResponse(w, http.StatusCreated, client)
+}
+
+// DeleteOAuthClientHandler — удаление приложения и всех выданных ему токенов
+func (h *Handlers) DeleteOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := h.service.OAuthService.DeleteClient(id); err != nil {
+		writeOAuthClientError(w, err)
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Администратор %d удалил приложение %s", userID, id)
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "приложение удалено"})
+}
+
+// authenticateOAuthClient разбирает форму запроса и проверяет приложение: client_id и
+// client_secret берутся из Basic-авторизации (RFC 6749, п. 2.3.1) или из полей формы.
+// При ошибке ответ уже записан.
+func (h *Handlers) authenticateOAuthClient(w http.ResponseWriter, r *http.Request) (model.OAuthClient, bool) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, service.ErrInvalidOAuthRequest)
+		return model.OAuthClient{}, false
+	}
+
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		// В Basic-авторизации OAuth2 id и секрет дополнительно закодированы как в форме
+		var errID, errSecret error
+		clientID, errID = url.QueryUnescape(clientID)
+		secret, errSecret = url.QueryUnescape(secret)
+		if errID != nil || errSecret != nil {
+			writeOAuthClientAuthError(w, true)
+			return model.OAuthClient{}, false
+		}
+	} else {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID == "" {
+		writeOAuthClientAuthError(w, basic)
+		return model.OAuthClient{}, false
+	}
+
+	client, err := h.service.OAuthService.AuthenticateClient(clientID, secret)
+	if errors.Is(err, service.ErrInvalidOAuthClient) {
+		writeOAuthClientAuthError(w, basic)
+		return client, false
+	}
+	if err != nil {
+		writeOAuthError(w, err)
+		return client, false
+	}
+	return client, true
+}
+
+// writeOAuthJSON пишет ответ эндпоинтов OAuth2 без общего конверта: клиенты OAuth2
+// ждут объект из RFC. Ответы с токенами не кэшируются (RFC 6749, п. 5.1).
+func writeOAuthJSON(w http.ResponseWriter, statusCode int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		logError("Ошибка при отдаче ответа OAuth2: %v", err)
+	}
+}
+
+// writeOAuthError пишет ошибку эндпоинтов OAuth2 в формате RFC 6749, п. 5.2.
+func writeOAuthError(w http.ResponseWriter, err error) {
+	var code string
+	switch {
+	case errors.Is(err, service.ErrInvalidOAuthRequest):
+		code = "invalid_request"
+	case errors.Is(err, service.ErrInvalidGrant), errors.Is(err, service.ErrAccountSuspended):
+		code = "invalid_grant"
+	case errors.Is(err, service.ErrInvalidScope):
+		code = "invalid_scope"
+	case errors.Is(err, service.ErrUnsupportedGrantType):
+		code = "unsupported_grant_type"
+	default:
+		logError("Ошибка сервера авторизации OAuth2: %v", err)
+		writeOAuthJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeOAuthJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": err.Error()})
+}
+
+// writeOAuthClientAuthError отвечает invalid_client. Если приложение пробовало
+// Basic-авторизацию, ответ 401 с WWW-Authenticate (RFC 6749, п. 5.2).
+func writeOAuthClientAuthError(w http.ResponseWriter, basic bool) {
+	status := http.StatusBadRequest
+	if basic {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		status = http.StatusUnauthorized
+	}
+	writeOAuthJSON(w, status, map[string]string{
+		"error":             "invalid_client",
+		"error_description": service.ErrInvalidOAuthClient.Error(),
+	})
+}
+
+// writeAuthorizeError пишет ответ с подходящим статусом для ошибок запроса авторизации.
+// Пользователь на экране согласия видит причину, в приложение он не возвращается.
+func writeAuthorizeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidOAuthClient):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, model.ErrUnknownOAuthClient)
+	case errors.Is(err, service.ErrInvalidOAuthRequest), errors.Is(err, service.ErrInvalidScope):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+	default:
+		logError("Ошибка запроса авторизации OAuth2: %v", err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+	}
+}
+
+// writeOAuthClientError пишет ответ с подходящим статусом для ошибок управления приложениями.
+func writeOAuthClientError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidOAuthClientInput):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+	case errors.Is(err, model.ErrUnknownOAuthClient):
+		pkg.WriteJSONResponse(w, http.StatusNotFound, model.ErrUnknownOAuthClient)
+	default:
+		logError("Ошибка при работе с приложениями OAuth2: %v", err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.mood/internal/service"
+	"go.mood/pkg"
+	"net/http"
+)
+
+// oidcStateCookie — cookie, в которой браузер хранит состояние входа до возврата от провайдера.
+const oidcStateCookie = "oidc_state"
+
+// GetOIDCProvidersHandler — провайдеры, через которых можно войти
+func (h *Handlers) GetOIDCProvidersHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, h.service.OIDCService.Providers())
+}
+
+// OIDCLoginHandler — перенаправление на страницу входа провайдера
+func (h *Handlers) OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+
+	provider := mux.Vars(r)["provider"]
+	req, err := h.service.OIDCService.BeginLogin(r.Context(), provider)
+	if err != nil {
+		writeOIDCError(w, provider, err)
+		return
+	}
+
+	// Lax: cookie должна вернуться при переходе с сайта провайдера на адрес возврата
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    req.State,
+		Path:     "/auth/oidc/" + provider,
+		MaxAge:   int(req.ExpiresIn),
+		HttpOnly: true,
+		Secure:   req.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, req.AuthURL, http.StatusFound)
+}
+
+// OIDCCallbackHandler — возврат от провайдера: выдача access- и refresh-токенов
+// (или mfa_token, если у пользователя подключена 2FA)
+func (h *Handlers) OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+
+	provider := mux.Vars(r)["provider"]
+	// Состояние одноразовое: cookie удаляется при любом исходе
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/auth/oidc/" + provider, MaxAge: -1, HttpOnly: true})
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		logWarn("Провайдер %s отказал во входе: %s %s", provider, e, q.Get("error_description"))
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, fmt.Errorf("провайдер отказал во входе: %s", e))
+		return
+	}
+	if q.Get("code") == "" {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("нужно передать code и state"))
+		return
+	}
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		writeOIDCError(w, provider, service.ErrInvalidOIDCState)
+		return
+	}
+
+	tokens, challenge, err := h.service.OIDCService.CompleteLogin(r.Context(), provider, cookie.Value, q.Get("state"), q.Get("code"), deviceInfo(r))
+	if err != nil {
+		writeOIDCError(w, provider, err)
+		return
+	}
+	if challenge != nil {
+		pkg.WriteJSONResponse(w, http.StatusOK, challenge)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, tokens)
+}
+
+// writeOIDCError пишет ответ с подходящим статусом для ошибок входа через провайдера.
+func writeOIDCError(w http.ResponseWriter, provider string, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownProvider):
+		pkg.WriteJSONResponse(w, http.StatusNotFound, err)
+	case errors.Is(err, service.ErrInvalidOIDCState), errors.Is(err, service.ErrOIDCEmailRequired):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+	case errors.Is(err, service.ErrOIDCLogin):
+		// Подробности (ответ провайдера, причина отказа в ID-токене) — только в лог
+		logWarn("Вход через провайдера %s не удался: %v", provider, err)
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, service.ErrOIDCLogin)
+	case errors.Is(err, service.ErrOIDCUnavailable):
+		logError("Провайдер %s недоступен: %v", provider, err)
+		pkg.WriteJSONResponse(w, http.StatusBadGateway, service.ErrOIDCUnavailable)
+	case errors.Is(err, service.ErrEmailTaken):
+		pkg.WriteJSONResponse(w, http.StatusConflict, errors.New("email уже используется другим аккаунтом: войдите паролем"))
+	case errors.Is(err, service.ErrEmailNotVerified), errors.Is(err, service.ErrAccountSuspended):
+		pkg.WriteJSONResponse(w, http.StatusForbidden, err)
+	default:
+		logError("Ошибка входа через провайдера %s: %v", provider, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+	}
+}
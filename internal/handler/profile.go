@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go.mood/internal/middleware"
+	"go.mood/internal/model"
+	"go.mood/internal/service"
+	"go.mood/pkg"
+	"net/http"
+)
+
+// GetProfileHandler — профиль текущего пользователя
+func (h *Handlers) GetProfileHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	user, err := h.service.AccountService.GetProfile(userID)
+	if err != nil {
+		writeProfileError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, user)
+}
+
+// UpdateProfileHandler — смена имени пользователя и email (email — с текущим паролем и подтверждением по ссылке)
+func (h *Handlers) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	var input model.ProfileUpdate
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных"))
+		return
+	}
+
+	result, err := h.service.AccountService.UpdateProfile(userID, &input)
+	if err != nil {
+		writeProfileError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, result)
+}
+
+// ChangePasswordHandler — смена пароля по текущему; остальные сессии завершаются
+func (h *Handlers) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	var in struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.NewPassword == "" {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("нужно передать current_password и new_password"))
+		return
+	}
+
+	err = h.service.AccountService.ChangePassword(userID, middleware.GetTokenID(r.Context()), in.CurrentPassword, in.NewPassword)
+	if err != nil {
+		writeProfileError(w, userID, err)
+		return
+	}
+	logInfo("Пользователь %d сменил пароль", userID)
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Пароль изменён, другие сессии завершены"})
+}
+
+// DeleteAccountHandler — удаление своего аккаунта со всеми данными
+func (h *Handlers) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	var in struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Password == "" {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("нужно передать password"))
+		return
+	}
+
+	if err := h.service.AccountService.DeleteAccount(userID, in.Password); err != nil {
+		writeProfileError(w, userID, err)
+		return
+	}
+	logInfo("Пользователь %d удалил свой аккаунт", userID)
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Аккаунт удалён"})
+}
+
+// ConfirmEmailChangeHandler — применение нового email по ссылке из письма (?token=...)
+func (h *Handlers) ConfirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("нужно передать token"))
+		return
+	}
+
+	err := h.service.AccountService.ConfirmEmailChange(token)
+	switch {
+	case errors.Is(err, model.ErrInvalidUserToken):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	case errors.Is(err, service.ErrEmailTaken):
+		pkg.WriteJSONResponse(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		logError("Ошибка смены email: %v", err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось сменить email"))
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Email изменён"})
+}
+
+// writeProfileError пишет ответ с подходящим статусом для ошибок профиля.
+func writeProfileError(w http.ResponseWriter, userID int64, err error) {
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		pkg.WriteJSONResponse(w, http.StatusUnprocessableEntity, invalid)
+	case errors.Is(err, service.ErrInvalidProfile):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+	case errors.Is(err, service.ErrWrongPassword):
+		pkg.WriteJSONResponse(w, http.StatusForbidden, err)
+	case errors.Is(err, service.ErrUsernameTaken), errors.Is(err, service.ErrEmailTaken), errors.Is(err, service.ErrLastAdmin):
+		pkg.WriteJSONResponse(w, http.StatusConflict, err)
+	default:
+		logError("Ошибка работы с профилем пользователя %d: %v", userID, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+	}
+}
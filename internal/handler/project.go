@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go.mood/internal/middleware"
+	"go.mood/internal/model"
+	"go.mood/internal/service"
+	"go.mood/pkg"
+	"net/http"
+)
+
+// GetProjectsHandler — получает проекты текущего пользователя (?archived=true — вместе с архивными)
+func (h *Handlers) GetProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+
+	projects, err := h.service.ProjectService.GetProjects(userID, r.URL.Query().Get("archived") == "true")
+	if err != nil {
+		writeProjectError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, projects)
+}
+
+// GetProjectHandler — получает проект по ID, если он принадлежит текущему пользователю
+func (h *Handlers) GetProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+
+	project, err := h.service.ProjectService.GetProjectByID(id, userID)
+	if err != nil {
+		writeProjectError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, project)
+}
+
+// CreateProjectHandler — создаёт новый проект для текущего пользователя
+func (h *Handlers) CreateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+	var project model.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных!"))
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d создаёт проект %q", userID, project.Name)
+
+	if err := h.service.ProjectService.CreateProject(&project, userID); err != nil {
+		writeProjectError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusCreated, project)
+}
+
+// UpdateProjectHandler — меняет имя, описание и цвет проекта текущего пользователя
+func (h *Handlers) UpdateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	var project model.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных!"))
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d обновляет проект %d", userID, id)
+
+	if err := h.service.ProjectService.UpdateProjectByIDWithCheck(id, userID, &project); err != nil {
+		writeProjectError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Проект успешно обновлён"})
+}
+
+// ArchiveProjectHandler — архивирует проект ({"archived": true}) или возвращает его из архива
+func (h *Handlers) ArchiveProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	var req struct {
+		Archived bool `json:"archived"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных!"))
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d меняет архивный статус проекта %d на %t", userID, id, req.Archived)
+
+	if err := h.service.ProjectService.SetProjectArchivedWithCheck(id, userID, req.Archived); err != nil {
+		writeProjectError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]any{"id": id, "archived": req.Archived})
+}
+
+// DeleteProjectHandler — удаляет проект; его задачи остаются у пользователя без проекта
+func (h *Handlers) DeleteProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodDelete); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d удаляет проект %d", userID, id)
+
+	if err := h.service.ProjectService.DeleteProjectByIDWithCheck(id, userID); err != nil {
+		writeProjectError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Проект успешно удалён"})
+}
+
+// GetProjectTasksHandler — задачи проекта постранично, с теми же параметрами, что и GET /tasks
+func (h *Handlers) GetProjectTasksHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	filter, err := parseTaskFilter(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	filter.ProjectID = &id
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d запросил задачи проекта %d", userID, id)
+
+	h.writeTaskPage(w, r, userID, filter)
+}
+
+// MoveTaskToProjectHandler — переносит задачу в проект ({"project_id": 5}) или убирает из проекта ({"project_id": null})
+func (h *Handlers) MoveTaskToProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	var req struct {
+		ProjectID *int64 `json:"project_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных!"))
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d переносит задачу %d в проект %v", userID, id, req.ProjectID)
+
+	if err := h.service.TaskService.MoveTaskToProject(id, userID, req.ProjectID); err != nil {
+		if errors.Is(err, model.ErrUnknownProject) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, model.ErrUnknownProject)
+			return
+		}
+		logWarn("Пользователь %d не смог перенести задачу %d: %v", userID, id, err)
+		pkg.WriteJSONResponse(w, http.StatusForbidden, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]any{"id": id, "project_id": req.ProjectID})
+}
+
+// writeProjectError переводит ошибку сервиса проектов в HTTP-ответ
+func writeProjectError(w http.ResponseWriter, userID int64, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidProject):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+	case errors.Is(err, model.ErrUnknownProject):
+		pkg.WriteJSONResponse(w, http.StatusNotFound, model.ErrUnknownProject)
+	default:
+		logError("Ошибка работы с проектами пользователя %d: %v", userID, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+	}
+}
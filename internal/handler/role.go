@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"go.mood/internal/middleware"
+	"go.mood/internal/model"
+	"go.mood/internal/service"
+	"go.mood/pkg"
+	"net/http"
+)
+
+// GetRolesHandler — все роли с их правами
+func (h *Handlers) GetRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.service.RoleService.GetRoles()
+	if err != nil {
+		writeRoleError(w, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, roles)
+}
+
+// GetPermissionsHandler — все известные права, из которых составляются роли
+func (h *Handlers) GetPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, model.Permissions)
+}
+
+// CreateRoleHandler — создание роли с набором прав
+func (h *Handlers) CreateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var input model.NewRole
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных"))
+		return
+	}
+
+	role, err := h.service.RoleService.CreateRole(&input)
+	if err != nil {
+		writeRoleError(w, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusCreated, role)
+}
+
+// UpdateRoleHandler — замена описания и прав роли
+func (h *Handlers) UpdateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var input model.NewRole
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных"))
+		return
+	}
+
+	role, err := h.service.RoleService.UpdateRole(mux.Vars(r)["name"], &input)
+	if err != nil {
+		writeRoleError(w, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, role)
+}
+
+// DeleteRoleHandler — удаление роли, не назначенной пользователям
+func (h *Handlers) DeleteRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.RoleService.DeleteRole(mux.Vars(r)["name"]); err != nil {
+		writeRoleError(w, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Роль удалена"})
+}
+
+// AssignRoleHandler — назначение пользователю роли
+func (h *Handlers) AssignRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("неверный ID пользователя"))
+		return
+	}
+
+	currentUserID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	var in struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Role == "" {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("нужно передать role"))
+		return
+	}
+
+	if err := h.service.RoleService.AssignRole(id, in.Role, currentUserID); err != nil {
+		writeRoleError(w, err)
+		return
+	}
+	logInfo("Пользователь %d назначил пользователю %d роль %s", currentUserID, id, in.Role)
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Роль назначена"})
+}
+
+// writeRoleError пишет ответ с подходящим статусом для ошибок ролей.
+func writeRoleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRole):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+	case errors.Is(err, model.ErrUnknownRole):
+		pkg.WriteJSONResponse(w, http.StatusNotFound, model.ErrUnknownRole)
+	case errors.Is(err, service.ErrBuiltInRole), errors.Is(err, model.ErrRoleExists), errors.Is(err, model.ErrRoleInUse):
+		pkg.WriteJSONResponse(w, http.StatusConflict, err)
+	default:
+		logError("Ошибка работы с ролями: %v", err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"github.com/gorilla/mux"
 	"go.mood/internal/database"
 	"go.mood/internal/middleware"
+	"go.mood/internal/model"
 	"go.mood/internal/service"
+	"net/http"
 )
 
 type Handlers struct {
@@ -26,29 +28,148 @@ func (h *Handlers) InitRoutes() *mux.Router {
 	// public: регистрация и логин
 	router.HandleFunc("/gistreer", h.RegisterHandler)
 	router.HandleFunc("/login", h.LoginHandler)
+	router.HandleFunc("/login/mfa", h.LoginMFAHandler)                      // второй шаг входа с 2FA
+	router.HandleFunc("/token/refresh", h.RefreshTokenHandler)              // обменять refresh-токен на новую пару
+	router.HandleFunc("/password/forgot", h.ForgotPasswordHandler)          // письмо со ссылкой для сброса пароля
+	router.HandleFunc("/password/reset", h.ResetPasswordHandler)            // новый пароль по токену из письма
+	router.HandleFunc("/email/verify", h.VerifyEmailHandler)                // подтверждение email по ссылке из письма
+	router.HandleFunc("/email/verify/resend", h.ResendVerificationHandler)  // повторное письмо подтверждения
+	router.HandleFunc("/email/change/confirm", h.ConfirmEmailChangeHandler) // новый email по ссылке из письма
+	router.HandleFunc("/.well-known/jwks.json", h.JWKSHandler)              // открытые ключи для проверки JWT
+
+	// public: вход через внешних провайдеров OpenID Connect
+	router.HandleFunc("/auth/oidc/providers", h.GetOIDCProvidersHandler)       // имена настроенных провайдеров
+	router.HandleFunc("/auth/oidc/{provider}/login", h.OIDCLoginHandler)       // перенаправление к провайдеру
+	router.HandleFunc("/auth/oidc/{provider}/callback", h.OIDCCallbackHandler) // возврат от провайдера, выдача токенов
+
+	// public: сервер авторизации OAuth2 для сторонних приложений (приложение передаёт client_id и секрет)
+	router.HandleFunc("/oauth/token", h.OAuthTokenHandler)                               // код авторизации или refresh-токен на пару токенов
+	router.HandleFunc("/oauth/introspect", h.OAuthIntrospectHandler)                     // действует ли токен приложения
+	router.HandleFunc("/oauth/revoke", h.OAuthRevokeHandler)                             // отозвать токен приложения
+	router.HandleFunc("/.well-known/oauth-authorization-server", h.OAuthMetadataHandler) // метаданные сервера авторизации
 
 	// защищённые маршруты — нужно передать токен
 	auth := router.PathPrefix("/").Subrouter()
-	auth.Use(middleware.AuthMiddleware)
+	auth.Use(middleware.AuthMiddleware(h.service))
+	auth.Use(auditImpersonation) // каждый запрос от имени пользователя попадает в лог
+
+	auth.Handle("/logout", sessionOnly(h.LogoutHandler)) // завершить текущую сессию (с all=true — все сессии)
+
+	// профиль текущего пользователя
+	auth.Handle("/me", sessionOnly(h.GetProfileHandler)).Methods(http.MethodGet)          // свой профиль
+	auth.Handle("/me", ownSessionOnly(h.UpdateProfileHandler)).Methods(http.MethodPatch)  // сменить имя и email
+	auth.Handle("/me", ownSessionOnly(h.DeleteAccountHandler)).Methods(http.MethodDelete) // удалить аккаунт
+	auth.Handle("/me/password", ownSessionOnly(h.ChangePasswordHandler))                  // сменить пароль
+
+	// сессии: где выполнен вход и какие приложения получили доступ
+	auth.Handle("/me/sessions", sessionOnly(h.GetSessionsHandler)).Methods(http.MethodGet)                 // действующие сессии
+	auth.Handle("/me/sessions", ownSessionOnly(h.TerminateAllSessionsHandler)).Methods(http.MethodDelete)  // выйти на всех устройствах
+	auth.Handle("/me/sessions/{id}", ownSessionOnly(h.TerminateSessionHandler)).Methods(http.MethodDelete) // завершить сессию
+
+	// 2fa (TOTP)
+	auth.Handle("/mfa", sessionOnly(h.GetMFAStatusHandler))                              // подключена ли 2FA
+	auth.Handle("/mfa/enroll", ownSessionOnly(h.EnrollMFAHandler))                       // новый секрет и otpauth:// ссылка
+	auth.Handle("/mfa/confirm", ownSessionOnly(h.ConfirmMFAHandler))                     // включить 2FA первым кодом
+	auth.Handle("/mfa/disable", ownSessionOnly(h.DisableMFAHandler))                     // отключить 2FA
+	auth.Handle("/mfa/recovery-codes", ownSessionOnly(h.RegenerateRecoveryCodesHandler)) // новые коды восстановления
+
+	// api keys (pat_...) для скриптов и интеграций
+	auth.Handle("/api-keys", sessionOnly(h.GetAPIKeysHandler)).Methods(http.MethodGet)              // ключи пользователя
+	auth.Handle("/api-keys", ownSessionOnly(h.CreateAPIKeyHandler)).Methods(http.MethodPost)        // создать ключ
+	auth.Handle("/api-keys/{id}", ownSessionOnly(h.RevokeAPIKeyHandler)).Methods(http.MethodDelete) // отозвать ключ
+
+	// согласие пользователя на доступ стороннего приложения (OAuth2, код авторизации с PKCE)
+	auth.Handle("/oauth/authorize", ownSessionOnly(h.GetOAuthAuthorizeHandler)).Methods(http.MethodGet) // что запрашивает приложение
+	auth.Handle("/oauth/authorize", ownSessionOnly(h.OAuthAuthorizeHandler)).Methods(http.MethodPost)   // разрешить или отказать
+
+	// tasks (права API-ключей и приложений: tasks:read / tasks:write)
+	auth.Handle("/tasks", scoped(model.ScopeTasksRead, h.GetAllTasksHandler))                                           // получить все задачи текущего пользователя
+	auth.Handle("/task", scoped(model.ScopeTasksWrite, h.CreateTaskHandler))                                            // создать новую задачу
+	auth.Handle("/tasks/search", scoped(model.ScopeTasksRead, h.SearchTasksHandler))                                    // полнотекстовый поиск
+	auth.Handle("/tasks/due/today", scoped(model.ScopeTasksRead, h.GetTasksDueTodayHandler))                            // задачи со сроком на сегодня
+	auth.Handle("/tasks/due/week", scoped(model.ScopeTasksRead, h.GetTasksDueThisWeekHandler))                          // задачи со сроком на этой неделе
+	auth.Handle("/tasks/overdue", scoped(model.ScopeTasksRead, h.GetOverdueTasksHandler))                               // просроченные задачи
+	auth.Handle("/tasks/{id}", scoped(model.ScopeTasksRead, h.GetTaskHandler)).Methods(http.MethodGet)                  // получить конкретную задачу по ID
+	auth.Handle("/tasks/{id}", scoped(model.ScopeTasksWrite, h.UpdateTaskHandler)).Methods(http.MethodPost)             // обновить задачу
+	auth.Handle("/tasks/{id}", scoped(model.ScopeTasksWrite, h.DeleteTaskHandler)).Methods(http.MethodDelete)           // удалить задачу
+	auth.Handle("/tasks/{id}/status", scoped(model.ScopeTasksWrite, h.UpdateTaskStatusHandler))                         // изменить статус
+	auth.Handle("/tasks/{id}/project", scoped(model.ScopeTasksWrite, h.MoveTaskToProjectHandler))                       // перенести в другой проект
+	auth.Handle("/tasks/{id}/subtasks", scoped(model.ScopeTasksRead, h.GetSubtasksHandler)).Methods(http.MethodGet)     // прямые подзадачи
+	auth.Handle("/tasks/{id}/subtasks", scoped(model.ScopeTasksWrite, h.CreateSubtaskHandler)).Methods(http.MethodPost) // создать подзадачу
+	auth.Handle("/tasks/{id}/tree", scoped(model.ScopeTasksRead, h.GetTaskTreeHandler))                                 // задача с деревом подзадач
+
+	// tags (tags:read / tags:write)
+	auth.Handle("/tags", scoped(model.ScopeTagsRead, h.GetTagsHandler)).Methods(http.MethodGet)            // метки текущего пользователя
+	auth.Handle("/tags", scoped(model.ScopeTagsWrite, h.CreateTagHandler)).Methods(http.MethodPost)        // создать метку
+	auth.Handle("/tags/{id}", scoped(model.ScopeTagsWrite, h.UpdateTagHandler)).Methods(http.MethodPost)   // изменить имя и цвет метки
+	auth.Handle("/tags/{id}", scoped(model.ScopeTagsWrite, h.DeleteTagHandler)).Methods(http.MethodDelete) // удалить метку и отвязать от задач
+
+	// projects (projects:read / projects:write)
+	auth.Handle("/projects", scoped(model.ScopeProjectsRead, h.GetProjectsHandler)).Methods(http.MethodGet)            // проекты пользователя (?archived=true — с архивными)
+	auth.Handle("/projects", scoped(model.ScopeProjectsWrite, h.CreateProjectHandler)).Methods(http.MethodPost)        // создать проект
+	auth.Handle("/projects/{id}", scoped(model.ScopeProjectsRead, h.GetProjectHandler)).Methods(http.MethodGet)        // получить проект
+	auth.Handle("/projects/{id}", scoped(model.ScopeProjectsWrite, h.UpdateProjectHandler)).Methods(http.MethodPost)   // изменить проект
+	auth.Handle("/projects/{id}", scoped(model.ScopeProjectsWrite, h.DeleteProjectHandler)).Methods(http.MethodDelete) // удалить проект, задачи остаются без проекта
+	auth.Handle("/projects/{id}/archive", scoped(model.ScopeProjectsWrite, h.ArchiveProjectHandler))                   // архивировать / вернуть из архива
+	auth.Handle("/projects/{id}/tasks", scoped(model.ScopeProjectsRead, h.GetProjectTasksHandler))                     // задачи проекта
 
-	// tasks
-	auth.HandleFunc("/tasks", h.GetAllTasksHandler)                  // получить все задачи текущего пользователя
-	auth.HandleFunc("/task", h.CreateTaskHandler)                    // создать новую задачу
-	auth.HandleFunc("/tasks/{id}", h.GetTaskHandler)                 // получить конкретную задачу по ID
-	auth.HandleFunc("/tasks/{id}", h.UpdateTaskHandler)              // обновить задачу
-	auth.HandleFunc("/tasks/{id}", h.DeleteTaskHandler)              // удалить задачу
-	auth.HandleFunc("/tasks/{id}/status", h.UpdateTaskStatusHandler) // изменить статус
+	// series (повторяющиеся задачи, права tasks:read / tasks:write)
+	auth.Handle("/series/{id}", scoped(model.ScopeTasksRead, h.GetSeriesHandler)).Methods(http.MethodGet)      // правило и счётчик повторений
+	auth.Handle("/series/{id}", scoped(model.ScopeTasksWrite, h.UpdateSeriesHandler)).Methods(http.MethodPost) // изменить всю серию
+	auth.Handle("/series/{id}/stop", scoped(model.ScopeTasksWrite, h.StopSeriesHandler))                       // остановить серию
+	auth.Handle("/series/{id}/tasks", scoped(model.ScopeTasksRead, h.GetSeriesTasksHandler))                   // задачи серии
 
 	// admin-only routes (под /admin)
 	admin := auth.PathPrefix("/admin").Subrouter()
-	admin.Use(middleware.RequireRole("admin"))
+	admin.Use(middleware.RequireSession())
 
-	// users
-	admin.HandleFunc("/users", h.GetAllUsersHandler)
-	admin.HandleFunc("/user/{id}", h.DeleteUserHandler)
+	// users (права users.*, см. роли ниже)
+	admin.Handle("/users", h.permitted(model.PermUsersRead, h.GetAllUsersHandler))                                 // поиск и постраничный список
+	admin.Handle("/user/{id}", h.permitted(model.PermUsersRead, h.GetUserHandler)).Methods(http.MethodGet)         // пользователь по ID
+	admin.Handle("/user/{id}", h.permitted(model.PermUsersDelete, h.DeleteUserHandler)).Methods(http.MethodDelete) // удалить пользователя
+	admin.Handle("/user/{id}/suspend", h.permitted(model.PermUsersSuspend, h.SuspendUserHandler))                  // приостановить аккаунт
+	admin.Handle("/user/{id}/reactivate", h.permitted(model.PermUsersSuspend, h.ReactivateUserHandler))            // снять приостановку
+	admin.Handle("/user/{id}/unlock", h.permitted(model.PermUsersUnlock, h.UnlockUserHandler))                     // снять блокировку входа
+	admin.Handle("/user/{id}/role", h.permitted(model.PermRolesManage, h.AssignRoleHandler))                       // назначить роль
+	admin.Handle("/user/{id}/tasks", h.permitted(model.PermTasksReadAny, h.GetUserTasksHandler))
+	admin.Handle("/users/{id}/impersonate", h.permitted(model.PermUsersImpersonate, h.ImpersonateHandler)) // токен входа от имени пользователя
+
+	// roles (наборы прав; встроенные user и admin не меняются)
+	admin.Handle("/permissions", h.permitted(model.PermRolesRead, h.GetPermissionsHandler))                           // все известные права
+	admin.Handle("/roles", h.permitted(model.PermRolesRead, h.GetRolesHandler)).Methods(http.MethodGet)               // роли с правами
+	admin.Handle("/roles", h.permitted(model.PermRolesManage, h.CreateRoleHandler)).Methods(http.MethodPost)          // создать роль
+	admin.Handle("/roles/{name}", h.permitted(model.PermRolesManage, h.UpdateRoleHandler)).Methods(http.MethodPost)   // изменить права роли
+	admin.Handle("/roles/{name}", h.permitted(model.PermRolesManage, h.DeleteRoleHandler)).Methods(http.MethodDelete) // удалить роль
+
+	// сторонние приложения OAuth2
+	admin.Handle("/oauth/clients", h.permitted(model.PermClientsManage, h.GetOAuthClientsHandler)).Methods(http.MethodGet)           // зарегистрированные приложения
+	admin.Handle("/oauth/clients", h.permitted(model.PermClientsManage, h.CreateOAuthClientHandler)).Methods(http.MethodPost)        // зарегистрировать приложение
+	admin.Handle("/oauth/clients/{id}", h.permitted(model.PermClientsManage, h.DeleteOAuthClientHandler)).Methods(http.MethodDelete) // удалить приложение и его токены
 	return router
 }
 
+// permitted оборачивает обработчик проверкой права из роли пользователя.
+func (h *Handlers) permitted(perm model.Permission, f http.HandlerFunc) http.Handler {
+	return middleware.RequirePermission(h.service, perm)(f)
+}
+
+// scoped оборачивает обработчик проверкой права API-ключа или приложения OAuth2;
+// запросы пользователя, вошедшего самостоятельно, проходят всегда.
+func scoped(scope model.Scope, f http.HandlerFunc) http.Handler {
+	return middleware.RequireScope(scope)(f)
+}
+
+// ownSessionOnly закрывает обработчик от запросов по API-ключу, от приложений и от администратора,
+// вошедшего от имени пользователя: менять аккаунт может только его владелец.
+func ownSessionOnly(f http.HandlerFunc) http.Handler {
+	return middleware.RequireSession()(middleware.DenyImpersonation()(f))
+}
+
+// sessionOnly закрывает обработчик от запросов по API-ключу и от приложений OAuth2.
+func sessionOnly(f http.HandlerFunc) http.Handler {
+	return middleware.RequireSession()(f)
+}
+
 //// InitRoutes — инициализация всех маршрутов (роутов) приложения
 //func (h *Handlers) InitRoutes() *mux.Router {
 //	router := mux.NewRouter()
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go.mood/internal/middleware"
+	"go.mood/internal/model"
+	"go.mood/internal/service"
+	"go.mood/pkg"
+	"net/http"
+)
+
+// GetSeriesHandler — получает серию повторяющейся задачи с её правилом
+func (h *Handlers) GetSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+
+	series, err := h.service.SeriesService.GetSeriesByID(id, userID)
+	if err != nil {
+		writeSeriesError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, series)
+}
+
+// UpdateSeriesHandler — меняет правило серии ({"rrule": "..."}) и текст или приоритет
+// всех её невыполненных задач ({"task": "...", "priority": "high"})
+func (h *Handlers) UpdateSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	var update model.SeriesUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных!"))
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d обновляет серию %d: %+v", userID, id, update)
+
+	if err := h.service.SeriesService.UpdateSeriesByIDWithCheck(id, userID, update); err != nil {
+		writeSeriesError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Серия успешно обновлена"})
+}
+
+// StopSeriesHandler — останавливает серию: уже созданные задачи остаются, новые не появляются
+func (h *Handlers) StopSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d останавливает серию %d", userID, id)
+
+	if err := h.service.SeriesService.StopSeriesWithCheck(id, userID); err != nil {
+		writeSeriesError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]any{"id": id, "stopped": true})
+}
+
+// GetSeriesTasksHandler — задачи серии постранично, с теми же параметрами, что и GET /tasks
+func (h *Handlers) GetSeriesTasksHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	filter, err := parseTaskFilter(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	filter.SeriesID = &id
+	// Серию показываем целиком, даже если её задачи лежат в архивном проекте
+	filter.WithArchive = true
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d запросил задачи серии %d", userID, id)
+
+	h.writeTaskPage(w, r, userID, filter)
+}
+
+// writeSeriesError переводит ошибку сервиса серий в HTTP-ответ
+func writeSeriesError(w http.ResponseWriter, userID int64, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRecurrence), errors.Is(err, service.ErrInvalidPriority):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+	case errors.Is(err, model.ErrUnknownSeries):
+		pkg.WriteJSONResponse(w, http.StatusNotFound, model.ErrUnknownSeries)
+	default:
+		logError("Ошибка работы с сериями пользователя %d: %v", userID, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+	}
+}
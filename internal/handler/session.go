@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"go.mood/internal/middleware"
+	"go.mood/internal/model"
+	"go.mood/pkg"
+	"net/http"
+)
+
+// GetSessionsHandler — где выполнен вход: действующие сессии на устройствах и доступы приложений
+func (h *Handlers) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	sessions, err := h.service.TokenService.GetSessions(userID, middleware.GetSessionID(r.Context()))
+	if err != nil {
+		writeSessionError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, sessions)
+}
+
+// TerminateSessionHandler — завершение сессии: выход на другом устройстве или отзыв доступа приложения
+func (h *Handlers) TerminateSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	if err := h.service.TokenService.TerminateSession(userID, mux.Vars(r)["id"]); err != nil {
+		writeSessionError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "сессия завершена"})
+}
+
+// TerminateAllSessionsHandler — выход на всех устройствах, включая текущее
+func (h *Handlers) TerminateAllSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.TokenService.Logout(userID, middleware.GetTokenID(ctx), middleware.GetSessionID(ctx), "", true); err != nil {
+		writeSessionError(w, userID, err)
+		return
+	}
+	logInfo("Пользователь %d вышел на всех устройствах", userID)
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "все сессии завершены"})
+}
+
+// writeSessionError пишет ответ с подходящим статусом для ошибок работы с сессиями.
+func writeSessionError(w http.ResponseWriter, userID int64, err error) {
+	if errors.Is(err, model.ErrUnknownSession) {
+		pkg.WriteJSONResponse(w, http.StatusNotFound, model.ErrUnknownSession)
+		return
+	}
+	logError("Ошибка при работе с сессиями пользователя %d: %v", userID, err)
+	pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go.mood/internal/middleware"
+	"go.mood/internal/model"
+	"go.mood/internal/service"
+	"go.mood/pkg"
+	"net/http"
+)
+
+// GetTagsHandler — получает все метки текущего пользователя
+func (h *Handlers) GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+
+	tags, err := h.service.TagService.GetTags(userID)
+	if err != nil {
+		logError("Ошибка при получении меток пользователя %d: %v", userID, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, tags)
+}
+
+// CreateTagHandler — создаёт новую метку для текущего пользователя
+func (h *Handlers) CreateTagHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+	var tag model.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных!"))
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d создаёт метку %q", userID, tag.Name)
+
+	if err := h.service.TagService.CreateTag(&tag, userID); err != nil {
+		writeTagError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusCreated, tag)
+}
+
+// UpdateTagHandler — меняет имя и цвет метки, если она принадлежит текущему пользователю
+func (h *Handlers) UpdateTagHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	var tag model.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных!"))
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d обновляет метку %d", userID, id)
+
+	if err := h.service.TagService.UpdateTagByIDWithCheck(id, userID, &tag); err != nil {
+		writeTagError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Метка успешно обновлена"})
+}
+
+// DeleteTagHandler — удаляет метку текущего пользователя и отвязывает её от всех задач
+func (h *Handlers) DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodDelete); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d удаляет метку %d", userID, id)
+
+	if err := h.service.TagService.DeleteTagByIDWithCheck(id, userID); err != nil {
+		writeTagError(w, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Метка успешно удалена"})
+}
+
+// writeTagError переводит ошибку сервиса меток в HTTP-ответ
+func writeTagError(w http.ResponseWriter, userID int64, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTag):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+	case errors.Is(err, model.ErrTagNameTaken):
+		pkg.WriteJSONResponse(w, http.StatusConflict, model.ErrTagNameTaken)
+	case errors.Is(err, model.ErrUnknownTag):
+		pkg.WriteJSONResponse(w, http.StatusNotFound, model.ErrUnknownTag)
+	default:
+		logError("Ошибка работы с метками пользователя %d: %v", userID, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+	}
+}
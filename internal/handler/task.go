@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"go.mood/internal/middleware"
 	"go.mood/internal/model"
+	"go.mood/internal/service"
 	"go.mood/pkg"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -34,21 +37,155 @@ func logWarn(format string, a ...interface{})  { log("WARN", colorYellow, format
 func logError(format string, a ...interface{}) { log("ERROR", colorRed, format, a...) }
 func logDebug(format string, a ...interface{}) { log("DEBUG", colorCyan, format, a...) }
 
-// GetAllTasksHandler — получает все задачи текущего пользователя
+// GetAllTasksHandler — получает задачи текущего пользователя постранично.
+// Параметры: status, priority, created_from, created_to, updated_from, updated_to (RFC 3339),
+// q (подстрока), tag (имя метки, можно несколько), project_id, archived=true (показать задачи
+// архивных проектов), sort (поле, с "-" — по убыванию), limit, cursor (next_cursor предыдущей страницы).
 func (h *Handlers) GetAllTasksHandler(w http.ResponseWriter, r *http.Request) {
 	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
 		return
 	}
 	userID, _ := middleware.GetUserID(r.Context())
-	logInfo("Пользователь %d запросил список всех своих задач", userID)
+	logInfo("Пользователь %d запросил список своих задач: %s", userID, r.URL.RawQuery)
 
-	tasks, err := h.service.TaskService.GetAllTasksByUserID(userID)
+	filter, err := parseTaskFilter(r)
 	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	h.writeTaskPage(w, r, userID, filter)
+}
+
+// writeTaskPage отдаёт страницу задач по фильтру вместе с next_cursor
+func (h *Handlers) writeTaskPage(w http.ResponseWriter, r *http.Request, userID int64, filter model.TaskFilter) {
+	page, err := h.service.TaskService.ListTasks(userID, filter, r.URL.Query().Get("cursor"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSort) || errors.Is(err, service.ErrInvalidCursor) ||
+			errors.Is(err, service.ErrInvalidPriority) || errors.Is(err, service.ErrInvalidStatus) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		if errors.Is(err, model.ErrUnknownProject) || errors.Is(err, model.ErrUnknownSeries) {
+			pkg.WriteJSONResponse(w, http.StatusNotFound, err)
+			return
+		}
 		logError("Ошибка при получении задач пользователя %d: %v", userID, err)
 		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
 		return
 	}
-	logInfo("Пользователь %d получил %d задач", userID, len(tasks))
+	logInfo("Пользователь %d получил %d задач", userID, len(page.Tasks))
+	pkg.WriteJSONResponse(w, http.StatusOK, pkg.Page{Data: page.Tasks, NextCursor: page.NextCursor})
+}
+
+// parseTaskFilter разбирает параметры запроса списка задач.
+func parseTaskFilter(r *http.Request) (model.TaskFilter, error) {
+	q := r.URL.Query()
+	filter := model.TaskFilter{
+		Status:      model.TaskStatus(q.Get("status")),
+		Priority:    model.Priority(q.Get("priority")),
+		Search:      q.Get("q"),
+		Tags:        q["tag"],
+		WithArchive: q.Get("archived") == "true",
+		SortBy:      strings.TrimPrefix(q.Get("sort"), "-"),
+		Desc:        strings.HasPrefix(q.Get("sort"), "-"),
+	}
+
+	if v := q.Get("project_id"); v != "" {
+		projectID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return filter, errors.New("некорректный параметр 'project_id'")
+		}
+		filter.ProjectID = &projectID
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return filter, errors.New("некорректный параметр 'limit'")
+		}
+		filter.Limit = limit
+	}
+
+	times := map[string]**time.Time{
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+		"updated_from": &filter.UpdatedFrom,
+		"updated_to":   &filter.UpdatedTo,
+	}
+	for name, dst := range times {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("некорректный параметр '%s': ожидается время в формате RFC 3339", name)
+		}
+		*dst = &t
+	}
+	return filter, nil
+}
+
+// SearchTasksHandler — полнотекстовый поиск по задачам текущего пользователя.
+// Параметры: q (слова, "фраза в кавычках", префикс*), limit.
+func (h *Handlers) SearchTasksHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	q := r.URL.Query().Get("q")
+	logInfo("Пользователь %d ищет задачи: %q", userID, q)
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("некорректный параметр 'limit'"))
+			return
+		}
+	}
+
+	results, err := h.service.TaskService.SearchTasks(userID, q, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrEmptySearchQuery) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		logError("Ошибка поиска задач пользователя %d: %v", userID, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, results)
+}
+
+// GetTasksDueTodayHandler — невыполненные задачи текущего пользователя со сроком на сегодня
+func (h *Handlers) GetTasksDueTodayHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeDueTasks(w, r, "на сегодня", h.service.TaskService.GetTasksDueToday)
+}
+
+// GetTasksDueThisWeekHandler — невыполненные задачи текущего пользователя со сроком на этой неделе
+func (h *Handlers) GetTasksDueThisWeekHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeDueTasks(w, r, "на неделю", h.service.TaskService.GetTasksDueThisWeek)
+}
+
+// GetOverdueTasksHandler — просроченные задачи текущего пользователя
+func (h *Handlers) GetOverdueTasksHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeDueTasks(w, r, "просроченные", h.service.TaskService.GetOverdueTasks)
+}
+
+func (h *Handlers) writeDueTasks(w http.ResponseWriter, r *http.Request, kind string, get func(userID int64) ([]model.Task, error)) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d запросил задачи %s", userID, kind)
+
+	tasks, err := get(userID)
+	if err != nil {
+		logError("Ошибка при получении задач %s пользователя %d: %v", kind, userID, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+		return
+	}
 	pkg.WriteJSONResponse(w, http.StatusOK, tasks)
 }
 
@@ -67,24 +204,71 @@ func (h *Handlers) GetTaskHandler(w http.ResponseWriter, r *http.Request) {
 
 	task, err := h.service.TaskService.GetTaskByID(id, userID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			logWarn("Задача %d не найдена для пользователя %d", id, userID)
-			pkg.WriteJSONResponse(w, http.StatusNotFound, fmt.Errorf("Задача с id %d не найдена", id))
-			return
-		}
-		// Проверяем, если ошибка — это "доступ запрещён", возвращаем 403
-		if err.Error() == "доступ запрещён" {
-			pkg.WriteJSONResponse(w, http.StatusForbidden, errors.New("доступ запрещён"))
-			return
-		}
-		logError("Ошибка при получении задачи %d: %v", id, err)
-		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+		writeGetTaskError(w, id, userID, err)
 		return
 	}
 	logInfo("Пользователь %d успешно получил задачу %d", userID, id)
 	pkg.WriteJSONResponse(w, http.StatusOK, task)
 }
 
+// GetTaskTreeHandler — получает задачу вместе с деревом подзадач и прогрессом на каждом уровне
+func (h *Handlers) GetTaskTreeHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d запросил дерево задачи %d", userID, id)
+
+	task, err := h.service.TaskService.GetTaskTree(id, userID)
+	if err != nil {
+		writeGetTaskError(w, id, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, task)
+}
+
+// GetSubtasksHandler — получает прямые подзадачи задачи текущего пользователя
+func (h *Handlers) GetSubtasksHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d запросил подзадачи задачи %d", userID, id)
+
+	subtasks, err := h.service.TaskService.GetSubtasks(id, userID)
+	if err != nil {
+		writeGetTaskError(w, id, userID, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, subtasks)
+}
+
+// writeGetTaskError переводит ошибку получения задачи в HTTP-ответ
+func writeGetTaskError(w http.ResponseWriter, id, userID int64, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		logWarn("Задача %d не найдена для пользователя %d", id, userID)
+		pkg.WriteJSONResponse(w, http.StatusNotFound, fmt.Errorf("Задача с id %d не найдена", id))
+		return
+	}
+	// Проверяем, если ошибка — это "доступ запрещён", возвращаем 403
+	if err.Error() == "доступ запрещён" {
+		pkg.WriteJSONResponse(w, http.StatusForbidden, errors.New("доступ запрещён"))
+		return
+	}
+	logError("Ошибка при получении задачи %d: %v", id, err)
+	pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+}
+
 // CreateTaskHandler — создаёт новую задачу для текущего пользователя
 func (h *Handlers) CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
@@ -99,6 +283,22 @@ func (h *Handlers) CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	logInfo("Пользователь %d создаёт задачу: %+v", userID, task)
 
 	if err := h.service.TaskService.CreateTask(&task, userID); err != nil {
+		if errors.Is(err, service.ErrInvalidPriority) || errors.Is(err, service.ErrInvalidRecurrence) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		if errors.Is(err, model.ErrUnknownTag) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, model.ErrUnknownTag)
+			return
+		}
+		if errors.Is(err, model.ErrUnknownProject) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, model.ErrUnknownProject)
+			return
+		}
+		if errors.Is(err, model.ErrUnknownParentTask) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, model.ErrUnknownParentTask)
+			return
+		}
 		logError("Ошибка создания задачи пользователем %d: %v", userID, err)
 		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка создания задачи!"))
 		return
@@ -107,7 +307,51 @@ func (h *Handlers) CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	pkg.WriteJSONResponse(w, http.StatusCreated, task)
 }
 
-// DeleteTaskHandler — удаляет задачу, если она принадлежит текущему пользователю
+// CreateSubtaskHandler — создаёт подзадачу задачи текущего пользователя
+func (h *Handlers) CreateSubtaskHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	var task model.Task
+	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных!"))
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+	logInfo("Пользователь %d создаёт подзадачу задачи %d: %+v", userID, id, task)
+
+	if err := h.service.TaskService.CreateSubtask(id, &task, userID); err != nil {
+		if errors.Is(err, model.ErrUnknownParentTask) {
+			pkg.WriteJSONResponse(w, http.StatusNotFound, model.ErrUnknownParentTask)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidPriority) || errors.Is(err, service.ErrInvalidRecurrence) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		if errors.Is(err, model.ErrUnknownTag) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, model.ErrUnknownTag)
+			return
+		}
+		if errors.Is(err, model.ErrUnknownProject) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, model.ErrUnknownProject)
+			return
+		}
+		logError("Ошибка создания подзадачи пользователем %d: %v", userID, err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка создания задачи!"))
+		return
+	}
+	logInfo("Пользователь %d успешно создал подзадачу ID %d задачи %d", userID, task.Id, id)
+	pkg.WriteJSONResponse(w, http.StatusCreated, task)
+}
+
+// DeleteTaskHandler — удаляет задачу, если она принадлежит текущему пользователю.
+// Задача с подзадачами удаляется вместе с ними только при ?cascade=true, иначе — 409.
 func (h *Handlers) DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	if ok := pkg.AllowMethod(w, r, http.MethodDelete); !ok {
 		return
@@ -120,7 +364,12 @@ func (h *Handlers) DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	userID, _ := middleware.GetUserID(r.Context())
 	logInfo("Пользователь %d пытается удалить задачу %d", userID, id)
 
-	if err := h.service.TaskService.DeleteTaskByIDWithCheck(id, userID); err != nil {
+	cascade := r.URL.Query().Get("cascade") == "true"
+	if err := h.service.TaskService.DeleteTaskByIDWithCheck(id, userID, cascade); err != nil {
+		if errors.Is(err, model.ErrTaskHasSubtasks) {
+			pkg.WriteJSONResponse(w, http.StatusConflict, model.ErrTaskHasSubtasks)
+			return
+		}
 		logWarn("Пользователь %d не смог удалить задачу %d: %v", userID, id, err)
 		pkg.WriteJSONResponse(w, http.StatusForbidden, err)
 		return
@@ -148,6 +397,14 @@ func (h *Handlers) UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	logInfo("Пользователь %d пытается обновить задачу %d данными %+v", userID, id, task)
 
 	if err := h.service.TaskService.UpdateTaskByIDWithCheck(id, userID, &task); err != nil {
+		if errors.Is(err, service.ErrInvalidPriority) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		if errors.Is(err, model.ErrUnknownTag) {
+			pkg.WriteJSONResponse(w, http.StatusBadRequest, model.ErrUnknownTag)
+			return
+		}
 		logWarn("Пользователь %d не смог обновить задачу %d: %v", userID, id, err)
 		pkg.WriteJSONResponse(w, http.StatusForbidden, err)
 		return
@@ -156,7 +413,8 @@ func (h *Handlers) UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Задача успешно обновлена"})
 }
 
-// UpdateTaskStatusHandler меняет статус задачи (true = сделано, false = не сделано)
+// UpdateTaskStatusHandler переводит задачу в другой статус: {"status": "in_progress"}.
+// Недопустимый статус или переход — 422. С ?cascade=true тот же статус получают подзадачи.
 func (h *Handlers) UpdateTaskStatusHandler(w http.ResponseWriter, r *http.Request) {
 	if ok := pkg.AllowMethod(w, r, http.MethodPatch); !ok {
 		return
@@ -167,14 +425,19 @@ func (h *Handlers) UpdateTaskStatusHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	var req struct {
-		Status bool `json:"status"`
+		Status model.TaskStatus `json:"status"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("Неверный формат данных!"))
 		return
 	}
 	userID, _ := middleware.GetUserID(r.Context())
-	if err := h.service.TaskService.UpdateTaskStatusByIDWithCheck(id, userID, req.Status); err != nil {
+	cascade := r.URL.Query().Get("cascade") == "true"
+	if err := h.service.TaskService.UpdateTaskStatusByIDWithCheck(id, userID, req.Status, cascade); err != nil {
+		if errors.Is(err, service.ErrInvalidStatus) || errors.Is(err, service.ErrInvalidTransition) {
+			pkg.WriteJSONResponse(w, http.StatusUnprocessableEntity, err)
+			return
+		}
 		pkg.WriteJSONResponse(w, http.StatusForbidden, err)
 		return
 	}
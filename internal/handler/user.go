@@ -2,24 +2,52 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"go.mood/internal/middleware"
+	"go.mood/internal/model"
+	"go.mood/internal/service"
 	"go.mood/pkg"
 	"net/http"
+	"strconv"
+	"time"
 )
 
-// GetAllUsersHandler — получает всех пользователей.
+// GetAllUsersHandler — пользователи постранично в порядке id.
+// Параметры: q (подстрока имени или email), role, suspended (true/false),
+// created_from, created_to (RFC 3339), limit, cursor (next_cursor предыдущей страницы).
 func (h *Handlers) GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
 	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
 		return
 	}
 
-	users, err := h.service.UserService.GetAllUsers()
+	filter, err := parseUserFilter(r)
 	if err != nil {
-		pkg.WriteJSONResponse(w, http.StatusInternalServerError, err)
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := h.service.UserService.ListUsers(filter, r.URL.Query().Get("cursor"))
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, pkg.Page{Data: page.Users, NextCursor: page.NextCursor})
+}
+
+// GetUserHandler — пользователь по ID
+func (h *Handlers) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("неверный ID пользователя"))
 		return
 	}
 
-	pkg.WriteJSONResponse(w, http.StatusOK, users)
+	user, err := h.service.UserService.GetUser(id)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	pkg.WriteJSONResponse(w, http.StatusOK, user)
 }
 
 // DeleteUserHandler — удаляет пользователя по ID, но с проверкой прав.
@@ -47,3 +75,148 @@ func (h *Handlers) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Пользователь успешно удален"})
 }
+
+// UnlockUserHandler — снимает блокировку входа после неудачных попыток.
+func (h *Handlers) UnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("неверный ID пользователя"))
+		return
+	}
+
+	if err := h.service.UserService.UnlockUser(id); err != nil {
+		pkg.WriteJSONResponse(w, http.StatusNotFound, err)
+		return
+	}
+
+	logInfo("Пользователь %d разблокирован администратором", id)
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Пользователь разблокирован"})
+}
+
+// SuspendUserHandler — приостанавливает аккаунт: вход и все токены пользователя перестают действовать
+func (h *Handlers) SuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("неверный ID пользователя"))
+		return
+	}
+
+	currentUserID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("не удалось получить ID пользователя"))
+		return
+	}
+
+	if err := h.service.UserService.SuspendUser(id, currentUserID); err != nil {
+		writeUserError(w, err)
+		return
+	}
+	logInfo("Пользователь %d приостановил аккаунт пользователя %d", currentUserID, id)
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Аккаунт приостановлен"})
+}
+
+// ReactivateUserHandler — снимает приостановку аккаунта
+func (h *Handlers) ReactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodPost); !ok {
+		return
+	}
+
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("неверный ID пользователя"))
+		return
+	}
+
+	if err := h.service.UserService.ReactivateUser(id); err != nil {
+		writeUserError(w, err)
+		return
+	}
+	logInfo("Аккаунт пользователя %d восстановлен администратором", id)
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "Аккаунт восстановлен"})
+}
+
+// GetUserTasksHandler — задачи любого пользователя (право tasks.read_any).
+func (h *Handlers) GetUserTasksHandler(w http.ResponseWriter, r *http.Request) {
+	if ok := pkg.AllowMethod(w, r, http.MethodGet); !ok {
+		return
+	}
+
+	id, err := pkg.GetID(r)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, errors.New("неверный ID пользователя"))
+		return
+	}
+
+	tasks, err := h.service.TaskService.GetAllTasksByUserID(id)
+	if err != nil {
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, tasks)
+}
+
+// parseUserFilter разбирает параметры запроса списка пользователей.
+func parseUserFilter(r *http.Request) (model.UserFilter, error) {
+	q := r.URL.Query()
+	filter := model.UserFilter{
+		Search: q.Get("q"),
+		Role:   q.Get("role"),
+	}
+
+	if v := q.Get("suspended"); v != "" {
+		suspended, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("некорректный параметр 'suspended'")
+		}
+		filter.Suspended = &suspended
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return filter, errors.New("некорректный параметр 'limit'")
+		}
+		filter.Limit = limit
+	}
+
+	times := map[string]**time.Time{
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+	}
+	for name, dst := range times {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("некорректный параметр '%s': ожидается время в формате RFC 3339", name)
+		}
+		*dst = &t
+	}
+	return filter, nil
+}
+
+// writeUserError пишет ответ с подходящим статусом для ошибок управления пользователями.
+func writeUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCursor), errors.Is(err, service.ErrSuspendSelf):
+		pkg.WriteJSONResponse(w, http.StatusBadRequest, err)
+	case errors.Is(err, service.ErrSuspendAdmin):
+		pkg.WriteJSONResponse(w, http.StatusForbidden, err)
+	case errors.Is(err, model.ErrUnknownUser):
+		pkg.WriteJSONResponse(w, http.StatusNotFound, err)
+	default:
+		logError("Ошибка управления пользователями: %v", err)
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("Ошибка сервера!"))
+	}
+}
@@ -0,0 +1,106 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JWK — открытый ключ в формате RFC 7517. Для RSA заполняются N и E, для Ed25519 — Crv и X.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS — набор открытых ключей для /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые ключи, которыми ещё можно проверить выданные токены:
+// активный и выведенные, срок приёма которых не истёк. Секрет HS256 не публикуется.
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := time.Now()
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		if !key.usable(now, ks.grace) {
+			continue
+		}
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// Generate создаёт в каталоге dir новый ключ алгоритма alg ("RS256" или "EdDSA")
+// и возвращает его kid. После перечитывания каталога ключ становится активным.
+func Generate(dir, alg string) (string, error) {
+	var private any
+	var err error
+	switch strings.ToUpper(alg) {
+	case "RS256":
+		private, err = rsa.GenerateKey(rand.Reader, minRSABits)
+	case "EDDSA", "ED25519":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", fmt.Errorf("неподдерживаемый алгоритм %s: используйте RS256 или EdDSA", alg)
+	}
+	if err != nil {
+		return "", fmt.Errorf("ошибка генерации ключа: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", fmt.Errorf("ошибка кодирования ключа: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("ошибка создания каталога ключей: %w", err)
+	}
+	kid := time.Now().UTC().Format("20060102T150405Z")
+	path := filepath.Join(dir, kid+keyExt)
+	// O_EXCL — не затираем ключ, созданный в ту же секунду
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("ошибка записи ключа: %w", err)
+	}
+	if err := pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		f.Close()
+		return "", fmt.Errorf("ошибка записи ключа: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("ошибка записи ключа: %w", err)
+	}
+	return kid, nil
+}
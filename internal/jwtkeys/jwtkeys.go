@@ -0,0 +1,255 @@
+// Package jwtkeys — ключи подписи JWT. Ключи RS256 и EdDSA хранятся в каталоге
+// PEM-файлами <kid>.pem; новые токены подписываются самым новым ключом, а прежние
+// ключи ещё принимаются при проверке, пока не истекут подписанные ими токены.
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// keyExt — расширение файлов ключей; имя файла без него — kid.
+	keyExt = ".pem"
+	// minRSABits — минимальная длина ключа RSA.
+	minRSABits = 2048
+	// defaultGrace — сколько принимать выведенный ключ, если срок не задан (срок жизни access-токена по умолчанию).
+	defaultGrace = time.Hour
+)
+
+var (
+	// ErrUnknownKey — в заголовке токена kid, которого нет в наборе.
+	ErrUnknownKey = errors.New("неизвестный ключ подписи")
+	// ErrKeyRetired — ключ выведен из употребления дольше, чем живут подписанные им токены.
+	ErrKeyRetired = errors.New("ключ подписи больше не действует")
+)
+
+// Key — ключ подписи. RetiredAt — когда ключ перестал быть активным (нулевое значение — активен).
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	CreatedAt time.Time
+	RetiredAt time.Time
+
+	signKey   any
+	verifyKey any
+}
+
+// KeySet — набор ключей подписи. Безопасен для одновременного использования.
+type KeySet struct {
+	dir    string
+	grace  time.Duration
+	legacy *Key
+
+	mu     sync.RWMutex
+	active *Key
+	keys   map[string]*Key
+}
+
+// Load создаёт набор ключей из каталога dir. secret — общий секрет HS256 (JWT_SECRET):
+// без каталога им подписываются все токены, а с каталогом он только принимается
+// для токенов без kid, выданных до перехода на асимметричные ключи.
+// grace — сколько принимать выведенный из употребления ключ, не меньше срока жизни access-токена
+// (0 — час).
+func Load(dir, secret string, grace time.Duration) (*KeySet, error) {
+	if dir == "" && secret == "" {
+		return nil, errors.New("не заданы ни каталог ключей auth.keys_dir, ни JWT_SECRET")
+	}
+	if grace <= 0 {
+		grace = defaultGrace
+	}
+
+	ks := &KeySet{dir: dir, grace: grace, keys: make(map[string]*Key)}
+	now := time.Now()
+	if secret != "" {
+		ks.legacy = &Key{
+			Method:    jwt.SigningMethodHS256,
+			CreatedAt: now,
+			signKey:   []byte(secret),
+			verifyKey: []byte(secret),
+		}
+	}
+	if dir == "" {
+		ks.active = ks.legacy
+		return ks, nil
+	}
+
+	// Секрет HS256 считается выведенным с момента запуска
+	if ks.legacy != nil {
+		ks.legacy.RetiredAt = now
+	}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload перечитывает каталог ключей. Активным становится ключ с самым поздним временем
+// изменения файла. Прежний ключ считается выведенным, когда набор впервые увидел более
+// новый, а не по времени изменения файла: после перезапуска или копирования с сохранением
+// времени (cp -p, rsync -a) подписанные им токены принимаются ещё весь срок grace.
+// При первой загрузке все ключи, кроме активного, считаются выведенными в момент загрузки.
+// При ошибке набор не меняется.
+func (ks *KeySet) Reload() error {
+	if ks.dir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(ks.dir)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения каталога ключей: %w", err)
+	}
+	var loaded []*Key
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), keyExt) {
+			continue
+		}
+		key, err := loadKey(filepath.Join(ks.dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		loaded = append(loaded, key)
+	}
+	if len(loaded) == 0 {
+		return fmt.Errorf("в каталоге %s нет ключей подписи: создайте ключ командой keys generate", ks.dir)
+	}
+
+	sort.Slice(loaded, func(i, j int) bool {
+		if !loaded[i].CreatedAt.Equal(loaded[j].CreatedAt) {
+			return loaded[i].CreatedAt.Before(loaded[j].CreatedAt)
+		}
+		return loaded[i].ID < loaded[j].ID
+	})
+	active := loaded[len(loaded)-1]
+	now := time.Now()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	keys := make(map[string]*Key, len(loaded))
+	for _, key := range loaded {
+		if key != active {
+			key.RetiredAt = now
+			if prev, ok := ks.keys[key.ID]; ok && !prev.RetiredAt.IsZero() {
+				key.RetiredAt = prev.RetiredAt
+			}
+		}
+		keys[key.ID] = key
+	}
+	ks.keys = keys
+	ks.active = active
+	return nil
+}
+
+// Sign подписывает claims активным ключом и указывает его kid в заголовке токена.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	key := ks.active
+	ks.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.signKey)
+}
+
+// Parse проверяет подпись и срок действия токена и возвращает его claims.
+func (ks *KeySet) Parse(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, ks.keyfunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// keyfunc выбирает ключ проверки по kid. Алгоритм токена должен совпадать с алгоритмом ключа,
+// иначе открытый ключ RSA можно было бы подсунуть как секрет HS256.
+func (ks *KeySet) keyfunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	ks.mu.RLock()
+	key := ks.keys[kid]
+	if kid == "" {
+		key = ks.legacy
+	}
+	ks.mu.RUnlock()
+
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	if !key.usable(time.Now(), ks.grace) {
+		return nil, ErrKeyRetired
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("алгоритм %s не подходит для ключа %q", t.Method.Alg(), kid)
+	}
+	return key.verifyKey, nil
+}
+
+// usable сообщает, принимаются ли ещё токены, подписанные ключом.
+func (k *Key) usable(now time.Time, grace time.Duration) bool {
+	return k.RetiredAt.IsZero() || now.Before(k.RetiredAt.Add(grace))
+}
+
+// loadKey читает закрытый ключ RSA (PKCS#1 или PKCS#8) или Ed25519 (PKCS#8) из PEM-файла.
+// kid — имя файла без расширения, время создания — время изменения файла.
+func loadKey(path string) (*Key, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ключа %s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ключа %s: %w", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("ключ %s: файл не в формате PEM", path)
+	}
+
+	var private any
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("ключ %s: неподдерживаемый тип PEM %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ключ %s: %w", path, err)
+	}
+
+	key := &Key{
+		ID:        strings.TrimSuffix(filepath.Base(path), keyExt),
+		CreatedAt: info.ModTime(),
+		signKey:   private,
+	}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("ключ %s: RSA короче %d бит", path, minRSABits)
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.verifyKey = &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.verifyKey = k.Public()
+	default:
+		return nil, fmt.Errorf("ключ %s: поддерживаются только RSA и Ed25519", path)
+	}
+	return key, nil
+}
@@ -0,0 +1,104 @@
+package jwtkeys
+
+import (
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// generateKey создаёт в dir ключ с kid и временем изменения файла modTime.
+func generateKey(t *testing.T, dir, kid string, modTime time.Time) {
+	t.Helper()
+	generated, err := Generate(dir, "EdDSA")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	path := filepath.Join(dir, kid+keyExt)
+	if err := os.Rename(filepath.Join(dir, generated+keyExt), path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func signWith(t *testing.T, ks *KeySet) string {
+	t.Helper()
+	signed, err := ks.Sign(jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return signed
+}
+
+func activeKid(t *testing.T, signed string) string {
+	t.Helper()
+	token, _, err := jwt.NewParser().ParseUnverified(signed, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
+
+// Ключ выводится, когда набор увидел более новый, а не по времени изменения файла:
+// файлы, скопированные с сохранением времени, не должны обрывать сессии.
+func TestReloadRetiresKeyWhenNewerKeyAppears(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	generateKey(t, dir, "old", now.Add(-72*time.Hour))
+
+	ks, err := Load(dir, "", time.Hour)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	oldToken := signWith(t, ks)
+
+	// Новый ключ с временем файла двое суток назад — больше grace
+	generateKey(t, dir, "new", now.Add(-48*time.Hour))
+	if err := ks.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if kid := activeKid(t, signWith(t, ks)); kid != "new" {
+		t.Fatalf("активный ключ %q, ожидался new", kid)
+	}
+	if _, err := ks.Parse(oldToken); err != nil {
+		t.Fatalf("токен прежнего ключа отклонён сразу после ротации: %v", err)
+	}
+
+	// Повторное перечитывание не сдвигает момент вывода
+	ks.keys["old"].RetiredAt = now.Add(-2 * time.Hour)
+	if err := ks.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if _, err := ks.Parse(oldToken); !errors.Is(err, ErrKeyRetired) {
+		t.Fatalf("токен ключа, выведенного дольше grace назад: %v, ожидалась ErrKeyRetired", err)
+	}
+}
+
+// После перезапуска прежние ключи принимаются ещё grace с момента загрузки.
+func TestLoadRetiresOlderKeysAtLoadTime(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	generateKey(t, dir, "old", now.Add(-72*time.Hour))
+	before, err := Load(dir, "", time.Hour)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	oldToken := signWith(t, before)
+	generateKey(t, dir, "new", now.Add(-48*time.Hour))
+
+	ks, err := Load(dir, "", time.Hour)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if kid := activeKid(t, signWith(t, ks)); kid != "new" {
+		t.Fatalf("активный ключ %q, ожидался new", kid)
+	}
+	if _, err := ks.Parse(oldToken); err != nil {
+		t.Fatalf("токен прежнего ключа отклонён после перезапуска: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileMailer никуда не отправляет письма, а дописывает их в файл или в лог —
+// для локального запуска без SMTP-сервера.
+type FileMailer struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileMailer создает новый экземпляр FileMailer. Пустой path — письма пишутся в лог.
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{path: path}
+}
+
+// Send записывает письмо.
+func (m *FileMailer) Send(msg Message) error {
+	entry := fmt.Sprintf("=== %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	if m.path == "" {
+		log.Print("Письмо (не отправлено):\n" + entry)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия файла писем: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(entry); err != nil {
+		return fmt.Errorf("ошибка записи письма: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package mailer
+
+// Message — текстовое письмо.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer — отправка писем пользователям (сброс пароля, подтверждение email).
+type Mailer interface {
+	Send(msg Message) error
+}
@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer отправляет письма через SMTP-сервер. Если сервер поддерживает
+// STARTTLS, net/smtp включает его сам; авторизация выполняется только при заданном логине.
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer создает новый экземпляр SMTPMailer.
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send отправляет письмо.
+func (m *SMTPMailer) Send(msg Message) error {
+	// Адрес попадает в заголовок как есть — не даём подставить свои заголовки
+	if strings.ContainsAny(msg.To, "\r\n") {
+		return fmt.Errorf("некорректный адрес получателя %q", msg.To)
+	}
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, buildMessage(m.from, msg)); err != nil {
+		return fmt.Errorf("ошибка отправки письма на %s: %w", msg.To, err)
+	}
+	return nil
+}
+
+// buildMessage собирает письмо в формате RFC 5322; тема кодируется по RFC 2047,
+// так как может содержать кириллицу.
+func buildMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
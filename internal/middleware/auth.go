@@ -3,11 +3,13 @@ package middleware
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
+	"go.mood/internal/model"
+	"go.mood/internal/service"
 	"go.mood/pkg"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 )
@@ -17,18 +19,54 @@ type ctxKey string
 const (
 	ctxKeyUserID   ctxKey = "user_id"
 	ctxKeyUserRole ctxKey = "user_role"
+	ctxKeyTokenID  ctxKey = "token_id"
+	ctxKeySession  ctxKey = "session_id"
+	ctxKeyMFA      ctxKey = "mfa"
+	ctxKeyActorID  ctxKey = "actor_id"
 )
 
-// AuthMiddleware — проверяет Authorization: Bearer <token>
-// кладёт user_id и role в context.
-func AuthMiddleware(next http.Handler) http.Handler {
-	secret := os.Getenv("JWT_SECRET")
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if secret == "" {
-			pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("server misconfigured: JWT_SECRET not set"))
-			return
-		}
+// RevocationChecker сообщает, отозван ли access-токен с данным jti (например, после logout).
+type RevocationChecker interface {
+	IsAccessTokenRevoked(jti string) (bool, error)
+}
+
+// TokenVerifier проверяет подпись и срок действия JWT и возвращает его claims.
+type TokenVerifier interface {
+	VerifyJWT(token string) (jwt.MapClaims, error)
+}
+
+// AccountChecker проверяет, что аккаунт пользователя существует и не приостановлен.
+type AccountChecker interface {
+	CheckAccount(userID int64) error
+}
+
+// SessionChecker проверяет, что сессия access-токена (claim sid) не завершена.
+type SessionChecker interface {
+	CheckSession(sid string) error
+}
+
+// Authenticator — проверки, для которых нужны ключи подписи и хранилище:
+// подпись и отзыв JWT, сессии, персональные API-ключи, состояние аккаунта.
+type Authenticator interface {
+	TokenVerifier
+	RevocationChecker
+	SessionChecker
+	APIKeyAuthenticator
+	AccountChecker
+}
 
+// AuthMiddleware — проверяет Authorization: Bearer <token> (JWT или API-ключ pat_...),
+// отклоняет отозванные токены, токены завершённых сессий и токены удалённых или
+// приостановленных пользователей и кладёт user_id, role, jti и sid в context. У токенов приложений OAuth2 в context
+// попадают и выданные приложению права (GetOAuthGrant).
+func AuthMiddleware(authenticator Authenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return authHandler(next, authenticator)
+	}
+}
+
+func authHandler(next http.Handler, authenticator Authenticator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		auth := r.Header.Get("Authorization")
 		if auth == "" {
 			pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("требуется авторизация"))
@@ -42,19 +80,26 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		}
 		tokenStr := parts[1]
 
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			// можно проверить метод подписи
-			return []byte(secret), nil
-		})
+		if strings.HasPrefix(tokenStr, model.APIKeyPrefix) {
+			ctx, err := apiKeyContext(r.Context(), authenticator, tokenStr)
+			if err != nil {
+				writeAPIKeyError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
 
-		if err != nil || !token.Valid {
+		claims, err := authenticator.VerifyJWT(tokenStr)
+		if err != nil {
 			pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("неверный или просроченный токен"))
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("неверные claims"))
+		// служебные токены (например, mfa_token после ввода пароля) подписаны тем же ключом,
+		// но доступа к API не дают
+		if typ, _ := claims["typ"].(string); typ != "" {
+			pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("токен не является токеном доступа"))
 			return
 		}
 
@@ -74,26 +119,120 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		role, _ := claims["role"].(string)
+		mfa, _ := claims["mfa"].(bool)
+
+		// claim act — администратор, вошедший от имени пользователя user_id
+		var actorID int64
+		if act, ok := claims["act"].(map[string]any); ok {
+			sub, _ := act["sub"].(string)
+			id, err := strconv.ParseInt(sub, 10, 64)
+			if err != nil {
+				pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("неверный claim act в токене"))
+				return
+			}
+			actorID = id
+		}
+
+		// токены, выданные до появления jti, отозвать нельзя — пропускаем их до истечения срока
+		jti, _ := claims["jti"].(string)
+		if jti != "" {
+			revoked, err := authenticator.IsAccessTokenRevoked(jti)
+			if err != nil {
+				pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось проверить токен"))
+				return
+			}
+			if revoked {
+				pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("токен отозван"))
+				return
+			}
+		}
+
+		// токены, выданные до появления сессий, sid не содержат
+		sid, _ := claims["sid"].(string)
+		if sid != "" {
+			if err := authenticator.CheckSession(sid); err != nil {
+				if errors.Is(err, service.ErrSessionTerminated) {
+					pkg.WriteJSONResponse(w, http.StatusUnauthorized, err)
+				} else {
+					pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось проверить сессию"))
+				}
+				return
+			}
+		}
+
+		// приостановка действует сразу, не дожидаясь истечения выданных токенов
+		if err := authenticator.CheckAccount(userID); err != nil {
+			writeAccountError(w, err)
+			return
+		}
+		if actorID != 0 {
+			if err := authenticator.CheckAccount(actorID); err != nil {
+				writeAccountError(w, err)
+				return
+			}
+		}
 
 		// запишем в context
 		ctx := context.WithValue(r.Context(), ctxKeyUserID, userID)
 		ctx = context.WithValue(ctx, ctxKeyUserRole, role)
+		ctx = context.WithValue(ctx, ctxKeyTokenID, jti)
+		ctx = context.WithValue(ctx, ctxKeySession, sid)
+		ctx = context.WithValue(ctx, ctxKeyMFA, mfa)
+		ctx = oauthGrantContext(ctx, claims)
+		if actorID != 0 {
+			ctx = context.WithValue(ctx, ctxKeyActorID, actorID)
+		}
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// RequireRole возвращает middleware, который разрешает доступ только роли requiredRole (например "admin")
-func RequireRole(requiredRole string) mux.MiddlewareFunc {
+func writeAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrAccountSuspended):
+		pkg.WriteJSONResponse(w, http.StatusForbidden, err)
+	case errors.Is(err, model.ErrUnknownUser):
+		pkg.WriteJSONResponse(w, http.StatusUnauthorized, err)
+	default:
+		pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось проверить аккаунт"))
+	}
+}
+
+// PermissionChecker сообщает, есть ли право у текущей роли пользователя.
+type PermissionChecker interface {
+	HasPermission(userID int64, perm model.Permission) (bool, error)
+}
+
+// RequirePermission возвращает middleware, который пропускает пользователя, только если
+// у его роли есть все права perms. Роль берётся из хранилища на каждый запрос, поэтому
+// смена роли действует сразу, без перевыпуска токенов. Права ролей действуют только
+// при входе по паролю с двухфакторной аутентификацией; API-ключам и приложениям OAuth2
+// они не достаются.
+func RequirePermission(checker PermissionChecker, perms ...model.Permission) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			role, err := GetUserRole(r.Context())
+			userID, err := GetUserID(r.Context())
 			if err != nil {
 				pkg.WriteJSONResponse(w, http.StatusUnauthorized, errors.New("требуется авторизация"))
 				return
 			}
-			if role != requiredRole {
-				pkg.WriteJSONResponse(w, http.StatusForbidden, errors.New("доступ запрещён"))
+			if err := delegatedAccessError(r.Context()); err != nil {
+				pkg.WriteJSONResponse(w, http.StatusForbidden, err)
+				return
+			}
+			for _, perm := range perms {
+				ok, err := checker.HasPermission(userID, perm)
+				if err != nil {
+					pkg.WriteJSONResponse(w, http.StatusInternalServerError, errors.New("не удалось проверить права"))
+					return
+				}
+				if !ok {
+					pkg.WriteJSONResponse(w, http.StatusForbidden, fmt.Errorf("доступ запрещён: нужно право %s", perm))
+					return
+				}
+			}
+			if !IsMFAVerified(r.Context()) {
+				pkg.WriteJSONResponse(w, http.StatusForbidden, errors.New("для этого действия нужно подключить двухфакторную аутентификацию (POST /mfa/enroll) и войти с кодом"))
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -114,6 +253,27 @@ func GetUserID(ctx context.Context) (int64, error) {
 	return id, nil
 }
 
+// GetActorID — helper: администратор, который выполняет запрос от имени пользователя
+// GetUserID; false — пользователь вошёл сам
+func GetActorID(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(ctxKeyActorID).(int64)
+	return id, ok
+}
+
+// DenyImpersonation возвращает middleware, который не пускает администратора, вошедшего
+// от имени пользователя, к необратимым действиям с аккаунтом (пароль, email, 2FA, ключи, удаление).
+func DenyImpersonation() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := GetActorID(r.Context()); ok {
+				pkg.WriteJSONResponse(w, http.StatusForbidden, errors.New("недоступно при входе от имени пользователя"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // GetUserRole — helper: доставать роль из context
 func GetUserRole(ctx context.Context) (string, error) {
 	v := ctx.Value(ctxKeyUserRole)
@@ -126,3 +286,21 @@ func GetUserRole(ctx context.Context) (string, error) {
 	}
 	return role, nil
 }
+
+// GetTokenID — helper: доставать jti access-токена из context (пустая строка, если его нет)
+func GetTokenID(ctx context.Context) string {
+	jti, _ := ctx.Value(ctxKeyTokenID).(string)
+	return jti
+}
+
+// GetSessionID — helper: доставать сессию (claim sid) из context (пустая строка, если её нет)
+func GetSessionID(ctx context.Context) string {
+	sid, _ := ctx.Value(ctxKeySession).(string)
+	return sid
+}
+
+// IsMFAVerified — helper: прошёл ли пользователь проверку второго фактора при входе
+func IsMFAVerified(ctx context.Context) bool {
+	mfa, _ := ctx.Value(ctxKeyMFA).(bool)
+	return mfa
+}
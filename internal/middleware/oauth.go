@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"go.mood/internal/model"
+	"strings"
+)
+
+const ctxKeyOAuthGrant ctxKey = "oauth_grant"
+
+// oauthGrantContext кладёт в context приложение OAuth2 и выданные ему права, если
+// access-токен выдан приложению (claims client_id и scope).
+func oauthGrantContext(ctx context.Context, claims map[string]any) context.Context {
+	clientID, _ := claims["client_id"].(string)
+	if clientID == "" {
+		return ctx
+	}
+	scope, _ := claims["scope"].(string)
+	return context.WithValue(ctx, ctxKeyOAuthGrant, model.OAuthGrant{
+		ClientID: clientID,
+		Scopes:   model.ParseScopes(strings.Fields(scope)),
+	})
+}
+
+// GetOAuthGrant — helper: приложение OAuth2 и его права, если запрос пришёл с токеном
+// приложения; false — пользователь вошёл сам или пришёл с API-ключом
+func GetOAuthGrant(ctx context.Context) (model.OAuthGrant, bool) {
+	grant, ok := ctx.Value(ctxKeyOAuthGrant).(model.OAuthGrant)
+	return grant, ok
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix — начало всех персональных API-ключей; по нему AuthMiddleware
+// отличает ключ от JWT.
+const APIKeyPrefix = "pat_"
+
+// Scope — право API-ключа.
+type Scope string
+
+const (
+	ScopeTasksRead     Scope = "tasks:read"
+	ScopeTasksWrite    Scope = "tasks:write"
+	ScopeProjectsRead  Scope = "projects:read"
+	ScopeProjectsWrite Scope = "projects:write"
+	ScopeTagsRead      Scope = "tags:read"
+	ScopeTagsWrite     Scope = "tags:write"
+)
+
+var knownScopes = map[Scope]bool{
+	ScopeTasksRead: true, ScopeTasksWrite: true,
+	ScopeProjectsRead: true, ScopeProjectsWrite: true,
+	ScopeTagsRead: true, ScopeTagsWrite: true,
+}
+
+var scopeDescriptions = map[Scope]string{
+	ScopeTasksRead:     "Просмотр задач",
+	ScopeTasksWrite:    "Создание, изменение и удаление задач",
+	ScopeProjectsRead:  "Просмотр проектов",
+	ScopeProjectsWrite: "Создание, изменение и удаление проектов",
+	ScopeTagsRead:      "Просмотр меток",
+	ScopeTagsWrite:     "Создание, изменение и удаление меток",
+}
+
+// Valid сообщает, известен ли scope.
+func (s Scope) Valid() bool {
+	return knownScopes[s]
+}
+
+// Description возвращает описание права для экрана согласия.
+func (s Scope) Description() string {
+	return scopeDescriptions[s]
+}
+
+// APIKey — персональный ключ доступа для скриптов и интеграций. В хранилище лежит
+// только SHA-256 от ключа; сам ключ показывается один раз при создании.
+type APIKey struct {
+	Id         int64      `json:"id"`
+	UserId     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // видимое начало ключа, например "pat_3fQx9Z"
+	TokenHash  string     `json:"-"`
+	Scopes     []Scope    `json:"scopes"` // пусто — все права пользователя
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+// Allows сообщает, разрешено ли ключу действие со scope.
+func (k APIKey) Allows(scope Scope) bool {
+	if len(k.Scopes) == 0 {
+		return true
+	}
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active сообщает, можно ли пользоваться ключом в момент now.
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// NewAPIKey — запрос на создание API-ключа.
+type NewAPIKey struct {
+	Name      string     `json:"name"`
+	Scopes    []Scope    `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedAPIKey — ответ на создание ключа: единственный раз, когда виден сам ключ.
+type CreatedAPIKey struct {
+	APIKey
+	Token string `json:"token"`
+}
+
+// ScopeStrings возвращает scopes в виде строк для хранения.
+func ScopeStrings(scopes []Scope) []string {
+	out := make([]string, len(scopes))
+	for i, s := range scopes {
+		out[i] = string(s)
+	}
+	return out
+}
+
+// ParseScopes преобразует сохранённые строки обратно в scopes.
+func ParseScopes(values []string) []Scope {
+	out := make([]Scope, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, Scope(v))
+		}
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package model
+
+import "errors"
+
+// Ошибки хранилища, которые одинаково возвращают PostgreSQL и in-memory реализации.
+var (
+	ErrUnknownUser = errors.New("пользователь не найден")
+	ErrEmailTaken  = errors.New("email уже используется")
+
+	ErrTagNameTaken = errors.New("метка с таким именем уже существует")
+	ErrUnknownTag   = errors.New("метка не найдена или вы не являетесь её владельцем")
+
+	ErrUnknownProject = errors.New("проект не найден или вы не являетесь его владельцем")
+
+	ErrUnknownParentTask = errors.New("родительская задача не найдена или вы не являетесь её владельцем")
+	ErrTaskHasSubtasks   = errors.New("у задачи есть подзадачи: удалите их или передайте cascade=true")
+	ErrInvalidTransition = errors.New("недопустимый переход статуса")
+
+	ErrUnknownSeries = errors.New("серия не найдена или вы не являетесь её владельцем")
+
+	ErrUnknownRefreshToken = errors.New("refresh-токен не найден")
+	ErrRefreshTokenReused  = errors.New("refresh-токен уже был использован: все токены этой цепочки отозваны")
+
+	ErrInvalidUserToken = errors.New("ссылка недействительна или устарела")
+
+	ErrMFANotEnrolled = errors.New("двухфакторная аутентификация не подключена")
+
+	ErrUnknownAPIKey = errors.New("API-ключ не найден или вы не являетесь его владельцем")
+
+	ErrUnknownRole = errors.New("роль не найдена")
+	ErrRoleExists  = errors.New("роль с таким именем уже существует")
+	ErrRoleInUse   = errors.New("роль назначена пользователям: сначала назначьте им другую роль")
+
+	ErrUnknownIdentity = errors.New("внешняя учётная запись не привязана")
+	ErrIdentityLinked  = errors.New("внешняя учётная запись уже привязана к пользователю")
+
+	ErrUnknownOAuthClient = errors.New("приложение не найдено")
+	ErrInvalidOAuthCode   = errors.New("код авторизации не найден или уже использован")
+	ErrOAuthCodeReused    = errors.New("код авторизации использован повторно")
+
+	ErrUnknownSession = errors.New("сессия не найдена")
+)
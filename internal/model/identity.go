@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Identity — учётная запись у внешнего провайдера OpenID Connect, привязанная к пользователю.
+// Пользователь находится по паре Provider и Subject (claim sub ID-токена).
+type Identity struct {
+	Id          int64      `json:"id"`
+	UserId      int64      `json:"user_id"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"` // email у провайдера на момент привязки
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+// OIDCAuthRequest — начало входа через провайдера: клиента перенаправляют на AuthURL,
+// а State сохраняется в cookie до возврата от провайдера.
+type OIDCAuthRequest struct {
+	AuthURL      string
+	State        string
+	ExpiresIn    int64 // сколько секунд ждать возврата от провайдера
+	SecureCookie bool  // адрес возврата работает по HTTPS
+}
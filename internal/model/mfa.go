@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// MFA — настройки TOTP пользователя. Пока ConfirmedAt пуст, секрет только выдан
+// и двухфакторная аутентификация не действует.
+type MFA struct {
+	UserId      int64
+	Secret      string
+	ConfirmedAt *time.Time
+	LastCounter int64 // последний принятый шаг времени TOTP
+}
+
+// Enabled сообщает, подтверждена ли двухфакторная аутентификация.
+func (m MFA) Enabled() bool {
+	return m.ConfirmedAt != nil
+}
+
+// RecoveryCode — одноразовый код восстановления; хранится только bcrypt-хеш.
+type RecoveryCode struct {
+	Id       int64
+	UserId   int64
+	CodeHash string
+	UsedAt   *time.Time
+}
+
+// MFAEnrollment — ответ на начало подключения TOTP.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// MFAStatus — состояние двухфакторной аутентификации пользователя.
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// MFAChallenge — ответ на вход по паролю, когда нужен второй фактор.
+// MFAToken обменивается на токены доступа вместе с кодом через POST /login/mfa.
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// MFAConfirmation — ответ на подтверждение TOTP: коды восстановления показываются
+// один раз, а прежние сессии завершаются и выдаются новые токены.
+type MFAConfirmation struct {
+	RecoveryCodes []string   `json:"recovery_codes"`
+	Tokens        *TokenPair `json:"tokens"`
+}
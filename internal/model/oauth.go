@@ -0,0 +1,157 @@
+package model
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OAuthClient — стороннее приложение, которому пользователи дают доступ к своим данным
+// по OAuth2. У публичного клиента (мобильное или настольное приложение) секрета нет:
+// его защищает только PKCE. Секрет конфиденциального клиента хранится как SHA-256.
+type OAuthClient struct {
+	Id           string    `json:"client_id"`
+	Name         string    `json:"name"`
+	SecretHash   string    `json:"-"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []Scope   `json:"scopes"` // права, которые приложение может запросить
+	CreatedBy    *int64    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Confidential сообщает, что у клиента есть секрет.
+func (c OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// HasRedirectURI сообщает, зарегистрирован ли адрес возврата. Сравнение точное, кроме
+// порта loopback-адресов: настольное приложение слушает свободный порт, выбранный при
+// запуске (RFC 8252, п. 7.3).
+func (c OAuthClient) HasRedirectURI(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri || sameLoopbackURI(u, uri) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLoopbackHost сообщает, что хост — loopback-адрес (localhost, 127.0.0.1 или ::1).
+func IsLoopbackHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// sameLoopbackURI сравнивает http-адреса на loopback-хосте без учёта порта.
+func sameLoopbackURI(registered, uri string) bool {
+	a, err := url.Parse(registered)
+	if err != nil || a.Scheme != "http" || !IsLoopbackHost(a.Hostname()) {
+		return false
+	}
+	b, err := url.Parse(uri)
+	if err != nil || b.Fragment != "" {
+		return false
+	}
+	return b.Scheme == a.Scheme && b.Hostname() == a.Hostname() && b.User == nil &&
+		b.EscapedPath() == a.EscapedPath() && b.RawQuery == a.RawQuery
+}
+
+// NewOAuthClient — запрос на регистрацию приложения.
+type NewOAuthClient struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []Scope  `json:"scopes"`
+	Confidential bool     `json:"confidential"` // выдать секрет (серверное приложение)
+}
+
+// CreatedOAuthClient — ответ на регистрацию: единственный раз, когда виден секрет.
+type CreatedOAuthClient struct {
+	OAuthClient
+	Secret string `json:"client_secret,omitempty"`
+}
+
+// OAuthCode — одноразовый код авторизации. Хранится только SHA-256 от кода;
+// FamilyID — цепочка refresh-токенов, выданных в обмен на код.
+type OAuthCode struct {
+	Id            int64
+	CodeHash      string
+	ClientID      string
+	UserId        int64
+	RedirectURI   string
+	Scopes        []Scope
+	CodeChallenge string // S256 от code_verifier (PKCE)
+	FamilyID      string
+	ExpiresAt     time.Time
+	CreatedAt     time.Time
+	UsedAt        *time.Time
+}
+
+// AuthorizationRequest — параметры запроса авторизации (RFC 6749, п. 4.1.1, и PKCE, RFC 7636).
+type AuthorizationRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// ConsentScreen — данные для экрана согласия: какое приложение и какие права запрашивает.
+type ConsentScreen struct {
+	ClientID    string      `json:"client_id"`
+	ClientName  string      `json:"client_name"`
+	Scopes      []ScopeInfo `json:"scopes"`
+	RedirectURI string      `json:"redirect_uri"`
+	State       string      `json:"state,omitempty"`
+}
+
+// ScopeInfo — право с описанием для пользователя.
+type ScopeInfo struct {
+	Scope       Scope  `json:"scope"`
+	Description string `json:"description"`
+}
+
+// OAuthGrant — приложение и права, с которыми пришёл запрос по OAuth2 access-токену.
+type OAuthGrant struct {
+	ClientID string
+	Scopes   []Scope
+}
+
+// Allows сообщает, выдано ли приложению право scope. В отличие от API-ключа,
+// пустой набор прав не даёт ничего.
+func (g OAuthGrant) Allows(scope Scope) bool {
+	for _, s := range g.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthTokenResponse — ответ эндпоинта токенов в формате RFC 6749, п. 5.1.
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// TokenIntrospection — ответ интроспекции токена (RFC 7662). У неактивного токена
+// заполнено только Active.
+type TokenIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	JTI       string `json:"jti,omitempty"`
+}
+
+// JoinScopes записывает права через пробел, как параметр scope в OAuth2.
+func JoinScopes(scopes []Scope) string {
+	return strings.Join(ScopeStrings(scopes), " ")
+}
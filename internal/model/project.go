@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type Project struct {
+	Id          int       `json:"id"`
+	UserId      int64     `json:"user_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Color       string    `json:"color"`    // #RRGGBB, как у меток
+	Archived    bool      `json:"archived"` // задачи архивного проекта скрыты из GET /tasks
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
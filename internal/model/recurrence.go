@@ -0,0 +1,255 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency — частота повторения из RRULE.
+type Frequency string
+
+const (
+	FreqDaily   Frequency = "DAILY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqMonthly Frequency = "MONTHLY"
+	FreqYearly  Frequency = "YEARLY"
+)
+
+// rruleDays сопоставляет коды дней недели RFC 5545 с time.Weekday.
+var rruleDays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// untilLayouts — допустимые форматы UNTIL: дата или дата-время в UTC.
+var untilLayouts = []string{"20060102T150405Z", "20060102T150405", "20060102"}
+
+// Recurrence — правило повторения, подмножество RRULE из RFC 5545:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, BYDAY (только для DAILY и WEEKLY),
+// а также UNTIL или COUNT.
+type Recurrence struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []time.Weekday
+	Until    *time.Time
+	Count    int
+}
+
+// ParseRecurrence разбирает строку вида "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=10".
+// Префикс "RRULE:" допускается.
+func ParseRecurrence(s string) (Recurrence, error) {
+	r := Recurrence{Interval: 1}
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	if s == "" {
+		return r, errors.New("пустое правило")
+	}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return r, fmt.Errorf("некорректная часть правила %q", part)
+		}
+		if seen[key] {
+			return r, fmt.Errorf("параметр %s указан дважды", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			r.Freq = Frequency(value)
+			if r.Freq != FreqDaily && r.Freq != FreqWeekly && r.Freq != FreqMonthly && r.Freq != FreqYearly {
+				return r, fmt.Errorf("неподдерживаемая частота %s", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("INTERVAL должен быть положительным числом")
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("COUNT должен быть положительным числом")
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return r, err
+			}
+			r.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := rruleDays[code]
+				if !ok {
+					return r, fmt.Errorf("неизвестный день недели %q", code)
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		default:
+			return r, fmt.Errorf("неподдерживаемый параметр %s", key)
+		}
+	}
+
+	switch {
+	case r.Freq == "":
+		return r, errors.New("не указан FREQ")
+	case r.Count > 0 && r.Until != nil:
+		return r, errors.New("COUNT и UNTIL нельзя указывать вместе")
+	case len(r.ByDay) > 0 && r.Freq != FreqDaily && r.Freq != FreqWeekly:
+		return r, errors.New("BYDAY поддерживается только для DAILY и WEEKLY")
+	case len(r.ByDay) > 0 && r.Freq == FreqDaily && r.Interval%7 == 0:
+		// День недели при таком шаге не меняется: правило либо не зависит от BYDAY,
+		// либо не совпадает ни с одним днём
+		return r, errors.New("BYDAY с FREQ=DAILY и INTERVAL, кратным 7, не поддерживается: используйте FREQ=WEEKLY")
+	}
+	return r, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range untilLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			// Дата без времени означает «включая весь этот день»
+			if layout == "20060102" {
+				t = t.Add(24*time.Hour - time.Nanosecond)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("UNTIL должен быть в формате YYYYMMDD или YYYYMMDDTHHMMSSZ")
+}
+
+// String возвращает правило в каноническом виде RRULE без префикса.
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for code, day := range rruleDays {
+			if r.hasDay(day) {
+				codes = append(codes, code)
+			}
+		}
+		// Упорядочиваем дни с понедельника, как принято в RRULE
+		sortWeekdayCodes(codes)
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next возвращает первое повторение строго после due, сохраняя время суток.
+// UNTIL и COUNT не учитываются — их проверяет TaskSeries.NextOccurrence.
+func (r Recurrence) Next(due time.Time) time.Time {
+	interval := max(r.Interval, 1)
+	switch r.Freq {
+	case FreqDaily:
+		next := due.AddDate(0, 0, interval)
+		// Дни недели повторяются с периодом 7, а INTERVAL, кратный 7, с BYDAY запрещён
+		// в ParseRecurrence, поэтому за 7 шагов находится каждый день недели
+		for i := 0; i < 7 && len(r.ByDay) > 0 && !r.hasDay(next.Weekday()); i++ {
+			next = next.AddDate(0, 0, interval)
+		}
+		return next
+	case FreqWeekly:
+		if len(r.ByDay) == 0 {
+			return due.AddDate(0, 0, 7*interval)
+		}
+		// Сначала оставшиеся дни текущей недели (неделя начинается с понедельника),
+		// затем дни недели через interval недель
+		weekday := (int(due.Weekday()) + 6) % 7
+		for d := weekday + 1; d < 7; d++ {
+			if r.hasDay(time.Weekday((d + 1) % 7)) {
+				return due.AddDate(0, 0, d-weekday)
+			}
+		}
+		monday := due.AddDate(0, 0, 7*interval-weekday)
+		for d := 0; d < 7; d++ {
+			if r.hasDay(time.Weekday((d + 1) % 7)) {
+				return monday.AddDate(0, 0, d)
+			}
+		}
+	case FreqMonthly, FreqYearly:
+		// Несуществующие даты (31 число, 29 февраля) пропускаются, как в RFC 5545
+		for k := 1; ; k++ {
+			months := k * interval
+			if r.Freq == FreqYearly {
+				months *= 12
+			}
+			next := due.AddDate(0, months, 0)
+			if next.Day() == due.Day() {
+				return next
+			}
+		}
+	}
+	return due
+}
+
+func (r Recurrence) hasDay(day time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func sortWeekdayCodes(codes []string) {
+	rank := func(code string) int { return (int(rruleDays[code]) + 6) % 7 }
+	for i := 1; i < len(codes); i++ {
+		for j := i; j > 0 && rank(codes[j]) < rank(codes[j-1]); j-- {
+			codes[j], codes[j-1] = codes[j-1], codes[j]
+		}
+	}
+}
+
+// TaskSeries — серия повторяющейся задачи. Каждое повторение — отдельная задача
+// с SeriesID; при выполнении очередной задачи создаётся следующая.
+type TaskSeries struct {
+	Id          int       `json:"id"`
+	UserId      int64     `json:"user_id"`
+	RRule       string    `json:"rrule"`
+	Occurrences int       `json:"occurrences"` // сколько задач серии уже создано
+	Stopped     bool      `json:"stopped"`     // остановленная серия больше не порождает задач
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// NextOccurrence возвращает срок следующей задачи серии после задачи со сроком due.
+// false — серия остановлена, исчерпан COUNT или следующий срок позже UNTIL.
+func (s TaskSeries) NextOccurrence(due time.Time) (time.Time, bool) {
+	if s.Stopped {
+		return time.Time{}, false
+	}
+	rule, err := ParseRecurrence(s.RRule)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if rule.Count > 0 && s.Occurrences >= rule.Count {
+		return time.Time{}, false
+	}
+	next := rule.Next(due)
+	if rule.Until != nil && next.After(*rule.Until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// SeriesUpdate — изменение серии: новое правило и поля, которые применяются
+// ко всем ещё не выполненным задачам серии. Пустое значение — не менять.
+type SeriesUpdate struct {
+	RRule    string   `json:"rrule"`
+	Task     string   `json:"task"`
+	Priority Priority `json:"priority"`
+}
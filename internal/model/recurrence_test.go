@@ -0,0 +1,30 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrenceRejectsUnreachableByDay(t *testing.T) {
+	for _, rule := range []string{"FREQ=DAILY;INTERVAL=7;BYDAY=MO", "FREQ=DAILY;INTERVAL=14;BYDAY=MO,FR"} {
+		if _, err := ParseRecurrence(rule); err == nil {
+			t.Errorf("ParseRecurrence(%q) принял правило, которое не совпадает с днями BYDAY", rule)
+		}
+	}
+	if _, err := ParseRecurrence("FREQ=DAILY;INTERVAL=7"); err != nil {
+		t.Errorf("ParseRecurrence(FREQ=DAILY;INTERVAL=7): %v", err)
+	}
+}
+
+func TestRecurrenceNextDailyByDay(t *testing.T) {
+	wednesday := time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC)
+	for interval := 1; interval <= 13; interval++ {
+		if interval%7 == 0 {
+			continue
+		}
+		rule := Recurrence{Freq: FreqDaily, Interval: interval, ByDay: []time.Weekday{time.Monday}}
+		if next := rule.Next(wednesday); next.Weekday() != time.Monday || !next.After(wednesday) {
+			t.Errorf("INTERVAL=%d: следующее повторение %s, ожидался понедельник после %s", interval, next, wednesday)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Permission — именованное право пользователя; права выдаются через роль.
+type Permission string
+
+const (
+	PermUsersRead        Permission = "users.read"
+	PermUsersDelete      Permission = "users.delete"
+	PermUsersUnlock      Permission = "users.unlock"
+	PermUsersSuspend     Permission = "users.suspend"
+	PermUsersImpersonate Permission = "users.impersonate"
+	PermRolesRead        Permission = "roles.read"
+	PermRolesManage      Permission = "roles.manage"
+	PermTasksReadAny     Permission = "tasks.read_any"
+	PermClientsManage    Permission = "clients.manage"
+)
+
+// Permissions — все известные права; встроенная роль admin получает их все.
+var Permissions = []Permission{
+	PermUsersRead, PermUsersDelete, PermUsersUnlock, PermUsersSuspend, PermUsersImpersonate,
+	PermRolesRead, PermRolesManage,
+	PermTasksReadAny,
+	PermClientsManage,
+}
+
+// Valid сообщает, известно ли право.
+func (p Permission) Valid() bool {
+	for _, known := range Permissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleDefinition — роль как набор прав. Встроенные роли user и admin
+// нельзя изменить или удалить.
+type RoleDefinition struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+	BuiltIn     bool         `json:"built_in"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// Has сообщает, входит ли право в роль.
+func (r RoleDefinition) Has(perm Permission) bool {
+	for _, p := range r.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Administrative сообщает, равносильна ли роль администратору: право roles.manage
+// позволяет выдать себе любые другие права.
+func (r RoleDefinition) Administrative() bool {
+	return r.Has(PermRolesManage)
+}
+
+// BuiltInRoles возвращает встроенные роли: user без особых прав и admin со всеми правами.
+func BuiltInRoles() []RoleDefinition {
+	return []RoleDefinition{
+		{Name: string(RoleUser), Description: "Обычный пользователь", Permissions: []Permission{}, BuiltIn: true},
+		{Name: string(RoleAdmin), Description: "Администратор", Permissions: append([]Permission(nil), Permissions...), BuiltIn: true},
+	}
+}
+
+// NewRole — запрос на создание или изменение роли. При изменении Name не используется.
+type NewRole struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// PermissionStrings возвращает права в виде строк для хранения.
+func PermissionStrings(perms []Permission) []string {
+	out := make([]string, len(perms))
+	for i, p := range perms {
+		out[i] = string(p)
+	}
+	return out
+}
+
+// ParsePermissions преобразует сохранённые строки обратно в права.
+func ParsePermissions(values []string) []Permission {
+	out := make([]Permission, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, Permission(v))
+		}
+	}
+	return out
+}
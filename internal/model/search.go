@@ -0,0 +1,72 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchTerm — слово или фраза из поискового запроса. Prefix означает,
+// что последнее слово ищется как префикс (запрос вида "отчёт*").
+type SearchTerm struct {
+	Words  []string
+	Prefix bool
+}
+
+// SearchQuery — разобранный поисковый запрос: задача подходит,
+// если в ней встречаются все термы.
+type SearchQuery struct {
+	Terms []SearchTerm
+}
+
+// TaskSearchResult — найденная задача с релевантностью и фрагментом текста,
+// в котором совпадения выделены тегами <b></b>. Snippet — готовый HTML: текст задачи
+// в нём экранирован, так что тегами в нём могут быть только <b></b>.
+type TaskSearchResult struct {
+	Task
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// Empty сообщает, что в запросе нет ни одного терма.
+func (q SearchQuery) Empty() bool {
+	return len(q.Terms) == 0
+}
+
+// ParseSearchQuery разбирает строку поиска: слова в двойных кавычках образуют
+// фразу, "*" в конце слова или фразы включает поиск по префиксу.
+// Все символы, кроме букв и цифр, считаются разделителями.
+func ParseSearchQuery(s string) SearchQuery {
+	var q SearchQuery
+	for i, part := range strings.Split(s, `"`) {
+		// Нечётные части находятся внутри кавычек
+		if i%2 == 1 {
+			if term, ok := newSearchTerm(part); ok {
+				q.Terms = append(q.Terms, term)
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			if term, ok := newSearchTerm(word); ok {
+				q.Terms = append(q.Terms, term)
+			}
+		}
+	}
+	return q
+}
+
+func newSearchTerm(s string) (SearchTerm, bool) {
+	s = strings.TrimSpace(s)
+	term := SearchTerm{
+		Words:  SearchTokens(s),
+		Prefix: strings.HasSuffix(s, "*"),
+	}
+	return term, len(term.Words) > 0
+}
+
+// SearchTokens разбивает текст на слова в нижнем регистре так же,
+// как это делает конфигурация 'simple' полнотекстового поиска PostgreSQL.
+func SearchTokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
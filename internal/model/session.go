@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// Session — вход пользователя на устройстве или доступ стороннего приложения. Id совпадает
+// с цепочкой refresh-токенов, начатой при входе, и передаётся в access-токенах в claim sid:
+// завершённая сессия отзывает цепочку, и её access-токены перестают приниматься сразу.
+type Session struct {
+	Id         string     `json:"id"`
+	UserId     int64      `json:"-"`
+	ClientID   string     `json:"client_id,omitempty"`   // приложение OAuth2; пусто — вход самого пользователя
+	ClientName string     `json:"client_name,omitempty"` // название приложения (только при чтении)
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"` // адрес, с которого выполнен вход
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"` // когда истечёт последний refresh-токен
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `json:"current"` // сессия, из которой пришёл запрос
+}
+
+// Active сообщает, что сессия не завершена и не истекла.
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// DeviceInfo — откуда выполнен вход; сохраняется в сессии, чтобы пользователь узнал устройство.
+type DeviceInfo struct {
+	UserAgent string
+	IP        string
+}
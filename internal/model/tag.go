@@ -0,0 +1,24 @@
+package model
+
+import (
+	"regexp"
+	"time"
+)
+
+// DefaultTagColor — цвет метки, если он не указан.
+const DefaultTagColor = "#808080"
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type Tag struct {
+	Id        int       `json:"id"`
+	UserId    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"` // #RRGGBB
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ValidTagColor сообщает, записан ли цвет в формате #RRGGBB.
+func ValidTagColor(color string) bool {
+	return tagColorPattern.MatchString(color)
+}
@@ -2,11 +2,116 @@ package model
 
 import "time"
 
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// Valid сообщает, является ли приоритет одним из допустимых значений.
+func (p Priority) Valid() bool {
+	switch p {
+	case PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent:
+		return true
+	}
+	return false
+}
+
+// TaskStatus — состояние задачи в рабочем процессе.
+type TaskStatus string
+
+const (
+	StatusPending    TaskStatus = "pending"
+	StatusInProgress TaskStatus = "in_progress"
+	StatusBlocked    TaskStatus = "blocked"
+	StatusDone       TaskStatus = "done"
+	StatusCancelled  TaskStatus = "cancelled"
+)
+
+// statusTransitions — разрешённые переходы между статусами.
+// Выполненную задачу можно вернуть в работу, отменённую — только в pending.
+var statusTransitions = map[TaskStatus][]TaskStatus{
+	StatusPending:    {StatusInProgress, StatusBlocked, StatusDone, StatusCancelled},
+	StatusInProgress: {StatusPending, StatusBlocked, StatusDone, StatusCancelled},
+	StatusBlocked:    {StatusPending, StatusInProgress, StatusCancelled},
+	StatusDone:       {StatusPending, StatusInProgress},
+	StatusCancelled:  {StatusPending},
+}
+
+// Valid сообщает, является ли статус одним из допустимых значений.
+func (s TaskStatus) Valid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// Open сообщает, что задача ещё не выполнена и не отменена.
+func (s TaskStatus) Open() bool {
+	return s != StatusDone && s != StatusCancelled
+}
+
+// CanTransitionTo сообщает, можно ли перевести задачу из статуса s в to.
+// Повторная установка того же статуса разрешена и ничего не меняет.
+func (s TaskStatus) CanTransitionTo(to TaskStatus) bool {
+	if s == to {
+		return to.Valid()
+	}
+	for _, allowed := range statusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusesAllowedTo возвращает статусы, из которых разрешён переход в to, включая сам to.
+func StatusesAllowedTo(to TaskStatus) []TaskStatus {
+	from := []TaskStatus{to}
+	for s, targets := range statusTransitions {
+		for _, t := range targets {
+			if t == to {
+				from = append(from, s)
+			}
+		}
+	}
+	return from
+}
+
 type Task struct {
-	Id        int       `gorm:"primary_key" json:"id"`
-	UserId    int64     `json:"user_id"`
-	Task      string    `json:"task"`       // Изменено с int на string
-	Status    string    `json:"status"`     // Изменено с int на string
-	CreatedAt time.Time `json:"created_at"` // Добавлено для created_at
-	UpdatedAt time.Time `json:"updated_at"` // Добавлено для updated_at
+	Id              int        `gorm:"primary_key" json:"id"`
+	UserId          int64      `json:"user_id"`
+	Task            string     `json:"task"`                   // Изменено с int на string
+	Status          TaskStatus `json:"status"`                 // pending, in_progress, blocked, done, cancelled
+	DueAt           *time.Time `json:"due_at"`                 // Срок выполнения, может отсутствовать
+	Priority        Priority   `json:"priority"`               // low, normal, high, urgent
+	CompletedAt     *time.Time `json:"completed_at,omitempty"` // Заполняется автоматически при выполнении
+	StatusChangedAt *time.Time `json:"status_changed_at"`      // Когда статус менялся последний раз
+	Tags            []Tag      `json:"tags,omitempty"`         // Метки задачи, заполняются при чтении
+	TagIDs          []int64    `json:"tag_ids,omitempty"`      // Метки для привязки; nil — не менять
+	ProjectID       *int64     `json:"project_id"`             // Проект задачи, nil — без проекта
+	ParentID        *int64     `json:"parent_id"`              // Родительская задача, nil — задача верхнего уровня
+	SeriesID        *int64     `json:"series_id"`              // Серия повторяющейся задачи, nil — задача не повторяется
+	Recurrence      string     `json:"recurrence,omitempty"`   // RRULE при создании; дальше правило хранится в серии
+	Progress        *Progress  `json:"progress,omitempty"`     // Прогресс по подзадачам, если они есть
+	Subtasks        []Task     `json:"subtasks,omitempty"`     // Дерево подзадач, заполняется только для /tasks/{id}/tree
+	CreatedAt       time.Time  `json:"created_at"`             // Добавлено для created_at
+	UpdatedAt       time.Time  `json:"updated_at"`             // Добавлено для updated_at
+}
+
+// Progress — доля выполненных прямых подзадач.
+type Progress struct {
+	Total   int `json:"total"`
+	Done    int `json:"done"`
+	Percent int `json:"percent"` // 0..100, округляется вниз
+}
+
+// NewProgress считает прогресс по числу подзадач total и выполненных из них done.
+func NewProgress(total, done int) *Progress {
+	p := &Progress{Total: total, Done: done}
+	if total > 0 {
+		p.Percent = done * 100 / total
+	}
+	return p
 }
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TaskSortFields — поля, по которым разрешена сортировка списка задач.
+var TaskSortFields = []string{"id", "created_at", "updated_at", "due_at", "priority"}
+
+// sortTimeLayout — формат времени в ключе сортировки. Фиксированная ширина
+// позволяет сравнивать ключи как строки, а PostgreSQL разбирает его как TIMESTAMP.
+const sortTimeLayout = "2006-01-02 15:04:05.000000"
+
+// priorityRank задаёт порядок приоритетов от низкого к срочному.
+var priorityRank = map[Priority]int{
+	PriorityLow:    0,
+	PriorityNormal: 1,
+	PriorityHigh:   2,
+	PriorityUrgent: 3,
+}
+
+// TaskFilter — условия выборки задач пользователя для GET /tasks.
+type TaskFilter struct {
+	UserID      int64
+	Status      TaskStatus
+	Priority    Priority
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	Search      string   // подстрока в тексте задачи без учёта регистра
+	Tags        []string // имена меток, которые должны быть у задачи одновременно
+	ProjectID   *int64   // только задачи этого проекта, включая архивный
+	WithArchive bool     // показывать задачи архивных проектов, когда ProjectID не задан
+	SeriesID    *int64   // только задачи этой серии повторений
+	SortBy      string   // одно из TaskSortFields
+	Desc        bool
+	Limit       int
+	After       *TaskCursor // вернуть задачи строго после этой позиции
+}
+
+// TaskCursor — позиция в отсортированном списке задач для keyset-пагинации.
+type TaskCursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d"`
+	Value  string `json:"v"`
+	ID     int    `json:"i"`
+}
+
+// TaskPage — одна страница списка задач.
+type TaskPage struct {
+	Tasks      []Task
+	NextCursor string
+}
+
+// ValidTaskSortField сообщает, разрешена ли сортировка по полю.
+func ValidTaskSortField(field string) bool {
+	for _, f := range TaskSortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidTaskSortKey проверяет, что key мог быть получен из SortKey для поля field.
+func ValidTaskSortKey(field, key string) bool {
+	switch field {
+	case "created_at", "updated_at", "due_at":
+		if field == "due_at" && key == "infinity" {
+			return true
+		}
+		_, err := time.Parse(sortTimeLayout, key)
+		return err == nil
+	default:
+		_, err := strconv.ParseUint(key, 10, 63)
+		return err == nil
+	}
+}
+
+// SortKey возвращает значение поля сортировки в виде строки, которую можно
+// сравнивать лексикографически. Задачи без срока идут после всех остальных.
+func (t Task) SortKey(field string) string {
+	switch field {
+	case "created_at":
+		return t.CreatedAt.Format(sortTimeLayout)
+	case "updated_at":
+		return t.UpdatedAt.Format(sortTimeLayout)
+	case "due_at":
+		if t.DueAt == nil {
+			return "infinity"
+		}
+		return t.DueAt.Format(sortTimeLayout)
+	case "priority":
+		return fmt.Sprintf("%d", priorityRank[t.Priority])
+	default:
+		return fmt.Sprintf("%020d", t.Id)
+	}
+}
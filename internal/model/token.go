@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// RefreshToken — непрозрачный refresh-токен. В хранилище лежит только SHA-256 от токена.
+// Токены одной цепочки ротации имеют общий FamilyID: при повторном использовании
+// уже заменённого токена отзывается вся цепочка.
+type RefreshToken struct {
+	Id              int64
+	UserId          int64
+	TokenHash       string
+	FamilyID        string
+	AccessJTI       string    // jti access-токена, выданного вместе с этим refresh-токеном
+	AccessExpiresAt time.Time // до этого момента jti нужно помнить после отзыва
+	ExpiresAt       time.Time
+	CreatedAt       time.Time
+	RevokedAt       *time.Time // заполняется при ротации, выходе или отзыве цепочки
+	// ClientID и Scopes — у токенов стороннего приложения (OAuth2); пусто — своя сессия пользователя
+	ClientID string
+	Scopes   []Scope
+}
+
+// TokenPair — ответ на вход и обновление токенов.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // срок жизни access-токена в секундах
+}
+
+// ImpersonationToken — короткоживущий access-токен, с которым администратор видит
+// сервис глазами пользователя. Refresh-токен к нему не выдаётся.
+type ImpersonationToken struct {
+	AccessToken string `json:"token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	UserID      int64  `json:"user_id"`
+	ActorID     int64  `json:"actor_id"`
+}
+
+// TokenPurpose — назначение одноразового токена пользователя.
+type TokenPurpose string
+
+const (
+	TokenPurposePasswordReset TokenPurpose = "password_reset"
+	TokenPurposeEmailVerify   TokenPurpose = "email_verify"
+	TokenPurposeEmailChange   TokenPurpose = "email_change"
+)
+
+// UserToken — одноразовый токен из письма (сброс пароля, подтверждение или смена email).
+// Как и у RefreshToken, хранится только SHA-256 от значения.
+type UserToken struct {
+	Id        int64
+	UserId    int64
+	Purpose   TokenPurpose
+	TokenHash string
+	NewEmail  string // для смены email — адрес, который станет действующим после подтверждения
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
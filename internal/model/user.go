@@ -2,13 +2,77 @@ package model
 
 import "time"
 
+// User — пользователь в хранилище. В ответах API отдаётся как UserView, без хеша пароля.
 type User struct {
 	Id           int       `gorm:"primary_key" json:"id"`
 	Username     string    `json:"user_name"`
 	Email        string    `json:"email"`
-	PasswordHash string    `json:"password_hash"`
+	PasswordHash string    `json:"-"`
 	Role         string    `gorm:"default:'user'" json:"role"`
 	CreateTime   time.Time `json:"create_time"`
+	// EmailVerifiedAt — когда пользователь подтвердил email; nil — ещё не подтвердил
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// LastLoginAt — последний успешный вход; FailedLoginCount — неудачные попытки подряд,
+	// после которых вход блокируется до LockedUntil
+	LastLoginAt      *time.Time `json:"last_login_at"`
+	FailedLoginCount int        `json:"failed_login_count"`
+	LockedUntil      *time.Time `json:"locked_until"`
+	// SuspendedAt — когда администратор приостановил аккаунт; nil — аккаунт активен
+	SuspendedAt *time.Time `json:"suspended_at"`
+}
+
+// UserView — пользователь в ответах API. Хеша пароля в нём нет, поэтому
+// его нельзя отдать клиенту по ошибке.
+type UserView struct {
+	Id               int        `json:"id"`
+	Username         string     `json:"user_name"`
+	Email            string     `json:"email"`
+	Role             string     `json:"role"`
+	CreateTime       time.Time  `json:"create_time"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	LastLoginAt      *time.Time `json:"last_login_at"`
+	FailedLoginCount int        `json:"failed_login_count"`
+	LockedUntil      *time.Time `json:"locked_until"`
+	SuspendedAt      *time.Time `json:"suspended_at"`
+}
+
+// View возвращает пользователя для ответа API.
+func (u User) View() UserView {
+	return UserView{
+		Id:               u.Id,
+		Username:         u.Username,
+		Email:            u.Email,
+		Role:             u.Role,
+		CreateTime:       u.CreateTime,
+		EmailVerifiedAt:  u.EmailVerifiedAt,
+		LastLoginAt:      u.LastLoginAt,
+		FailedLoginCount: u.FailedLoginCount,
+		LockedUntil:      u.LockedUntil,
+		SuspendedAt:      u.SuspendedAt,
+	}
+}
+
+// Suspended сообщает, приостановлен ли аккаунт.
+func (u User) Suspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// UserFilter — условия выборки пользователей для GET /admin/users.
+// Пользователи упорядочены по id; AfterID — вернуть пользователей с id строго больше.
+type UserFilter struct {
+	Search      string // подстрока имени или email без учёта регистра
+	Role        string
+	Suspended   *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Limit       int
+	AfterID     int
+}
+
+// UserPage — одна страница списка пользователей.
+type UserPage struct {
+	Users      []UserView
+	NextCursor string
 }
 
 type NewUser struct {
@@ -16,3 +80,19 @@ type NewUser struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
+
+// ProfileUpdate — изменение профиля текущим пользователем. Пустое поле — не менять.
+// Для смены email нужен текущий пароль; новый адрес начинает действовать после
+// перехода по ссылке из письма.
+type ProfileUpdate struct {
+	Username        *string `json:"username"`
+	Email           *string `json:"email"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+// ProfileUpdateResult — профиль после изменения. PendingEmail — новый адрес,
+// на который отправлено письмо для подтверждения.
+type ProfileUpdateResult struct {
+	User         *UserView `json:"user"`
+	PendingEmail string    `json:"pending_email,omitempty"`
+}
@@ -0,0 +1,84 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+)
+
+// jwk — открытый ключ в формате RFC 7517.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS разбирает ключи подписи RSA, EC (P-256, P-384, P-521) и Ed25519.
+// Ключи шифрования и ключи неизвестных типов пропускаются.
+func parseJWKS(raw []json.RawMessage) map[string]any {
+	keys := make(map[string]any, len(raw))
+	for _, data := range raw {
+		var k jwk
+		if err := json.Unmarshal(data, &k); err != nil || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		if key := k.publicKey(); key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys
+}
+
+// publicKey возвращает открытый ключ или nil, если ключ не разобрать.
+func (k jwk) publicKey() any {
+	switch k.Kty {
+	case "RSA":
+		n, e := decodeInt(k.N), decodeInt(k.E)
+		if n == nil || e == nil || !e.IsInt64() {
+			return nil
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil
+		}
+		x, y := decodeInt(k.X), decodeInt(k.Y)
+		if x == nil || y == nil || !curve.IsOnCurve(x, y) {
+			return nil
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if k.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil
+		}
+		return ed25519.PublicKey(x)
+	}
+	return nil
+}
+
+// decodeInt декодирует целое число из base64url без выравнивания.
+func decodeInt(s string) *big.Int {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+	return new(big.Int).SetBytes(b)
+}
@@ -0,0 +1,321 @@
+// Package oidc — вход через внешнего провайдера OpenID Connect по коду авторизации с PKCE.
+// Адреса провайдера берутся из документа discovery (/.well-known/openid-configuration),
+// а ID-токен проверяется открытыми ключами из его jwks_uri.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// httpTimeout — предел на каждый запрос к провайдеру.
+	httpTimeout = 10 * time.Second
+	// metadataTTL — сколько хранить документ discovery до повторного запроса.
+	metadataTTL = time.Hour
+	// jwksMinRefresh — не чаще этого перезапрашивать ключи при встрече неизвестного kid.
+	jwksMinRefresh = time.Minute
+	// clockSkew — допустимое расхождение часов с провайдером при проверке exp и iat.
+	clockSkew = time.Minute
+	// maxResponseSize — предел размера ответа провайдера.
+	maxResponseSize = 1 << 20
+)
+
+// ErrInvalidIDToken — ID-токен не прошёл проверку подписи, издателя, получателя, срока или nonce.
+var ErrInvalidIDToken = errors.New("ID-токен провайдера не прошёл проверку")
+
+// Config — настройки провайдера.
+type Config struct {
+	Name         string   // имя провайдера в адресах /auth/oidc/{name}/...
+	Issuer       string   // издатель; по нему находится документ discovery
+	ClientID     string   // идентификатор приложения у провайдера
+	ClientSecret string   // пусто — публичный клиент, защищённый только PKCE
+	RedirectURL  string   // адрес возврата, зарегистрированный у провайдера
+	Scopes       []string // пусто — openid, email, profile
+}
+
+// Claims — сведения о пользователе из проверенного ID-токена.
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+// Provider — провайдер OpenID Connect. Безопасен для одновременного использования.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu         sync.Mutex
+	meta       *metadata
+	metaAt     time.Time
+	keys       map[string]any // kid -> открытый ключ
+	keysAt     time.Time
+	keysLoaded bool
+}
+
+// metadata — нужные поля документа discovery.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// New создаёт провайдера. К провайдеру он обращается только при первом входе,
+// поэтому недоступность провайдера не мешает запуску сервера.
+func New(cfg Config) *Provider {
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{cfg: cfg, client: &http.Client{Timeout: httpTimeout}}
+}
+
+// Name возвращает имя провайдера.
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// SecureRedirect сообщает, что адрес возврата работает по HTTPS.
+func (p *Provider) SecureRedirect() bool {
+	return strings.HasPrefix(p.cfg.RedirectURL, "https://")
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера. state и nonce связывают ответ
+// провайдера с этим входом, а verifier — секрет PKCE, который понадобится в Exchange.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange обменивает код авторизации на токены провайдера и возвращает ID-токен без проверки.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("ошибка запроса токена: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var out struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.do(req, &out)
+	if err != nil {
+		return "", fmt.Errorf("ошибка запроса токена: %w", err)
+	}
+	if status != http.StatusOK || out.Error != "" {
+		return "", fmt.Errorf("провайдер отклонил код (HTTP %d): %s %s", status, out.Error, out.ErrorDescription)
+	}
+	if out.IDToken == "" {
+		return "", errors.New("провайдер не вернул id_token: проверьте, что запрошен scope openid")
+	}
+	return out.IDToken, nil
+}
+
+// VerifyIDToken проверяет подпись ID-токена ключами провайдера, издателя, получателя,
+// срок действия и nonce, выданный при начале входа.
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) { return p.key(ctx, t) },
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	// При нескольких получателях токен должен быть выдан именно этому приложению
+	if aud, _ := claims.GetAudience(); len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.cfg.ClientID {
+			return nil, fmt.Errorf("%w: azp %q не совпадает с client_id", ErrInvalidIDToken, azp)
+		}
+	}
+	got, _ := claims["nonce"].(string)
+	if subtle.ConstantTimeCompare([]byte(got), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce не совпадает", ErrInvalidIDToken)
+	}
+
+	out := &Claims{}
+	out.Subject, _ = claims["sub"].(string)
+	out.Email, _ = claims["email"].(string)
+	out.PreferredUsername, _ = claims["preferred_username"].(string)
+	out.Name, _ = claims["name"].(string)
+	// Некоторые провайдеры передают email_verified строкой
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		out.EmailVerified = v
+	case string:
+		out.EmailVerified = v == "true"
+	}
+	if out.Subject == "" {
+		return nil, fmt.Errorf("%w: нет claim sub", ErrInvalidIDToken)
+	}
+	return out, nil
+}
+
+// metadata возвращает документ discovery, запрашивая его при первом обращении и раз в metadataTTL.
+func (p *Provider) metadata(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta != nil && time.Since(p.metaAt) < metadataTTL {
+		return p.meta, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса discovery: %w", err)
+	}
+	var meta metadata
+	status, err := p.do(req, &meta)
+	if err != nil || status != http.StatusOK {
+		if p.meta != nil {
+			// Провайдер временно недоступен — продолжаем с прежним документом
+			return p.meta, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("HTTP %d", status)
+		}
+		return nil, fmt.Errorf("ошибка получения discovery провайдера %s: %w", p.cfg.Name, err)
+	}
+	// Документ должен принадлежать настроенному издателю (OpenID Connect Discovery, п. 4.3)
+	if strings.TrimSuffix(meta.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery провайдера %s: issuer %q не совпадает с настроенным %q", p.cfg.Name, meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery провайдера %s: нет authorization_endpoint, token_endpoint или jwks_uri", p.cfg.Name)
+	}
+	p.meta = &meta
+	p.metaAt = time.Now()
+	return p.meta, nil
+}
+
+// key выбирает открытый ключ проверки ID-токена по kid. Неизвестный kid означает, что
+// провайдер сменил ключи: набор перезапрашивается, но не чаще jwksMinRefresh.
+func (p *Provider) key(ctx context.Context, t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if p.keysLoaded && time.Since(p.keysAt) < jwksMinRefresh {
+		return nil, fmt.Errorf("неизвестный ключ подписи %q", kid)
+	}
+	keys, err := p.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.keys, p.keysAt, p.keysLoaded = keys, time.Now(), true
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("неизвестный ключ подписи %q", kid)
+}
+
+// lookupKey ищет ключ по kid; токен без kid подходит, только если ключ у провайдера один.
+// Вызывать под блокировкой.
+func (p *Provider) lookupKey(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// fetchKeys запрашивает открытые ключи провайдера. Вызывать под блокировкой после metadata.
+func (p *Provider) fetchKeys(ctx context.Context) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.meta.JWKSURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса ключей провайдера: %w", err)
+	}
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	status, err := p.do(req, &set)
+	if err != nil || status != http.StatusOK {
+		if err == nil {
+			err = fmt.Errorf("HTTP %d", status)
+		}
+		return nil, fmt.Errorf("ошибка получения ключей провайдера %s: %w", p.cfg.Name, err)
+	}
+	return parseJWKS(set.Keys), nil
+}
+
+// do выполняет запрос и разбирает JSON-ответ в out. Возвращает HTTP-статус.
+func (p *Provider) do(req *http.Request, out any) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, out); err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("некорректный JSON в ответе: %w", err)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,385 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.mood/internal/database"
+	"go.mood/internal/mailer"
+	"go.mood/internal/model"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrEmailNotVerified возвращается при входе, если в конфиге требуется подтверждённый email.
+	ErrEmailNotVerified = errors.New("email не подтверждён: перейдите по ссылке из письма")
+	// ErrInvalidProfile возвращается при некорректном имени пользователя или email.
+	ErrInvalidProfile = errors.New("некорректные данные профиля")
+	// ErrWrongPassword возвращается, если для изменения аккаунта передан неверный текущий пароль.
+	ErrWrongPassword = errors.New("неверный текущий пароль")
+	// ErrUsernameTaken и ErrEmailTaken возвращаются, если имя или email уже заняты другим пользователем.
+	ErrUsernameTaken = errors.New("имя пользователя уже занято")
+	ErrEmailTaken    = model.ErrEmailTaken
+	// ErrLastAdmin возвращается при попытке удалить единственного администратора.
+	ErrLastAdmin = errors.New("нельзя удалить единственного администратора: сначала назначьте другого")
+)
+
+const (
+	defaultPasswordResetTTL     = time.Hour
+	defaultEmailVerificationTTL = 48 * time.Hour
+
+	maxUsernameLength = 50
+	maxEmailLength    = 100
+)
+
+// AccountService — восстановление пароля и подтверждение email через одноразовые токены из писем.
+type AccountService struct {
+	db        *database.Database
+	mailer    mailer.Mailer
+	passwords *passwordChecker
+	hashes    *passwordHashes
+	baseURL   string
+	resetTTL  time.Duration
+	verifyTTL time.Duration
+}
+
+// NewAccountService создаёт новый экземпляр AccountService. Нулевые сроки жизни
+// заменяются значениями по умолчанию: час для сброса пароля и двое суток для подтверждения email.
+func NewAccountService(db *database.Database, m mailer.Mailer, passwords *passwordChecker, hashes *passwordHashes, baseURL string, resetTTL, verifyTTL time.Duration) *AccountService {
+	if resetTTL <= 0 {
+		resetTTL = defaultPasswordResetTTL
+	}
+	if verifyTTL <= 0 {
+		verifyTTL = defaultEmailVerificationTTL
+	}
+	return &AccountService{
+		db:        db,
+		mailer:    m,
+		passwords: passwords,
+		hashes:    hashes,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		resetTTL:  resetTTL,
+		verifyTTL: verifyTTL,
+	}
+}
+
+// RequestPasswordReset отправляет на email письмо с токеном сброса пароля.
+// Для незарегистрированного email ничего не делает и не сообщает об этом,
+// чтобы по ответу нельзя было узнать, есть ли такой пользователь.
+func (s *AccountService) RequestPasswordReset(email string) error {
+	user, err := s.db.Users.GetUserByEmail(strings.TrimSpace(email))
+	if err != nil {
+		return nil
+	}
+
+	token, err := s.issueToken(user, model.TokenPurposePasswordReset, s.resetTTL, "")
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nДля сброса пароля отправьте этот токен вместе с новым паролем на POST %s/password/reset:\n\n%s\n\n"+
+			"Токен действует %s. Если вы не запрашивали сброс пароля, просто проигнорируйте письмо.",
+			user.Username, s.baseURL, token, s.resetTTL),
+	})
+}
+
+// ResetPassword задаёт новый пароль по токену из письма, снимает блокировку входа
+// и завершает все сессии пользователя. Пароль, не подходящий по политике, — *ValidationError;
+// токен при этом не тратится, и с ним можно повторить попытку.
+func (s *AccountService) ResetPassword(token, newPassword string) error {
+	now := time.Now()
+	t, err := s.db.Tokens.GetUserToken(hashToken(token), model.TokenPurposePasswordReset, now)
+	if err != nil {
+		return err
+	}
+	user, err := s.db.Users.GetUserByID(t.UserId)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	reason, err := s.passwords.check(newPassword, user.Username, user.Email)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		return &ValidationError{Fields: map[string]string{"password": reason}}
+	}
+	if _, err := s.db.Tokens.ConsumeUserToken(hashToken(token), model.TokenPurposePasswordReset, now); err != nil {
+		return err
+	}
+
+	hash, err := s.hashes.hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Users.UpdatePassword(t.UserId, hash); err != nil {
+		return fmt.Errorf("ошибка при смене пароля: %w", err)
+	}
+	// Владелец email подтвердил, что это он, — блокировка после перебора пароля больше не нужна
+	if err := s.db.Users.UnlockUser(t.UserId); err != nil {
+		return fmt.Errorf("ошибка при разблокировке пользователя: %w", err)
+	}
+	// Старый пароль мог быть скомпрометирован — выходим на всех устройствах
+	if err := s.db.Tokens.RevokeRefreshTokensByUserID(t.UserId, now); err != nil {
+		return fmt.Errorf("ошибка при отзыве refresh-токенов: %w", err)
+	}
+	return nil
+}
+
+// SendEmailVerification отправляет пользователю письмо со ссылкой подтверждения email.
+// Если email уже подтверждён, письмо не отправляется.
+func (s *AccountService) SendEmailVerification(user model.User) error {
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	token, err := s.issueToken(user, model.TokenPurposeEmailVerify, s.verifyTTL, "")
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы подтвердить email, перейдите по ссылке:\n\n%s/email/verify?token=%s\n\nСсылка действует %s.",
+			user.Username, s.baseURL, url.QueryEscape(token), s.verifyTTL),
+	})
+}
+
+// ResendEmailVerification повторно отправляет письмо подтверждения на email.
+// Как и RequestPasswordReset, молча ничего не делает для неизвестного email.
+func (s *AccountService) ResendEmailVerification(email string) error {
+	user, err := s.db.Users.GetUserByEmail(strings.TrimSpace(email))
+	if err != nil {
+		return nil
+	}
+	return s.SendEmailVerification(user)
+}
+
+// VerifyEmail подтверждает email по токену из письма.
+func (s *AccountService) VerifyEmail(token string) error {
+	now := time.Now()
+	t, err := s.db.Tokens.ConsumeUserToken(hashToken(token), model.TokenPurposeEmailVerify, now)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Users.MarkEmailVerified(t.UserId, now); err != nil {
+		return fmt.Errorf("ошибка при подтверждении email: %w", err)
+	}
+	return nil
+}
+
+// GetProfile возвращает профиль текущего пользователя.
+func (s *AccountService) GetProfile(userID int64) (*model.UserView, error) {
+	user, err := s.db.Users.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении профиля: %w", err)
+	}
+	view := user.View()
+	return &view, nil
+}
+
+// UpdateProfile меняет имя пользователя и (с текущим паролем) email. Новый email
+// не применяется сразу: на него отправляется ссылка, а на старый — уведомление.
+// Данные проверяются целиком до изменений, чтобы ошибка не оставила профиль изменённым наполовину.
+func (s *AccountService) UpdateProfile(userID int64, input *model.ProfileUpdate) (*model.ProfileUpdateResult, error) {
+	user, err := s.db.Users.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении профиля: %w", err)
+	}
+
+	username := user.Username
+	if input.Username != nil {
+		username = strings.TrimSpace(*input.Username)
+		if problem := usernameProblem(username); problem != "" && username != user.Username {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidProfile, problem)
+		}
+		if other, err := s.db.Users.GetUserByUsername(username); err == nil && other.Id != user.Id {
+			return nil, ErrUsernameTaken
+		}
+	}
+	email := user.Email
+	if input.Email != nil {
+		email = strings.TrimSpace(*input.Email)
+		if email != user.Email {
+			if err := s.checkNewEmail(user, email, input.CurrentPassword); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if username != user.Username {
+		if err := s.db.Users.UpdateUsername(userID, username); err != nil {
+			return nil, fmt.Errorf("ошибка при изменении имени: %w", err)
+		}
+		user.Username = username
+	}
+	view := user.View()
+	result := &model.ProfileUpdateResult{User: &view}
+	if email != user.Email {
+		if err := s.sendEmailChange(user, email); err != nil {
+			return nil, err
+		}
+		result.PendingEmail = email
+	}
+	return result, nil
+}
+
+// ConfirmEmailChange применяет новый email по токену из письма; email сразу считается подтверждённым.
+func (s *AccountService) ConfirmEmailChange(token string) error {
+	now := time.Now()
+	t, err := s.db.Tokens.ConsumeUserToken(hashToken(token), model.TokenPurposeEmailChange, now)
+	if err != nil {
+		return err
+	}
+	// Адрес мог занять кто-то другой, пока письмо шло
+	if other, err := s.db.Users.GetUserByEmail(t.NewEmail); err == nil && int64(other.Id) != t.UserId {
+		return ErrEmailTaken
+	}
+	if err := s.db.Users.UpdateEmail(t.UserId, t.NewEmail, &now); err != nil {
+		return fmt.Errorf("ошибка при смене email: %w", err)
+	}
+	return nil
+}
+
+// ChangePassword меняет пароль по текущему и завершает все остальные сессии пользователя;
+// сессия с access-токеном jti остаётся. Новый пароль, не подходящий по политике, — *ValidationError.
+func (s *AccountService) ChangePassword(userID int64, jti, currentPassword, newPassword string) error {
+	user, err := s.db.Users.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении профиля: %w", err)
+	}
+	if err := s.checkPassword(user, currentPassword); err != nil {
+		return err
+	}
+	reason, err := s.passwords.check(newPassword, user.Username, user.Email)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		return &ValidationError{Fields: map[string]string{"new_password": reason}}
+	}
+
+	hash, err := s.hashes.hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Users.UpdatePassword(userID, hash); err != nil {
+		return fmt.Errorf("ошибка при смене пароля: %w", err)
+	}
+
+	now := time.Now()
+	familyID, err := s.db.Tokens.GetRefreshTokenFamilyByAccessJTI(jti)
+	if errors.Is(err, model.ErrUnknownRefreshToken) {
+		// Токен выдан без refresh-токена — сохранять нечего, отзываем всё
+		err = s.db.Tokens.RevokeRefreshTokensByUserID(userID, now)
+	} else if err == nil {
+		err = s.db.Tokens.RevokeOtherRefreshTokens(userID, familyID, now)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при отзыве других сессий: %w", err)
+	}
+	return nil
+}
+
+// DeleteAccount удаляет аккаунт вместе со всеми данными пользователя (каскадом в хранилище)
+// после проверки пароля. Единственного администратора удалить нельзя.
+func (s *AccountService) DeleteAccount(userID int64, password string) error {
+	user, err := s.db.Users.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении профиля: %w", err)
+	}
+	if err := s.checkPassword(user, password); err != nil {
+		return err
+	}
+	admin, err := isAdministrator(s.db, user)
+	if err != nil {
+		return err
+	}
+	if admin {
+		admins, err := countAdministrators(s.db)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return ErrLastAdmin
+		}
+	}
+
+	// Выданные access-токены отзываются до удаления, пока известны их jti
+	if err := s.db.Tokens.RevokeRefreshTokensByUserID(userID, time.Now()); err != nil {
+		return fmt.Errorf("ошибка при отзыве токенов: %w", err)
+	}
+	if err := s.db.Users.DeleteUserByID(userID); err != nil {
+		return fmt.Errorf("ошибка при удалении аккаунта: %w", err)
+	}
+	return nil
+}
+
+// checkNewEmail проверяет пароль и новый адрес перед сменой email.
+func (s *AccountService) checkNewEmail(user model.User, email, password string) error {
+	if err := s.checkPassword(user, password); err != nil {
+		return err
+	}
+	if problem := emailProblem(email); problem != "" {
+		return fmt.Errorf("%w: %s", ErrInvalidProfile, problem)
+	}
+	if _, err := s.db.Users.GetUserByEmail(email); err == nil {
+		return ErrEmailTaken
+	}
+	return nil
+}
+
+// sendEmailChange отправляет на новый адрес ссылку для подтверждения смены email.
+func (s *AccountService) sendEmailChange(user model.User, email string) error {
+	token, err := s.issueToken(user, model.TokenPurposeEmailChange, s.verifyTTL, email)
+	if err != nil {
+		return err
+	}
+	if err := s.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Смена email",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы сделать этот адрес email вашего аккаунта, перейдите по ссылке:\n\n%s/email/change/confirm?token=%s\n\nСсылка действует %s.",
+			user.Username, s.baseURL, url.QueryEscape(token), s.verifyTTL),
+	}); err != nil {
+		return err
+	}
+	// Уведомление на старый адрес — на случай, если смену запросил не владелец аккаунта
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Запрошена смена email",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nДля вашего аккаунта запрошена смена email на %s. Адрес изменится после подтверждения по ссылке из письма на новый адрес.\n\n"+
+			"Если это были не вы, смените пароль.", user.Username, email),
+	})
+}
+
+// checkPassword сравнивает пароль с хешем пользователя.
+func (s *AccountService) checkPassword(user model.User, password string) error {
+	if ok, _ := s.hashes.verify(password, user.PasswordHash); password == "" || !ok {
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// issueToken сохраняет новый одноразовый токен (старые токены того же назначения
+// перестают действовать) и возвращает его значение для письма. newEmail — только для смены email.
+func (s *AccountService) issueToken(user model.User, purpose model.TokenPurpose, ttl time.Duration, newEmail string) (string, error) {
+	raw, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	t := &model.UserToken{
+		UserId:    int64(user.Id),
+		Purpose:   purpose,
+		TokenHash: hashToken(raw),
+		NewEmail:  newEmail,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	if err := s.db.Tokens.CreateUserToken(t); err != nil {
+		return "", fmt.Errorf("ошибка при сохранении токена: %w", err)
+	}
+	return raw, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.mood/internal/database"
+	"go.mood/internal/model"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrInvalidAPIKey возвращается при пустом или слишком длинном имени ключа,
+	// неизвестном scope или сроке действия в прошлом.
+	ErrInvalidAPIKey = errors.New("некорректные параметры API-ключа")
+	// ErrAPIKeyUnauthorized возвращается, если ключ не найден, отозван или истёк.
+	ErrAPIKeyUnauthorized = errors.New("неверный, отозванный или просроченный API-ключ")
+)
+
+const (
+	maxAPIKeyNameLength = 100
+	// apiKeyVisibleLength — сколько символов ключа (вместе с "pat_") хранится открыто.
+	apiKeyVisibleLength = len(model.APIKeyPrefix) + 8
+)
+
+// APIKeyService — персональные API-ключи для скриптов и интеграций.
+type APIKeyService struct {
+	db *database.Database
+}
+
+// NewAPIKeyService создаёт новый экземпляр APIKeyService.
+func NewAPIKeyService(db *database.Database) *APIKeyService {
+	return &APIKeyService{
+		db: db,
+	}
+}
+
+// CreateAPIKey создаёт ключ и возвращает его значение — единственный раз.
+func (s *APIKeyService) CreateAPIKey(userID int64, input *model.NewAPIKey) (*model.CreatedAPIKey, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPIKeyNameLength {
+		return nil, fmt.Errorf("%w: имя обязательно (до %d символов)", ErrInvalidAPIKey, maxAPIKeyNameLength)
+	}
+	for _, scope := range input.Scopes {
+		if !scope.Valid() {
+			return nil, fmt.Errorf("%w: неизвестный scope %q", ErrInvalidAPIKey, scope)
+		}
+	}
+	now := time.Now()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expires_at должен быть в будущем", ErrInvalidAPIKey)
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	token := model.APIKeyPrefix + secret
+	key := model.APIKey{
+		UserId:    userID,
+		Name:      name,
+		Prefix:    token[:apiKeyVisibleLength],
+		TokenHash: hashToken(token),
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+		CreatedAt: now,
+	}
+	if key.Scopes == nil {
+		key.Scopes = []model.Scope{}
+	}
+	if err := s.db.APIKeys.CreateAPIKey(&key); err != nil {
+		return nil, fmt.Errorf("ошибка при создании API-ключа: %w", err)
+	}
+	return &model.CreatedAPIKey{APIKey: key, Token: token}, nil
+}
+
+// GetAPIKeys возвращает действующие и истёкшие, но не отозванные ключи пользователя.
+func (s *APIKeyService) GetAPIKeys(userID int64) ([]model.APIKey, error) {
+	keys, err := s.db.APIKeys.GetAPIKeysByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении API-ключей: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ пользователя.
+func (s *APIKeyService) RevokeAPIKey(id, userID int64) error {
+	return s.db.APIKeys.RevokeAPIKeyWithOwner(id, userID, time.Now())
+}
+
+// AuthenticateAPIKey проверяет ключ из заголовка Authorization и возвращает его
+// вместе с владельцем. Время последнего использования обновляется попутно, но, как и у сессий,
+// не чаще sessionTouchInterval, чтобы не писать в хранилище на каждый запрос.
+func (s *APIKeyService) AuthenticateAPIKey(token string) (model.APIKey, model.User, error) {
+	now := time.Now()
+	key, err := s.db.APIKeys.GetAPIKeyByHash(hashToken(token))
+	if err != nil {
+		if errors.Is(err, model.ErrUnknownAPIKey) {
+			return key, model.User{}, ErrAPIKeyUnauthorized
+		}
+		return key, model.User{}, fmt.Errorf("ошибка при получении API-ключа: %w", err)
+	}
+	if !key.Active(now) {
+		return key, model.User{}, ErrAPIKeyUnauthorized
+	}
+	user, err := s.db.Users.GetUserByID(key.UserId)
+	if err != nil {
+		return key, user, ErrAPIKeyUnauthorized
+	}
+	if user.Suspended() {
+		return key, user, ErrAccountSuspended
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= sessionTouchInterval {
+		if err := s.db.APIKeys.TouchAPIKey(key.Id, now); err != nil {
+			return key, user, fmt.Errorf("ошибка при обновлении API-ключа: %w", err)
+		}
+	}
+	return key, user, nil
+}
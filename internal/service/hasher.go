@@ -0,0 +1,244 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultArgon2Memory      = 64 * 1024 // КиБ
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 2
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// errMalformedHash возвращается при разборе повреждённого хеша пароля.
+var errMalformedHash = errors.New("некорректный формат хеша пароля")
+
+// PasswordHasher — алгоритм хеширования паролей. Хеш хранится строкой, в которой
+// записаны алгоритм и параметры, поэтому его можно проверить и после смены настроек.
+type PasswordHasher interface {
+	// Hash возвращает хеш пароля с новой случайной солью.
+	Hash(password string) (string, error)
+	// Recognizes сообщает, получен ли хеш этим алгоритмом.
+	Recognizes(encoded string) bool
+	// Verify сравнивает пароль с хешем этого алгоритма; параметры берутся из самого хеша.
+	Verify(password, encoded string) (bool, error)
+	// Outdated сообщает, что хеш этого алгоритма получен с параметрами, отличными от текущих.
+	Outdated(encoded string) bool
+	// MaxPasswordBytes возвращает, сколько первых байт пароля учитывает алгоритм; 0 — все.
+	MaxPasswordBytes() int
+}
+
+// BcryptHasher хеширует пароли bcrypt. Хеш — в формате $2a$<стоимость>$<соль и хеш>.
+type BcryptHasher struct {
+	Cost int // от 4 до 31; 0 — 10
+}
+
+func (h *BcryptHasher) cost() int {
+	if h.Cost < bcrypt.MinCost {
+		return bcrypt.DefaultCost
+	}
+	return h.Cost
+}
+
+// Hash возвращает bcrypt-хеш пароля.
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost())
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Recognizes сообщает, что хеш получен bcrypt.
+func (h *BcryptHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// Verify сравнивает пароль с bcrypt-хешем.
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Outdated сообщает, что стоимость хеша отличается от настроенной.
+func (h *BcryptHasher) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost()
+}
+
+// MaxPasswordBytes возвращает 72: остальные байты пароля bcrypt отбрасывает.
+func (h *BcryptHasher) MaxPasswordBytes() int {
+	return 72
+}
+
+// Argon2idHasher хеширует пароли Argon2id. Хеш — в формате PHC:
+// $argon2id$v=19$m=<память>,t=<итерации>,p=<потоки>$<соль>$<хеш>, соль и хеш в base64 без выравнивания.
+type Argon2idHasher struct {
+	Memory      uint32 // память в КиБ; 0 — 64 МиБ
+	Iterations  uint32 // 0 — 3
+	Parallelism uint8  // 0 — 2
+}
+
+// argon2Params — параметры Argon2id, записанные в хеше.
+type argon2Params struct {
+	version     int
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func (h *Argon2idHasher) params() argon2Params {
+	p := argon2Params{
+		version:     argon2.Version,
+		memory:      h.Memory,
+		iterations:  h.Iterations,
+		parallelism: h.Parallelism,
+	}
+	if p.memory == 0 {
+		p.memory = defaultArgon2Memory
+	}
+	if p.iterations == 0 {
+		p.iterations = defaultArgon2Iterations
+	}
+	if p.parallelism == 0 {
+		p.parallelism = defaultArgon2Parallelism
+	}
+	return p
+}
+
+// Hash возвращает хеш Argon2id в формате PHC.
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("ошибка генерации соли: %w", err)
+	}
+	p := h.params()
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", p.version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Recognizes сообщает, что хеш получен Argon2id.
+func (h *Argon2idHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// Verify сравнивает пароль с хешем Argon2id за постоянное время.
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	p, salt, key, err := parseArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	if p.version != argon2.Version {
+		return false, fmt.Errorf("%w: неподдерживаемая версия Argon2 %d", errMalformedHash, p.version)
+	}
+	other := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// Outdated сообщает, что версия, параметры или длина хеша отличаются от текущих.
+func (h *Argon2idHasher) Outdated(encoded string) bool {
+	p, _, key, err := parseArgon2id(encoded)
+	return err != nil || p != h.params() || len(key) != argon2KeyLength
+}
+
+// MaxPasswordBytes возвращает 0: Argon2id учитывает пароль целиком.
+func (h *Argon2idHasher) MaxPasswordBytes() int {
+	return 0
+}
+
+// parseArgon2id разбирает хеш Argon2id в формате PHC.
+func parseArgon2id(encoded string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, errMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &p.version); err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+	if p.memory == 0 || p.iterations == 0 || p.parallelism == 0 {
+		return p, nil, nil, errMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errMalformedHash
+	}
+	return p, salt, key, nil
+}
+
+// passwordHashes хеширует новые пароли текущим алгоритмом и проверяет пароли по хешам,
+// сохранённым любым из поддерживаемых алгоритмов.
+type passwordHashes struct {
+	current PasswordHasher
+	known   []PasswordHasher
+
+	dummyOnce sync.Once
+	dummy     string
+}
+
+// newPasswordHashes создаёт новый экземпляр passwordHashes. nil — Argon2id с параметрами по умолчанию.
+func newPasswordHashes(current PasswordHasher) *passwordHashes {
+	if current == nil {
+		current = &Argon2idHasher{}
+	}
+	return &passwordHashes{
+		current: current,
+		known:   []PasswordHasher{current, &Argon2idHasher{}, &BcryptHasher{}},
+	}
+}
+
+// hash хеширует пароль текущим алгоритмом.
+func (h *passwordHashes) hash(password string) (string, error) {
+	hash, err := h.current.Hash(password)
+	if err != nil {
+		return "", fmt.Errorf("ошибка хеширования пароля: %w", err)
+	}
+	return hash, nil
+}
+
+// verify сообщает, подходит ли пароль к хешу, и нужно ли пересчитать хеш текущим
+// алгоритмом. Повреждённый или пустой хеш не подходит ни к какому паролю.
+func (h *passwordHashes) verify(password, encoded string) (ok, rehash bool) {
+	for _, hasher := range h.known {
+		if !hasher.Recognizes(encoded) {
+			continue
+		}
+		if ok, err := hasher.Verify(password, encoded); err != nil || !ok {
+			return false, false
+		}
+		return true, !h.current.Recognizes(encoded) || h.current.Outdated(encoded)
+	}
+	return false, false
+}
+
+// verifyDummy проверяет пароль по заранее посчитанному хешу, чтобы вход с неизвестным
+// именем занимал столько же времени, сколько с неверным паролем, и по времени ответа
+// нельзя было узнать, существует ли пользователь.
+func (h *passwordHashes) verifyDummy(password string) {
+	h.dummyOnce.Do(func() {
+		h.dummy, _ = h.hash("dummy-password")
+	})
+	h.verify(password, h.dummy)
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"fmt"
+	"go.mood/internal/database"
+	"go.mood/internal/model"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLoginThreshold   = 5
+	defaultIPLoginThreshold = 20
+	defaultLoginLockout     = time.Minute
+	defaultLoginMaxLockout  = time.Hour
+
+	// ipPruneInterval — как часто удалять из памяти устаревшие записи о попытках с IP.
+	ipPruneInterval = time.Minute
+)
+
+// LockedError возвращается, когда вход временно заблокирован из-за неудачных попыток.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("слишком много неудачных попыток входа, повторите через %s", e.RetryAfter.Round(time.Second))
+}
+
+// LockoutPolicy — настройки защиты входа от перебора. Нулевые значения заменяются значениями по умолчанию.
+type LockoutPolicy struct {
+	Threshold   int           // неудачных попыток подряд до блокировки аккаунта; 0 — 5
+	IPThreshold int           // неудачных попыток с одного IP до его блокировки; 0 — 20
+	Lockout     time.Duration // первая блокировка; 0 — минута, каждая следующая вдвое дольше
+	MaxLockout  time.Duration // предел блокировки; 0 — час
+}
+
+// ipAttempts — неудачные попытки входа с одного IP.
+type ipAttempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// loginGuard считает неудачные попытки входа по пользователю (в БД) и по IP (в памяти)
+// и после порога блокирует вход с экспоненциально растущим сроком.
+type loginGuard struct {
+	db     *database.Database
+	policy LockoutPolicy
+
+	mu        sync.Mutex
+	ips       map[string]*ipAttempts
+	lastPrune time.Time
+}
+
+// newLoginGuard создаёт новый экземпляр loginGuard.
+func newLoginGuard(db *database.Database, policy LockoutPolicy) *loginGuard {
+	if policy.Threshold <= 0 {
+		policy.Threshold = defaultLoginThreshold
+	}
+	if policy.IPThreshold <= 0 {
+		policy.IPThreshold = defaultIPLoginThreshold
+	}
+	if policy.Lockout <= 0 {
+		policy.Lockout = defaultLoginLockout
+	}
+	if policy.MaxLockout <= 0 {
+		policy.MaxLockout = defaultLoginMaxLockout
+	}
+	return &loginGuard{
+		db:     db,
+		policy: policy,
+		ips:    make(map[string]*ipAttempts),
+	}
+}
+
+// checkIP возвращает *LockedError, если вход с ip временно заблокирован.
+func (g *loginGuard) checkIP(ip string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if a, ok := g.ips[ip]; ok {
+		if wait := time.Until(a.lockedUntil); wait > 0 {
+			return &LockedError{RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// checkUser возвращает *LockedError, если вход пользователю временно заблокирован.
+func (g *loginGuard) checkUser(user model.User) error {
+	if user.LockedUntil != nil {
+		if wait := time.Until(*user.LockedUntil); wait > 0 {
+			return &LockedError{RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// failIP учитывает неудачную попытку с ip. Счётчик IP не сбрасывается успешным входом
+// (иначе его обнулял бы вход в собственный аккаунт), а забывается после MaxLockout без ошибок.
+func (g *loginGuard) failIP(ip string) {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.prune(now)
+	a, ok := g.ips[ip]
+	if !ok || now.Sub(a.lastFailure) > g.policy.MaxLockout {
+		a = &ipAttempts{}
+		g.ips[ip] = a
+	}
+	a.failures++
+	a.lastFailure = now
+	if a.failures >= g.policy.IPThreshold {
+		a.lockedUntil = now.Add(g.lockout(a.failures - g.policy.IPThreshold))
+	}
+}
+
+// failUser учитывает неудачную попытку входа пользователя и после порога блокирует аккаунт.
+// Если попытка привела к блокировке, возвращает *LockedError.
+func (g *loginGuard) failUser(userID int64) error {
+	failures, err := g.db.Users.IncrementFailedLogins(userID)
+	if err != nil {
+		return err
+	}
+	if failures < g.policy.Threshold {
+		return nil
+	}
+	lockout := g.lockout(failures - g.policy.Threshold)
+	if err := g.db.Users.LockUser(userID, time.Now().Add(lockout)); err != nil {
+		return err
+	}
+	return &LockedError{RetryAfter: lockout}
+}
+
+// succeed запоминает успешный вход пользователя и сбрасывает его счётчик неудачных попыток.
+func (g *loginGuard) succeed(userID int64) error {
+	if err := g.db.Users.RecordLogin(userID, time.Now()); err != nil {
+		return fmt.Errorf("ошибка при сохранении времени входа: %w", err)
+	}
+	return nil
+}
+
+// lockout возвращает срок блокировки после n неудачных попыток сверх порога: Lockout·2ⁿ, не больше MaxLockout.
+func (g *loginGuard) lockout(n int) time.Duration {
+	d := g.policy.Lockout
+	for i := 0; i < n && d < g.policy.MaxLockout; i++ {
+		d *= 2
+	}
+	return min(d, g.policy.MaxLockout)
+}
+
+// prune удаляет записи об IP, которые уже не заблокированы и давно не ошибались.
+// Вызывать под блокировкой.
+func (g *loginGuard) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < ipPruneInterval {
+		return
+	}
+	g.lastPrune = now
+	for ip, a := range g.ips {
+		if now.After(a.lockedUntil) && now.Sub(a.lastFailure) > g.policy.MaxLockout {
+			delete(g.ips, ip)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"go.mood/internal/database"
+	"go.mood/internal/model"
+	"go.mood/internal/totp"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMFAAlreadyEnabled возвращается при повторном подключении уже подтверждённой 2FA.
+	ErrMFAAlreadyEnabled = errors.New("двухфакторная аутентификация уже подключена")
+	// ErrInvalidMFACode возвращается для неверного, просроченного или уже использованного кода.
+	ErrInvalidMFACode = errors.New("неверный код подтверждения")
+)
+
+const (
+	defaultMFAIssuer = "go.mood"
+
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10 // символов без дефиса
+)
+
+// MFAService — двухфакторная аутентификация по TOTP с кодами восстановления.
+type MFAService struct {
+	db     *database.Database
+	tokens *TokenService
+	guard  *loginGuard
+	issuer string
+}
+
+// NewMFAService создаёт новый экземпляр MFAService. issuer показывается
+// в приложении-аутентификаторе; пустой — "go.mood".
+func NewMFAService(db *database.Database, tokens *TokenService, guard *loginGuard, issuer string) *MFAService {
+	if issuer == "" {
+		issuer = defaultMFAIssuer
+	}
+	return &MFAService{
+		db:     db,
+		tokens: tokens,
+		guard:  guard,
+		issuer: issuer,
+	}
+}
+
+// GetMFAStatus возвращает, подключена ли 2FA и сколько осталось кодов восстановления.
+func (s *MFAService) GetMFAStatus(userID int64) (*model.MFAStatus, error) {
+	m, err := s.db.MFA.GetMFA(userID)
+	if errors.Is(err, model.ErrMFANotEnrolled) {
+		return &model.MFAStatus{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении настроек 2FA: %w", err)
+	}
+	codes, err := s.db.MFA.GetRecoveryCodes(userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении кодов восстановления: %w", err)
+	}
+	return &model.MFAStatus{Enabled: m.Enabled(), RecoveryCodesLeft: len(codes)}, nil
+}
+
+// EnrollMFA выдаёт новый секрет TOTP. 2FA начнёт действовать после ConfirmMFA;
+// повторный вызов до подтверждения заменяет секрет.
+func (s *MFAService) EnrollMFA(userID int64) (*model.MFAEnrollment, error) {
+	if m, err := s.db.MFA.GetMFA(userID); err == nil && m.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	user, err := s.db.Users.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.MFA.SaveMFASecret(userID, secret); err != nil {
+		return nil, fmt.Errorf("ошибка при сохранении секрета 2FA: %w", err)
+	}
+	return &model.MFAEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmMFA включает 2FA по первому коду из приложения. Все прежние сессии
+// пользователя завершаются; возвращаются коды восстановления и токены новой сессии на device.
+func (s *MFAService) ConfirmMFA(userID int64, code string, device model.DeviceInfo) (*model.MFAConfirmation, error) {
+	m, err := s.db.MFA.GetMFA(userID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	now := time.Now()
+	counter, ok := totp.Validate(m.Secret, normalizeCode(code), now)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.MFA.ConfirmMFA(userID, counter, hashes, now); err != nil {
+		return nil, fmt.Errorf("ошибка при подтверждении 2FA: %w", err)
+	}
+	// Сессии, начатые без второго фактора, больше не должны действовать
+	if err := s.db.Tokens.RevokeRefreshTokensByUserID(userID, now); err != nil {
+		return nil, fmt.Errorf("ошибка при отзыве refresh-токенов: %w", err)
+	}
+
+	user, err := s.db.Users.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	tokens, err := s.tokens.IssueTokens(user, true, device)
+	if err != nil {
+		return nil, err
+	}
+	return &model.MFAConfirmation{RecoveryCodes: codes, Tokens: tokens}, nil
+}
+
+// DisableMFA отключает 2FA; нужен действующий код TOTP или код восстановления.
+func (s *MFAService) DisableMFA(userID int64, code string) error {
+	if err := s.checkCode(userID, code); err != nil {
+		return err
+	}
+	if err := s.db.MFA.DeleteMFA(userID); err != nil {
+		return fmt.Errorf("ошибка при отключении 2FA: %w", err)
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления новыми; нужен действующий код.
+func (s *MFAService) RegenerateRecoveryCodes(userID int64, code string) ([]string, error) {
+	if err := s.checkCode(userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.MFA.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, fmt.Errorf("ошибка при сохранении кодов восстановления: %w", err)
+	}
+	return codes, nil
+}
+
+// VerifyLogin завершает вход с 2FA: проверяет mfa_token из LoginUser и код
+// (TOTP или код восстановления) и выдаёт токены доступа новой сессии на device.
+func (s *MFAService) VerifyLogin(mfaToken, code string, device model.DeviceInfo) (*model.TokenPair, error) {
+	userID, err := s.tokens.ParseMFAChallenge(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.db.Users.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	// Неверные коды считаются вместе с неверными паролями, чтобы их нельзя было перебирать
+	if err := s.guard.checkUser(user); err != nil {
+		return nil, err
+	}
+	if err := s.checkCode(userID, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.guard.failUser(userID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+	if err := s.guard.succeed(userID); err != nil {
+		return nil, err
+	}
+	return s.tokens.IssueTokens(user, true, device)
+}
+
+// checkCode проверяет код TOTP (один и тот же код дважды не принимается)
+// или код восстановления, который после этого становится использованным.
+func (s *MFAService) checkCode(userID int64, code string) error {
+	m, err := s.db.MFA.GetMFA(userID)
+	if err != nil {
+		return err
+	}
+	if !m.Enabled() {
+		return model.ErrMFANotEnrolled
+	}
+	code = normalizeCode(code)
+	now := time.Now()
+
+	if counter, ok := totp.Validate(m.Secret, code, now); ok {
+		fresh, err := s.db.MFA.UseTOTPCounter(userID, counter)
+		if err != nil {
+			return fmt.Errorf("ошибка при проверке кода: %w", err)
+		}
+		if !fresh {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	if len(code) != recoveryCodeLength {
+		return ErrInvalidMFACode
+	}
+	codes, err := s.db.MFA.GetRecoveryCodes(userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении кодов восстановления: %w", err)
+	}
+	for _, c := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(c.CodeHash), []byte(code)) != nil {
+			continue
+		}
+		used, err := s.db.MFA.UseRecoveryCode(c.Id, now)
+		if err != nil {
+			return fmt.Errorf("ошибка при использовании кода восстановления: %w", err)
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+	return ErrInvalidMFACode
+}
+
+// normalizeCode убирает пробелы и дефисы и приводит код к нижнему регистру,
+// чтобы коды восстановления можно было вводить в любом виде.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+// generateRecoveryCodes возвращает коды восстановления вида "abcde-fghij" и их bcrypt-хеши.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := randomBytes(recoveryCodeLength)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(raw))[:recoveryCodeLength]
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка хеширования кода восстановления: %w", err)
+		}
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, string(hash))
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,504 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go.mood/internal/database"
+	"go.mood/internal/model"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Ошибки протокола OAuth2. Обработчик передаёт их клиенту в поле error по RFC 6749, п. 5.2.
+var (
+	// ErrInvalidOAuthClient — приложение не найдено или секрет неверен (invalid_client).
+	ErrInvalidOAuthClient = errors.New("приложение не найдено или секрет неверен")
+	// ErrInvalidOAuthRequest — в запросе не хватает параметров или они некорректны (invalid_request).
+	ErrInvalidOAuthRequest = errors.New("некорректный запрос OAuth2")
+	// ErrInvalidGrant — код авторизации или refresh-токен недействителен (invalid_grant).
+	ErrInvalidGrant = errors.New("код авторизации или refresh-токен недействителен")
+	// ErrInvalidScope — запрошено право, которое приложению недоступно (invalid_scope).
+	ErrInvalidScope = errors.New("запрошено недоступное право")
+	// ErrUnsupportedGrantType — неизвестный grant_type (unsupported_grant_type).
+	ErrUnsupportedGrantType = errors.New("поддерживаются grant_type authorization_code и refresh_token")
+
+	// ErrInvalidOAuthClientInput возвращается при регистрации приложения с некорректными параметрами.
+	ErrInvalidOAuthClientInput = errors.New("некорректные параметры приложения")
+)
+
+const (
+	// oauthCodeTTL — сколько действует код авторизации до обмена на токены.
+	oauthCodeTTL = 5 * time.Minute
+
+	maxOAuthClientNameLength = 100
+	maxRedirectURIs          = 10
+)
+
+// OAuthService — сервер авторизации OAuth2 для сторонних приложений: регистрация
+// приложений, код авторизации с PKCE, токены с ограниченными правами, интроспекция и отзыв.
+// Приложения получают обычные access- и refresh-токены сервиса с claims client_id и scope,
+// пароль пользователя они не видят.
+type OAuthService struct {
+	db      *database.Database
+	tokens  *TokenService
+	baseURL string
+}
+
+// NewOAuthService создаёт новый экземпляр OAuthService. baseURL — адрес API,
+// который публикуется в метаданных сервера авторизации как issuer.
+func NewOAuthService(db *database.Database, tokens *TokenService, baseURL string) *OAuthService {
+	return &OAuthService{
+		db:      db,
+		tokens:  tokens,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// CreateClient регистрирует приложение. Секрет выдаётся только конфиденциальным
+// приложениям и возвращается один раз.
+func (s *OAuthService) CreateClient(createdBy int64, input *model.NewOAuthClient) (*model.CreatedOAuthClient, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxOAuthClientNameLength {
+		return nil, fmt.Errorf("%w: имя обязательно (до %d символов)", ErrInvalidOAuthClientInput, maxOAuthClientNameLength)
+	}
+	if len(input.RedirectURIs) == 0 || len(input.RedirectURIs) > maxRedirectURIs {
+		return nil, fmt.Errorf("%w: нужно от 1 до %d redirect_uris", ErrInvalidOAuthClientInput, maxRedirectURIs)
+	}
+	for _, uri := range input.RedirectURIs {
+		if err := checkRedirectURI(uri); err != nil {
+			return nil, err
+		}
+	}
+	if len(input.Scopes) == 0 {
+		return nil, fmt.Errorf("%w: нужно указать scopes", ErrInvalidOAuthClientInput)
+	}
+	for _, scope := range input.Scopes {
+		if !scope.Valid() {
+			return nil, fmt.Errorf("%w: неизвестный scope %q", ErrInvalidOAuthClientInput, scope)
+		}
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	client := model.OAuthClient{
+		Id:           id,
+		Name:         name,
+		RedirectURIs: input.RedirectURIs,
+		Scopes:       input.Scopes,
+		CreatedBy:    &createdBy,
+		CreatedAt:    time.Now(),
+	}
+	var secret string
+	if input.Confidential {
+		if secret, err = randomToken(32); err != nil {
+			return nil, err
+		}
+		client.SecretHash = hashToken(secret)
+	}
+	if err := s.db.OAuth.CreateClient(&client); err != nil {
+		return nil, fmt.Errorf("ошибка при регистрации приложения: %w", err)
+	}
+	return &model.CreatedOAuthClient{OAuthClient: client, Secret: secret}, nil
+}
+
+// GetClients возвращает все зарегистрированные приложения.
+func (s *OAuthService) GetClients() ([]model.OAuthClient, error) {
+	clients, err := s.db.OAuth.GetClients()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении приложений: %w", err)
+	}
+	return clients, nil
+}
+
+// DeleteClient удаляет приложение; все выданные ему токены сразу перестают действовать.
+func (s *OAuthService) DeleteClient(id string) error {
+	return s.db.OAuth.DeleteClient(id, time.Now())
+}
+
+// PrepareAuthorization проверяет запрос авторизации и возвращает данные для экрана согласия.
+func (s *OAuthService) PrepareAuthorization(req *model.AuthorizationRequest) (*model.ConsentScreen, error) {
+	client, redirectURI, scopes, err := s.checkAuthorization(req)
+	if err != nil {
+		return nil, err
+	}
+	screen := &model.ConsentScreen{
+		ClientID:    client.Id,
+		ClientName:  client.Name,
+		Scopes:      make([]model.ScopeInfo, len(scopes)),
+		RedirectURI: redirectURI,
+		State:       req.State,
+	}
+	for i, scope := range scopes {
+		screen.Scopes[i] = model.ScopeInfo{Scope: scope, Description: scope.Description()}
+	}
+	return screen, nil
+}
+
+// Authorize завершает запрос авторизации решением пользователя и возвращает адрес,
+// на который нужно вернуть пользователя в приложение: с кодом авторизации или,
+// если пользователь отказал, с error=access_denied.
+func (s *OAuthService) Authorize(userID int64, req *model.AuthorizationRequest, approved bool) (string, error) {
+	client, redirectURI, scopes, err := s.checkAuthorization(req)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	if !approved {
+		params.Set("error", "access_denied")
+		return appendQuery(redirectURI, params), nil
+	}
+
+	code, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if err := s.db.OAuth.CreateCode(&model.OAuthCode{
+		CodeHash:      hashToken(code),
+		ClientID:      client.Id,
+		UserId:        userID,
+		RedirectURI:   redirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     now.Add(oauthCodeTTL),
+		CreatedAt:     now,
+	}); err != nil {
+		return "", fmt.Errorf("ошибка при сохранении кода авторизации: %w", err)
+	}
+	params.Set("code", code)
+	return appendQuery(redirectURI, params), nil
+}
+
+// AuthenticateClient проверяет приложение, обратившееся к эндпоинтам токенов.
+// Конфиденциальное приложение должно передать секрет, публичное — только client_id.
+func (s *OAuthService) AuthenticateClient(clientID, secret string) (model.OAuthClient, error) {
+	client, err := s.db.OAuth.GetClient(clientID)
+	if errors.Is(err, model.ErrUnknownOAuthClient) {
+		return client, ErrInvalidOAuthClient
+	}
+	if err != nil {
+		return client, fmt.Errorf("ошибка при получении приложения: %w", err)
+	}
+	if client.Confidential() {
+		if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(client.SecretHash)) != 1 {
+			return client, ErrInvalidOAuthClient
+		}
+	} else if secret != "" {
+		return client, ErrInvalidOAuthClient
+	}
+	return client, nil
+}
+
+// ExchangeCode обменивает код авторизации на токены (grant_type=authorization_code) и
+// начинает сессию приложения; device — откуда пришёл запрос приложения.
+// Повторное предъявление кода отзывает токены, выданные по нему в первый раз.
+func (s *OAuthService) ExchangeCode(client model.OAuthClient, code, redirectURI, verifier string, device model.DeviceInfo) (*model.OAuthTokenResponse, error) {
+	if code == "" || verifier == "" {
+		return nil, fmt.Errorf("%w: нужно передать code и code_verifier", ErrInvalidOAuthRequest)
+	}
+	familyID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	c, err := s.db.OAuth.ConsumeCode(hashToken(code), familyID, now)
+	switch {
+	case errors.Is(err, model.ErrInvalidOAuthCode):
+		return nil, ErrInvalidGrant
+	case errors.Is(err, model.ErrOAuthCodeReused):
+		if c.FamilyID != "" {
+			if err := s.db.Tokens.RevokeRefreshTokenFamily(c.FamilyID, now); err != nil {
+				return nil, fmt.Errorf("ошибка при отзыве токенов по коду: %w", err)
+			}
+		}
+		return nil, fmt.Errorf("%w: код уже использован, выданные по нему токены отозваны", ErrInvalidGrant)
+	case err != nil:
+		return nil, fmt.Errorf("ошибка при использовании кода авторизации: %w", err)
+	}
+
+	if c.ClientID != client.Id || !now.Before(c.ExpiresAt) {
+		return nil, ErrInvalidGrant
+	}
+	// redirect_uri обязателен, если его передавали в запросе авторизации (RFC 6749, п. 4.1.3);
+	// код и так привязан к адресу, поэтому без параметра сверять нечего
+	if redirectURI != "" && redirectURI != c.RedirectURI {
+		return nil, fmt.Errorf("%w: redirect_uri не совпадает с запросом авторизации", ErrInvalidGrant)
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	if subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(c.CodeChallenge)) != 1 {
+		return nil, fmt.Errorf("%w: code_verifier не подходит к code_challenge", ErrInvalidGrant)
+	}
+
+	user, err := s.db.Users.GetUserByID(c.UserId)
+	if err != nil {
+		return nil, ErrInvalidGrant
+	}
+	pair, err := s.tokens.startFamily(user, familyID, false, &model.OAuthGrant{ClientID: client.Id, Scopes: c.Scopes}, device)
+	if err != nil {
+		return nil, err
+	}
+	return oauthTokenResponse(pair, c.Scopes), nil
+}
+
+// RefreshGrant обменивает refresh-токен приложения на новую пару (grant_type=refresh_token).
+// scope может только сузить права, выданные пользователем.
+func (s *OAuthService) RefreshGrant(client model.OAuthClient, rawRefreshToken, scope string) (*model.OAuthTokenResponse, error) {
+	if rawRefreshToken == "" {
+		return nil, fmt.Errorf("%w: нужно передать refresh_token", ErrInvalidOAuthRequest)
+	}
+	pair, scopes, err := s.tokens.rotateRefreshToken(rawRefreshToken, client.Id, model.ParseScopes(strings.Fields(scope)))
+	if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, model.ErrRefreshTokenReused) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGrant, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return oauthTokenResponse(pair, scopes), nil
+}
+
+// Introspect сообщает, действует ли токен, выданный приложению client (RFC 7662).
+// Токены других приложений и собственные сессии пользователей считаются неактивными.
+// hint — token_type_hint: с какого типа токена начать проверку.
+func (s *OAuthService) Introspect(client model.OAuthClient, token, hint string) (*model.TokenIntrospection, error) {
+	inactive := &model.TokenIntrospection{}
+	if token == "" {
+		return inactive, nil
+	}
+	check := []func(model.OAuthClient, string) (*model.TokenIntrospection, error){s.introspectAccess, s.introspectRefresh}
+	if hint == "refresh_token" {
+		check[0], check[1] = check[1], check[0]
+	}
+	for _, f := range check {
+		info, err := f(client, token)
+		if err != nil || info != nil {
+			return info, err
+		}
+	}
+	return inactive, nil
+}
+
+// Revoke отзывает токен, выданный приложению client (RFC 7009): refresh-токен — вместе
+// со всей цепочкой, access-токен — только его. Неизвестные и чужие токены пропускаются.
+func (s *OAuthService) Revoke(client model.OAuthClient, token string) error {
+	now := time.Now()
+	if refresh, err := s.db.Tokens.GetRefreshTokenByHash(hashToken(token)); err == nil {
+		if refresh.ClientID != client.Id {
+			return nil
+		}
+		return s.db.Tokens.RevokeRefreshTokenFamily(refresh.FamilyID, now)
+	} else if !errors.Is(err, model.ErrUnknownRefreshToken) {
+		return err
+	}
+
+	claims, err := s.tokens.VerifyJWT(token)
+	if err != nil || claims["client_id"] != client.Id {
+		return nil
+	}
+	jti, _ := claims["jti"].(string)
+	exp, err := claims.GetExpirationTime()
+	if jti == "" || err != nil || exp == nil {
+		return nil
+	}
+	return s.db.Tokens.RevokeAccessToken(jti, exp.Time, now)
+}
+
+// Metadata возвращает метаданные сервера авторизации (RFC 8414).
+func (s *OAuthService) Metadata() map[string]any {
+	scopes := make([]string, 0, len(scopeOrder))
+	for _, scope := range scopeOrder {
+		scopes = append(scopes, string(scope))
+	}
+	return map[string]any{
+		"issuer":                                s.baseURL,
+		"authorization_endpoint":                s.baseURL + "/oauth/authorize",
+		"token_endpoint":                        s.baseURL + "/oauth/token",
+		"introspection_endpoint":                s.baseURL + "/oauth/introspect",
+		"revocation_endpoint":                   s.baseURL + "/oauth/revoke",
+		"jwks_uri":                              s.baseURL + "/.well-known/jwks.json",
+		"scopes_supported":                      scopes,
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+	}
+}
+
+// scopeOrder — права в порядке, в котором они публикуются в метаданных.
+var scopeOrder = []model.Scope{
+	model.ScopeTasksRead, model.ScopeTasksWrite,
+	model.ScopeProjectsRead, model.ScopeProjectsWrite,
+	model.ScopeTagsRead, model.ScopeTagsWrite,
+}
+
+// checkAuthorization проверяет запрос авторизации и возвращает приложение, адрес возврата
+// и запрошенные права. PKCE (S256) обязателен для всех приложений.
+func (s *OAuthService) checkAuthorization(req *model.AuthorizationRequest) (model.OAuthClient, string, []model.Scope, error) {
+	client, err := s.db.OAuth.GetClient(req.ClientID)
+	if errors.Is(err, model.ErrUnknownOAuthClient) {
+		return client, "", nil, ErrInvalidOAuthClient
+	}
+	if err != nil {
+		return client, "", nil, fmt.Errorf("ошибка при получении приложения: %w", err)
+	}
+
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !client.HasRedirectURI(redirectURI) {
+		return client, "", nil, fmt.Errorf("%w: redirect_uri не зарегистрирован для приложения", ErrInvalidOAuthRequest)
+	}
+	if req.ResponseType != "code" {
+		return client, "", nil, fmt.Errorf("%w: поддерживается только response_type=code", ErrInvalidOAuthRequest)
+	}
+	if req.CodeChallengeMethod != "S256" || !validPKCEValue(req.CodeChallenge) {
+		return client, "", nil, fmt.Errorf("%w: нужен code_challenge с code_challenge_method=S256", ErrInvalidOAuthRequest)
+	}
+
+	requested := model.ParseScopes(strings.Fields(req.Scope))
+	if len(requested) == 0 {
+		requested = client.Scopes
+	}
+	allowed := model.OAuthGrant{Scopes: client.Scopes}
+	seen := make(map[model.Scope]bool, len(requested))
+	scopes := make([]model.Scope, 0, len(requested))
+	for _, scope := range requested {
+		if !allowed.Allows(scope) {
+			return client, "", nil, fmt.Errorf("%w: приложению недоступно право %s", ErrInvalidScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return client, redirectURI, scopes, nil
+}
+
+// introspectAccess проверяет access-токен приложения; nil — это не access-токен приложения.
+func (s *OAuthService) introspectAccess(client model.OAuthClient, token string) (*model.TokenIntrospection, error) {
+	claims, err := s.tokens.VerifyJWT(token)
+	if err != nil || claims["typ"] != nil || claims["client_id"] != client.Id {
+		return nil, nil
+	}
+	jti, _ := claims["jti"].(string)
+	if revoked, err := s.tokens.IsAccessTokenRevoked(jti); err != nil || revoked {
+		return &model.TokenIntrospection{}, err
+	}
+	userID, _ := claims["user_id"].(float64)
+	user, err := s.db.Users.GetUserByID(int64(userID))
+	if err != nil || user.Suspended() {
+		return &model.TokenIntrospection{}, nil
+	}
+	scope, _ := claims["scope"].(string)
+	info := &model.TokenIntrospection{
+		Active:    true,
+		Scope:     scope,
+		ClientID:  client.Id,
+		Username:  user.Username,
+		Subject:   strconv.Itoa(user.Id),
+		TokenType: "Bearer",
+		JTI:       jti,
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		info.ExpiresAt = exp.Unix()
+	}
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		info.IssuedAt = iat.Unix()
+	}
+	return info, nil
+}
+
+// introspectRefresh проверяет refresh-токен приложения; nil — такого refresh-токена нет.
+func (s *OAuthService) introspectRefresh(client model.OAuthClient, token string) (*model.TokenIntrospection, error) {
+	refresh, err := s.db.Tokens.GetRefreshTokenByHash(hashToken(token))
+	if errors.Is(err, model.ErrUnknownRefreshToken) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if refresh.ClientID != client.Id || refresh.RevokedAt != nil || !time.Now().Before(refresh.ExpiresAt) {
+		return &model.TokenIntrospection{}, nil
+	}
+	user, err := s.db.Users.GetUserByID(refresh.UserId)
+	if err != nil || user.Suspended() {
+		return &model.TokenIntrospection{}, nil
+	}
+	return &model.TokenIntrospection{
+		Active:    true,
+		Scope:     model.JoinScopes(refresh.Scopes),
+		ClientID:  client.Id,
+		Username:  user.Username,
+		Subject:   strconv.Itoa(user.Id),
+		TokenType: "refresh_token",
+		ExpiresAt: refresh.ExpiresAt.Unix(),
+		IssuedAt:  refresh.CreatedAt.Unix(),
+	}, nil
+}
+
+// checkRedirectURI проверяет адрес возврата при регистрации приложения: https, http только
+// для loopback-адресов настольных приложений (RFC 8252, п. 7.3) или собственная схема
+// мобильного приложения. Фрагмент в адресе запрещён (RFC 6749, п. 3.1.2).
+func checkRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" || u.Fragment != "" || strings.Contains(uri, "#") {
+		return fmt.Errorf("%w: redirect_uri %q должен быть абсолютным адресом без фрагмента", ErrInvalidOAuthClientInput, uri)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		if u.Host == "" {
+			return fmt.Errorf("%w: в redirect_uri %q нет хоста", ErrInvalidOAuthClientInput, uri)
+		}
+	case "http":
+		if !model.IsLoopbackHost(u.Hostname()) {
+			return fmt.Errorf("%w: http допускается только для localhost, а не %q", ErrInvalidOAuthClientInput, uri)
+		}
+	case "javascript", "data", "file", "vbscript":
+		return fmt.Errorf("%w: недопустимая схема в redirect_uri %q", ErrInvalidOAuthClientInput, uri)
+	}
+	return nil
+}
+
+// validPKCEValue проверяет code_challenge: 43–128 символов из base64url (RFC 7636, п. 4.1–4.2).
+func validPKCEValue(v string) bool {
+	if len(v) < 43 || len(v) > 128 {
+		return false
+	}
+	for _, r := range v {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || strings.ContainsRune("-._~", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendQuery добавляет параметры к адресу возврата, сохраняя его собственные параметры.
+func appendQuery(uri string, params url.Values) string {
+	sep := "?"
+	if strings.Contains(uri, "?") {
+		sep = "&"
+	}
+	return uri + sep + params.Encode()
+}
+
+// oauthTokenResponse переводит пару токенов в ответ эндпоинта токенов OAuth2.
+func oauthTokenResponse(pair *model.TokenPair, scopes []model.Scope) *model.OAuthTokenResponse {
+	return &model.OAuthTokenResponse{
+		AccessToken:  pair.AccessToken,
+		TokenType:    pair.TokenType,
+		ExpiresIn:    pair.ExpiresIn,
+		RefreshToken: pair.RefreshToken,
+		Scope:        model.JoinScopes(scopes),
+	}
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mood/internal/database"
+	"go.mood/internal/model"
+	"go.mood/internal/oidc"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrUnknownProvider возвращается для провайдера входа, которого нет в конфиге.
+	ErrUnknownProvider = errors.New("провайдер входа не найден")
+	// ErrInvalidOIDCState возвращается, если ответ провайдера не относится к начатому входу или вход устарел.
+	ErrInvalidOIDCState = errors.New("вход через провайдера устарел или начат в другом браузере: начните заново")
+	// ErrOIDCLogin возвращается, если провайдер не подтвердил вход: код не обменялся или ID-токен не прошёл проверку.
+	ErrOIDCLogin = errors.New("провайдер не подтвердил вход")
+	// ErrOIDCUnavailable возвращается, если не удалось получить настройки провайдера.
+	ErrOIDCUnavailable = errors.New("провайдер входа недоступен, попробуйте позже")
+	// ErrOIDCEmailRequired возвращается, если для нового пользователя провайдер не передал email.
+	ErrOIDCEmailRequired = errors.New("провайдер не передал email: разрешите доступ к email или зарегистрируйтесь с паролем")
+)
+
+const (
+	// oidcStateTTL — сколько ждать возврата от провайдера после начала входа.
+	oidcStateTTL = 10 * time.Minute
+	// oidcStateType — значение claim typ у токена состояния входа; access-токены его не содержат.
+	oidcStateType = "oidc_state"
+	// maxUsernameAttempts — сколько суффиксов перебрать, подбирая свободное имя новому пользователю.
+	maxUsernameAttempts = 100
+)
+
+// OIDCService — вход через внешних провайдеров OpenID Connect. Учётная запись провайдера
+// привязывается к пользователю при первом входе: к существующему — по подтверждённому
+// провайдером email, иначе создаётся новый пользователь без пароля.
+type OIDCService struct {
+	db                   *database.Database
+	tokens               *TokenService
+	guard                *loginGuard
+	providers            map[string]*oidc.Provider
+	requireVerifiedEmail bool
+}
+
+// NewOIDCService создаёт новый экземпляр OIDCService с провайдерами из configs.
+func NewOIDCService(db *database.Database, tokens *TokenService, guard *loginGuard, configs []oidc.Config, requireVerifiedEmail bool) *OIDCService {
+	providers := make(map[string]*oidc.Provider, len(configs))
+	for _, cfg := range configs {
+		providers[cfg.Name] = oidc.New(cfg)
+	}
+	return &OIDCService{
+		db:                   db,
+		tokens:               tokens,
+		guard:                guard,
+		providers:            providers,
+		requireVerifiedEmail: requireVerifiedEmail,
+	}
+}
+
+// Providers возвращает имена настроенных провайдеров по алфавиту.
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BeginLogin начинает вход через провайдера: возвращает адрес его страницы входа и
+// подписанное состояние входа (state, nonce и секрет PKCE), которое клиент хранит в cookie
+// и возвращает в CompleteLogin.
+func (s *OIDCService) BeginLogin(ctx context.Context, name string) (*model.OIDCAuthRequest, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	var values [3]string // state, nonce, verifier
+	for i := range values {
+		v, err := randomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	authURL, err := p.AuthCodeURL(ctx, values[0], values[1], values[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCUnavailable, err)
+	}
+
+	now := time.Now()
+	signed, err := s.tokens.keys.Sign(jwt.MapClaims{
+		"typ":      oidcStateType,
+		"provider": name,
+		"state":    values[0],
+		"nonce":    values[1],
+		"verifier": values[2],
+		"iat":      now.Unix(),
+		"exp":      now.Add(oidcStateTTL).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подписать состояние входа: %w", err)
+	}
+	return &model.OIDCAuthRequest{
+		AuthURL:      authURL,
+		State:        signed,
+		ExpiresIn:    int64(oidcStateTTL.Seconds()),
+		SecureCookie: p.SecureRedirect(),
+	}, nil
+}
+
+// CompleteLogin завершает вход по коду, с которым провайдер вернул клиента. stateToken —
+// состояние из BeginLogin, state — параметр state из адреса возврата, device — откуда
+// выполнен вход. Как и LoginUser, при подключённой 2FA возвращает MFAChallenge вместо токенов.
+func (s *OIDCService) CompleteLogin(ctx context.Context, name, stateToken, state, code string, device model.DeviceInfo) (*model.TokenPair, *model.MFAChallenge, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, nil, ErrUnknownProvider
+	}
+	claims, err := s.tokens.keys.Parse(stateToken)
+	if err != nil || claims["typ"] != oidcStateType || claims["provider"] != name {
+		return nil, nil, ErrInvalidOIDCState
+	}
+	expected, _ := claims["state"].(string)
+	if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(state)) != 1 {
+		return nil, nil, ErrInvalidOIDCState
+	}
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["verifier"].(string)
+
+	rawIDToken, err := p.Exchange(ctx, code, verifier)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrOIDCLogin, err)
+	}
+	idClaims, err := p.VerifyIDToken(ctx, rawIDToken, nonce)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrOIDCLogin, err)
+	}
+
+	user, err := s.resolveUser(name, idClaims)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Suspended() {
+		return nil, nil, ErrAccountSuspended
+	}
+	if s.requireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return nil, nil, ErrEmailNotVerified
+	}
+
+	mfa, err := s.tokens.mfaEnabled(int64(user.Id))
+	if err != nil {
+		return nil, nil, err
+	}
+	if mfa {
+		challenge, err := s.tokens.IssueMFAChallenge(user)
+		return nil, challenge, err
+	}
+	if err := s.guard.succeed(int64(user.Id)); err != nil {
+		return nil, nil, err
+	}
+	tokens, err := s.tokens.IssueTokens(user, false, device)
+	return tokens, nil, err
+}
+
+// resolveUser находит пользователя, к которому привязана учётная запись провайдера,
+// привязывая её при первом входе.
+func (s *OIDCService) resolveUser(provider string, claims *oidc.Claims) (model.User, error) {
+	identity, err := s.db.Identities.GetIdentity(provider, claims.Subject)
+	if err == nil {
+		if err := s.db.Identities.TouchIdentity(identity.Id, time.Now()); err != nil {
+			return model.User{}, err
+		}
+		return s.db.Users.GetUserByID(identity.UserId)
+	}
+	if !errors.Is(err, model.ErrUnknownIdentity) {
+		return model.User{}, err
+	}
+
+	identity = model.Identity{Provider: provider, Subject: claims.Subject, Email: claims.Email}
+	if claims.Email == "" {
+		return model.User{}, ErrOIDCEmailRequired
+	}
+	if existing, err := s.db.Users.GetUserByEmail(claims.Email); err == nil {
+		// Привязываем к существующему пользователю, только если провайдер ручается за email:
+		// иначе любой, кто укажет у провайдера чужой адрес, вошёл бы в чужой аккаунт
+		if !claims.EmailVerified {
+			return model.User{}, ErrEmailTaken
+		}
+		identity.UserId = int64(existing.Id)
+		if err := s.db.Identities.CreateIdentity(&identity); err != nil {
+			return model.User{}, fmt.Errorf("ошибка при привязке учётной записи провайдера: %w", err)
+		}
+		if existing.EmailVerifiedAt == nil {
+			now := time.Now()
+			if err := s.db.Users.MarkEmailVerified(int64(existing.Id), now); err != nil {
+				return model.User{}, fmt.Errorf("ошибка при подтверждении email: %w", err)
+			}
+			existing.EmailVerifiedAt = &now
+		}
+		return existing, nil
+	}
+	return s.provisionUser(&identity, claims)
+}
+
+// provisionUser создаёт пользователя без пароля для новой учётной записи провайдера.
+// Войти по паролю он сможет, задав его через восстановление пароля.
+func (s *OIDCService) provisionUser(identity *model.Identity, claims *oidc.Claims) (model.User, error) {
+	if len(claims.Email) > maxEmailLength {
+		return model.User{}, fmt.Errorf("%w: email длиннее %d символов", ErrOIDCLogin, maxEmailLength)
+	}
+	username, err := s.freeUsername(claims)
+	if err != nil {
+		return model.User{}, err
+	}
+	user := model.User{
+		Username: username,
+		Email:    claims.Email,
+		Role:     string(model.RoleUser),
+	}
+	if err := s.db.Identities.CreateUserWithIdentity(&user, identity); err != nil {
+		return model.User{}, fmt.Errorf("ошибка при создании пользователя: %w", err)
+	}
+	if claims.EmailVerified {
+		if err := s.db.Users.MarkEmailVerified(int64(user.Id), time.Now()); err != nil {
+			return model.User{}, fmt.Errorf("ошибка при подтверждении email: %w", err)
+		}
+	}
+	return s.db.Users.GetUserByID(int64(user.Id))
+}
+
+// freeUsername подбирает свободное имя из preferred_username или начала email,
+// добавляя числовой суффикс, если имя занято.
+func (s *OIDCService) freeUsername(claims *oidc.Claims) (string, error) {
+	base := strings.TrimSpace(claims.PreferredUsername)
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if base == "" {
+		base = "user"
+	}
+	for i := 1; i <= maxUsernameAttempts; i++ {
+		suffix := ""
+		if i > 1 {
+			suffix = strconv.Itoa(i)
+		}
+		name := truncateRunes(base, maxUsernameLength-len(suffix)) + suffix
+		if _, err := s.db.Users.GetUserByUsername(name); err != nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: не удалось подобрать свободное имя пользователя для %q", ErrOIDCLogin, base)
+}
+
+// truncateRunes обрезает строку до n символов.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
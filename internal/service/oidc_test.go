@@ -0,0 +1,580 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mood/internal/database"
+	"go.mood/internal/jwtkeys"
+	"go.mood/internal/model"
+	"go.mood/internal/oidc"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	stubProvider    = "stub"
+	stubClientID    = "mood-client"
+	stubRedirectURL = "https://mood.example/auth/oidc/stub/callback"
+)
+
+// stubOIDC — поддельный провайдер OpenID Connect: отдаёт документ discovery и ключи,
+// а на запрос токена проверяет PKCE и возвращает ID-токен с nonce из начала входа.
+type stubOIDC struct {
+	srv    *httptest.Server
+	issuer string // issuer в документе discovery; по умолчанию адрес сервера
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+
+	// claims дополняет или меняет claims ID-токена, sign подписывает его вместо ключа RSA.
+	claims func(c jwt.MapClaims)
+	sign   func(c jwt.MapClaims) string
+
+	mu       sync.Mutex
+	codes    map[string]stubCode
+	requests int // запросов к token_endpoint
+}
+
+// stubCode — выданный код авторизации: code_challenge и nonce начатого входа.
+type stubCode struct {
+	challenge string
+	nonce     string
+}
+
+func newStubOIDC(t *testing.T) *stubOIDC {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &stubOIDC{rsaKey: rsaKey, ecKey: ecKey, codes: make(map[string]stubCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.issuer,
+			"authorization_endpoint": p.srv.URL + "/authorize",
+			"token_endpoint":         p.srv.URL + "/token",
+			"jwks_uri":               p.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{
+				"kty": "RSA", "kid": "rsa1", "use": "sig",
+				"n": b64(rsaKey.N.Bytes()),
+				"e": b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC", "kid": "ec1", "use": "sig", "crv": "P-256",
+				"x": b64(ecKey.X.FillBytes(make([]byte, size))),
+				"y": b64(ecKey.Y.FillBytes(make([]byte, size))),
+			},
+		}})
+	})
+	mux.HandleFunc("/token", p.handleToken)
+	p.srv = httptest.NewServer(mux)
+	p.issuer = p.srv.URL
+	t.Cleanup(p.srv.Close)
+	return p
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// handleToken обменивает код на ID-токен, если code_verifier соответствует code_challenge.
+func (p *stubOIDC) handleToken(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	p.mu.Lock()
+	p.requests++
+	code, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || b64(sum[:]) != code.challenge ||
+		r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != stubClientID ||
+		r.PostForm.Get("redirect_uri") != stubRedirectURL {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   p.srv.URL,
+		"sub":   "subject-1",
+		"aud":   stubClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": code.nonce,
+		"email": "alice@example.com",
+	}
+	if p.claims != nil {
+		p.claims(claims)
+	}
+	idToken := ""
+	if p.sign != nil {
+		idToken = p.sign(claims)
+	} else {
+		idToken = p.signRSA(claims)
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+}
+
+func (p *stubOIDC) signRSA(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "rsa1"
+	signed, _ := token.SignedString(p.rsaKey)
+	return signed
+}
+
+// authorize начинает вход и, как браузер на странице провайдера, получает код авторизации.
+// Возвращает состояние входа для cookie, параметр state адреса возврата и код.
+func (p *stubOIDC) authorize(t *testing.T, s *OIDCService) (stateToken, state, code string) {
+	t.Helper()
+	req, err := s.BeginLogin(context.Background(), stubProvider)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	u, err := url.Parse(req.AuthURL)
+	if err != nil {
+		t.Fatalf("некорректный адрес входа %q: %v", req.AuthURL, err)
+	}
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("client_id") != stubClientID || q.Get("redirect_uri") != stubRedirectURL ||
+		q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("некорректный адрес входа: %s", req.AuthURL)
+	}
+
+	code, err = randomToken(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	p.codes[code] = stubCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	p.mu.Unlock()
+	return req.State, q.Get("state"), code
+}
+
+// login проходит вход через провайдера целиком.
+func (p *stubOIDC) login(t *testing.T, s *OIDCService) (*model.TokenPair, error) {
+	t.Helper()
+	stateToken, state, code := p.authorize(t, s)
+	tokens, _, err := s.CompleteLogin(context.Background(), stubProvider, stateToken, state, code, model.DeviceInfo{})
+	return tokens, err
+}
+
+func newTestOIDCService(t *testing.T, issuer string) (*OIDCService, *database.Database) {
+	t.Helper()
+	keys, err := jwtkeys.Load("", "test-secret", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := database.NewMemoryDatabase()
+	tokens := NewTokenService(db, keys, 0, 0)
+	configs := []oidc.Config{{
+		Name:        stubProvider,
+		Issuer:      issuer,
+		ClientID:    stubClientID,
+		RedirectURL: stubRedirectURL,
+	}}
+	return NewOIDCService(db, tokens, newLoginGuard(db, LockoutPolicy{}), configs, false), db
+}
+
+// userFromTokens возвращает пользователя, которому выданы токены.
+func userFromTokens(t *testing.T, s *OIDCService, db *database.Database, tokens *model.TokenPair) model.User {
+	t.Helper()
+	claims, err := s.tokens.keys.Parse(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("выдан некорректный access-токен: %v", err)
+	}
+	id, _ := claims["user_id"].(float64)
+	user, err := db.Users.GetUserByID(int64(id))
+	if err != nil {
+		t.Fatalf("access-токен выдан неизвестному пользователю %v: %v", claims["user_id"], err)
+	}
+	return user
+}
+
+func mustListUsers(t *testing.T, db *database.Database) []model.User {
+	t.Helper()
+	users, err := db.Users.ListUsers(model.UserFilter{})
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	return users
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	p := newStubOIDC(t)
+	p.issuer = "https://idp.example"
+	s, _ := newTestOIDCService(t, p.srv.URL)
+
+	if _, err := s.BeginLogin(context.Background(), stubProvider); !errors.Is(err, ErrOIDCUnavailable) {
+		t.Fatalf("BeginLogin с чужим issuer в discovery: %v, ожидалась ErrOIDCUnavailable", err)
+	}
+}
+
+func TestOIDCProvisionsUserOnFirstLogin(t *testing.T) {
+	p := newStubOIDC(t)
+	s, db := newTestOIDCService(t, p.srv.URL+"/")
+	taken := model.User{Username: "alice", Email: "other@example.com", PasswordHash: "hash"}
+	if err := db.Users.CreateUser(&taken); err != nil {
+		t.Fatal(err)
+	}
+	p.claims = func(c jwt.MapClaims) {
+		c["preferred_username"] = "alice"
+		c["email_verified"] = true
+	}
+
+	tokens, err := p.login(t, s)
+	if err != nil {
+		t.Fatalf("первый вход: %v", err)
+	}
+	user := userFromTokens(t, s, db, tokens)
+	if user.Id == taken.Id || user.Username != "alice2" || user.Email != "alice@example.com" {
+		t.Errorf("создан пользователь %q <%s>, ожидался alice2 <alice@example.com>", user.Username, user.Email)
+	}
+	if user.PasswordHash != "" || user.EmailVerifiedAt == nil {
+		t.Errorf("новый пользователь: хеш пароля %q, email подтверждён %v", user.PasswordHash, user.EmailVerifiedAt)
+	}
+	identity, err := db.Identities.GetIdentity(stubProvider, "subject-1")
+	if err != nil || identity.UserId != int64(user.Id) {
+		t.Errorf("учётная запись провайдера: %+v, %v", identity, err)
+	}
+
+	// Повторный вход находит того же пользователя по sub, даже если email у провайдера сменился
+	p.claims = func(c jwt.MapClaims) { c["email"] = "alice@new.example" }
+	tokens, err = p.login(t, s)
+	if err != nil {
+		t.Fatalf("повторный вход: %v", err)
+	}
+	if again := userFromTokens(t, s, db, tokens); again.Id != user.Id {
+		t.Errorf("повторный вход выдал токены пользователю %d, ожидался %d", again.Id, user.Id)
+	}
+	if n := len(mustListUsers(t, db)); n != 2 {
+		t.Errorf("пользователей %d, ожидалось 2", n)
+	}
+}
+
+func TestOIDCProvisionRequiresEmail(t *testing.T) {
+	p := newStubOIDC(t)
+	s, _ := newTestOIDCService(t, p.srv.URL)
+	p.claims = func(c jwt.MapClaims) { delete(c, "email") }
+
+	if _, err := p.login(t, s); !errors.Is(err, ErrOIDCEmailRequired) {
+		t.Fatalf("вход без email: %v, ожидалась ErrOIDCEmailRequired", err)
+	}
+}
+
+func TestOIDCLinksExistingUserByVerifiedEmailOnly(t *testing.T) {
+	p := newStubOIDC(t)
+	s, db := newTestOIDCService(t, p.srv.URL)
+	existing := model.User{Username: "alice", Email: "alice@example.com", PasswordHash: "hash"}
+	if err := db.Users.CreateUser(&existing); err != nil {
+		t.Fatal(err)
+	}
+
+	p.claims = func(c jwt.MapClaims) { c["email_verified"] = false }
+	if _, err := p.login(t, s); !errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("вход с неподтверждённым email чужого аккаунта: %v, ожидалась ErrEmailTaken", err)
+	}
+	if _, err := db.Identities.GetIdentity(stubProvider, "subject-1"); !errors.Is(err, model.ErrUnknownIdentity) {
+		t.Fatalf("учётная запись провайдера привязана без подтверждённого email: %v", err)
+	}
+
+	// Некоторые провайдеры передают email_verified строкой
+	p.claims = func(c jwt.MapClaims) { c["email_verified"] = "true" }
+	tokens, err := p.login(t, s)
+	if err != nil {
+		t.Fatalf("вход с подтверждённым email: %v", err)
+	}
+	user := userFromTokens(t, s, db, tokens)
+	if user.Id != existing.Id {
+		t.Errorf("токены выданы пользователю %d, ожидался %d", user.Id, existing.Id)
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Error("email существующего пользователя не отмечен подтверждённым")
+	}
+	if n := len(mustListUsers(t, db)); n != 1 {
+		t.Errorf("пользователей %d, ожидался 1", n)
+	}
+}
+
+func TestOIDCVerifiesIDToken(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		claims func(p *stubOIDC, c jwt.MapClaims)
+		sign   func(p *stubOIDC, c jwt.MapClaims) string
+		ok     bool
+	}{
+		{name: "RS256", ok: true},
+		{
+			name: "ES256",
+			sign: func(p *stubOIDC, c jwt.MapClaims) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodES256, c)
+				token.Header["kid"] = "ec1"
+				signed, _ := token.SignedString(p.ecKey)
+				return signed
+			},
+			ok: true,
+		},
+		{
+			name: "несколько получателей, azp — это приложение",
+			claims: func(p *stubOIDC, c jwt.MapClaims) {
+				c["aud"] = []string{stubClientID, "other-client"}
+				c["azp"] = stubClientID
+			},
+			ok: true,
+		},
+		{
+			name: "подпись чужим ключом",
+			sign: func(p *stubOIDC, c jwt.MapClaims) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+				token.Header["kid"] = "rsa1"
+				signed, _ := token.SignedString(otherKey)
+				return signed
+			},
+		},
+		{
+			name: "неизвестный kid",
+			sign: func(p *stubOIDC, c jwt.MapClaims) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+				token.Header["kid"] = "rsa2"
+				signed, _ := token.SignedString(p.rsaKey)
+				return signed
+			},
+		},
+		{
+			name: "HS256 с открытым ключом провайдера как секретом",
+			sign: func(p *stubOIDC, c jwt.MapClaims) string {
+				der, _ := x509.MarshalPKIXPublicKey(&p.rsaKey.PublicKey)
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+				token.Header["kid"] = "rsa1"
+				signed, _ := token.SignedString(der)
+				return signed
+			},
+		},
+		{
+			name: "alg none",
+			sign: func(p *stubOIDC, c jwt.MapClaims) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodNone, c)
+				token.Header["kid"] = "rsa1"
+				signed, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+				return signed
+			},
+		},
+		{
+			name: "чужой издатель",
+			claims: func(p *stubOIDC, c jwt.MapClaims) {
+				c["iss"] = "https://idp.example"
+			},
+		},
+		{
+			name: "чужой получатель",
+			claims: func(p *stubOIDC, c jwt.MapClaims) {
+				c["aud"] = "other-client"
+			},
+		},
+		{
+			name: "несколько получателей без azp",
+			claims: func(p *stubOIDC, c jwt.MapClaims) {
+				c["aud"] = []string{stubClientID, "other-client"}
+			},
+		},
+		{
+			name: "несколько получателей, azp — другое приложение",
+			claims: func(p *stubOIDC, c jwt.MapClaims) {
+				c["aud"] = []string{stubClientID, "other-client"}
+				c["azp"] = "other-client"
+			},
+		},
+		{
+			name: "чужой nonce",
+			claims: func(p *stubOIDC, c jwt.MapClaims) {
+				c["nonce"] = "replayed-nonce"
+			},
+		},
+		{
+			name: "без nonce",
+			claims: func(p *stubOIDC, c jwt.MapClaims) {
+				delete(c, "nonce")
+			},
+		},
+		{
+			name: "истёк",
+			claims: func(p *stubOIDC, c jwt.MapClaims) {
+				c["iat"] = time.Now().Add(-time.Hour).Unix()
+				c["exp"] = time.Now().Add(-10 * time.Minute).Unix()
+			},
+		},
+		{
+			name: "без exp",
+			claims: func(p *stubOIDC, c jwt.MapClaims) {
+				delete(c, "exp")
+			},
+		},
+		{
+			name: "без sub",
+			claims: func(p *stubOIDC, c jwt.MapClaims) {
+				delete(c, "sub")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newStubOIDC(t)
+			s, db := newTestOIDCService(t, p.srv.URL)
+			if tt.claims != nil {
+				p.claims = func(c jwt.MapClaims) { tt.claims(p, c) }
+			}
+			if tt.sign != nil {
+				p.sign = func(c jwt.MapClaims) string { return tt.sign(p, c) }
+			}
+
+			tokens, err := p.login(t, s)
+			if tt.ok {
+				if err != nil || tokens == nil {
+					t.Fatalf("вход отклонён: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrOIDCLogin) {
+				t.Fatalf("вход: %v, ожидалась ErrOIDCLogin", err)
+			}
+			if n := len(mustListUsers(t, db)); n != 0 {
+				t.Errorf("по отклонённому ID-токену создано пользователей: %d", n)
+			}
+		})
+	}
+}
+
+func TestOIDCRejectsInvalidState(t *testing.T) {
+	p := newStubOIDC(t)
+	s, _ := newTestOIDCService(t, p.srv.URL)
+	sign := func(claims jwt.MapClaims) string {
+		signed, err := s.tokens.keys.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(stateToken, state string) (string, string)
+	}{
+		{
+			name: "state из адреса возврата не совпадает",
+			tamper: func(stateToken, state string) (string, string) {
+				return stateToken, state + "x"
+			},
+		},
+		{
+			name: "без state в адресе возврата",
+			tamper: func(stateToken, state string) (string, string) {
+				return stateToken, ""
+			},
+		},
+		{
+			name: "изменено содержимое cookie",
+			tamper: func(stateToken, state string) (string, string) {
+				parts := strings.Split(stateToken, ".")
+				payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+				var claims map[string]any
+				json.Unmarshal(payload, &claims)
+				claims["exp"] = time.Now().Add(time.Hour).Unix() * 10
+				payload, _ = json.Marshal(claims)
+				parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+				return strings.Join(parts, "."), state
+			},
+		},
+		{
+			name: "истёк",
+			tamper: func(stateToken, state string) (string, string) {
+				claims, _ := s.tokens.keys.Parse(stateToken)
+				claims["iat"] = time.Now().Add(-2 * oidcStateTTL).Unix()
+				claims["exp"] = time.Now().Add(-oidcStateTTL).Unix()
+				return sign(claims), state
+			},
+		},
+		{
+			name: "выдан для другого провайдера",
+			tamper: func(stateToken, state string) (string, string) {
+				claims, _ := s.tokens.keys.Parse(stateToken)
+				claims["provider"] = "other"
+				return sign(claims), state
+			},
+		},
+		{
+			name: "не состояние входа",
+			tamper: func(stateToken, state string) (string, string) {
+				claims, _ := s.tokens.keys.Parse(stateToken)
+				delete(claims, "typ")
+				return sign(claims), state
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateToken, state, code := p.authorize(t, s)
+			stateToken, state = tt.tamper(stateToken, state)
+
+			_, _, err := s.CompleteLogin(context.Background(), stubProvider, stateToken, state, code, model.DeviceInfo{})
+			if !errors.Is(err, ErrInvalidOIDCState) {
+				t.Fatalf("CompleteLogin: %v, ожидалась ErrInvalidOIDCState", err)
+			}
+		})
+	}
+	if p.requests != 0 {
+		t.Errorf("при неверном состоянии входа код обменивался у провайдера %d раз", p.requests)
+	}
+}
+
+func TestOIDCPKCEVerifier(t *testing.T) {
+	p := newStubOIDC(t)
+	s, _ := newTestOIDCService(t, p.srv.URL)
+
+	// Код первого входа с состоянием второго: секрет PKCE не соответствует code_challenge
+	_, _, code := p.authorize(t, s)
+	stateToken, state, _ := p.authorize(t, s)
+	_, _, err := s.CompleteLogin(context.Background(), stubProvider, stateToken, state, code, model.DeviceInfo{})
+	if !errors.Is(err, ErrOIDCLogin) {
+		t.Fatalf("обмен кода с чужим секретом PKCE: %v, ожидалась ErrOIDCLogin", err)
+	}
+
+	stateToken, state, code = p.authorize(t, s)
+	if _, _, err := s.CompleteLogin(context.Background(), stubProvider, stateToken, state, code, model.DeviceInfo{}); err != nil {
+		t.Fatalf("вход со своим секретом PKCE: %v", err)
+	}
+	// Код одноразовый
+	if _, _, err := s.CompleteLogin(context.Background(), stubProvider, stateToken, state, code, model.DeviceInfo{}); !errors.Is(err, ErrOIDCLogin) {
+		t.Fatalf("повторный обмен кода: %v, ожидалась ErrOIDCLogin", err)
+	}
+}
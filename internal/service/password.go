@@ -0,0 +1,219 @@
+package service
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultPasswordMinLength = 8
+	// maxPasswordBytes — предел длины пароля: хватает для любой парольной фразы
+	// и не даёт заставить сервер хешировать сколь угодно длинные строки.
+	maxPasswordBytes = 256
+
+	// breachedPrefixLength — сколько первых символов SHA-1 пароля используется для поиска
+	// в файле утёкших паролей: как в k-anonymity API Have I Been Pwned.
+	breachedPrefixLength = 5
+)
+
+// PasswordPolicy — требования к паролям при регистрации, сбросе и смене пароля.
+type PasswordPolicy struct {
+	MinLength   int      // минимум символов; 0 — 8
+	MinClasses  int      // сколько разных классов символов нужно (строчные, заглавные, цифры, прочие); 0 — не проверять
+	BannedWords []string // слова, которые не может содержать пароль (без учёта регистра)
+	// BreachedFile — файл утёкших паролей в формате Have I Been Pwned: строки SHA1:COUNT,
+	// отсортированные по хешу. Пусто — не проверять
+	BreachedFile string
+}
+
+// passwordChecker проверяет пароли по PasswordPolicy.
+type passwordChecker struct {
+	policy   PasswordPolicy
+	maxBytes int
+}
+
+// newPasswordChecker создаёт новый экземпляр passwordChecker. Длина пароля ограничена
+// maxPasswordBytes и тем, сколько байт пароля учитывает алгоритм hasher.
+func newPasswordChecker(policy PasswordPolicy, hasher PasswordHasher) *passwordChecker {
+	if policy.MinLength <= 0 {
+		policy.MinLength = defaultPasswordMinLength
+	}
+	words := make([]string, 0, len(policy.BannedWords))
+	for _, w := range policy.BannedWords {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			words = append(words, w)
+		}
+	}
+	policy.BannedWords = words
+	maxBytes := maxPasswordBytes
+	if limit := hasher.MaxPasswordBytes(); limit > 0 && limit < maxBytes {
+		maxBytes = limit
+	}
+	return &passwordChecker{policy: policy, maxBytes: maxBytes}
+}
+
+// check возвращает причину, по которой пароль не подходит пользователю с именем username
+// и адресом email, или пустую строку. Ошибка — только если не удалось прочитать файл утёкших паролей.
+func (c *passwordChecker) check(password, username, email string) (string, error) {
+	if password == "" {
+		return "пароль обязателен", nil
+	}
+	if utf8.RuneCountInString(password) < c.policy.MinLength {
+		return fmt.Sprintf("пароль должен быть не короче %d символов", c.policy.MinLength), nil
+	}
+	if len(password) > c.maxBytes {
+		return fmt.Sprintf("пароль должен быть не длиннее %d байт", c.maxBytes), nil
+	}
+	if c.policy.MinClasses > 0 && passwordClasses(password) < c.policy.MinClasses {
+		return fmt.Sprintf("пароль должен содержать символы хотя бы %d видов: строчные и заглавные буквы, цифры, прочие символы", c.policy.MinClasses), nil
+	}
+
+	lower := strings.ToLower(password)
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	for _, same := range []string{strings.ToLower(username), strings.ToLower(email), local} {
+		if same != "" && lower == same {
+			return "пароль не должен совпадать с именем пользователя или email", nil
+		}
+	}
+	for _, w := range c.policy.BannedWords {
+		if strings.Contains(lower, w) {
+			return "пароль содержит запрещённое слово", nil
+		}
+	}
+
+	breached, err := c.breached(password)
+	if err != nil {
+		return "", err
+	}
+	if breached {
+		return "пароль встречается в утёкших базах паролей, выберите другой", nil
+	}
+	return "", nil
+}
+
+// breached ищет SHA-1 пароля в файле утёкших паролей. Как и в k-anonymity API, из файла
+// читаются только хеши с теми же первыми символами, а сравнение идёт уже среди них.
+func (c *passwordChecker) breached(password string) (bool, error) {
+	if c.policy.BreachedFile == "" {
+		return false, nil
+	}
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+
+	suffixes, err := breachedRange(c.policy.BreachedFile, prefix)
+	if err != nil {
+		return false, fmt.Errorf("ошибка проверки пароля по утёкшим базам: %w", err)
+	}
+	for _, s := range suffixes {
+		if s == suffix {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// breachedRange возвращает окончания хешей из отсортированного файла path, которые начинаются с prefix.
+// Начало диапазона ищется двоичным поиском по смещению в файле, поэтому файл не читается целиком.
+func breachedRange(path, prefix string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Ищем наибольшее смещение, с которого следующая полная строка ещё меньше prefix
+	lo, hi := int64(0), info.Size()
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		hash, err := hashAfter(f, mid)
+		if err != nil {
+			return nil, err
+		}
+		if hash != "" && hash < prefix {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	if _, err := f.Seek(lo, io.SeekStart); err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(f)
+	if lo > 0 {
+		// Строка, в середину которой указывает lo, меньше prefix
+		scanner.Scan()
+	}
+	var suffixes []string
+	for scanner.Scan() {
+		hash := lineHash(scanner.Text())
+		if hash < prefix {
+			continue
+		}
+		if !strings.HasPrefix(hash, prefix) {
+			break
+		}
+		suffixes = append(suffixes, hash[len(prefix):])
+	}
+	return suffixes, scanner.Err()
+}
+
+// hashAfter возвращает хеш первой строки, которая начинается после смещения offset; пусто — конец файла.
+func hashAfter(f *os.File, offset int64) (string, error) {
+	r := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62))
+	if offset > 0 {
+		if _, err := r.ReadString('\n'); err != nil {
+			if err == io.EOF {
+				return "", nil
+			}
+			return "", err
+		}
+	}
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return lineHash(line), nil
+}
+
+// lineHash возвращает хеш из строки SHA1:COUNT в верхнем регистре.
+func lineHash(line string) string {
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	return strings.ToUpper(hash)
+}
+
+// passwordClasses считает, символы скольких классов есть в пароле.
+func passwordClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	count := 0
+	for _, ok := range []bool{lower, upper, digit, other} {
+		if ok {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.mood/internal/database"
+	"go.mood/internal/model"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidProject возвращается при пустом или слишком длинном имени проекта либо неверном цвете.
+var ErrInvalidProject = errors.New("имя проекта обязательно (до 100 символов), цвет — в формате #RRGGBB")
+
+const maxProjectNameLength = 100
+
+// ProjectService — сервис для работы с проектами.
+type ProjectService struct {
+	db *database.Database
+}
+
+// NewProjectService создаёт новый экземпляр ProjectService.
+func NewProjectService(db *database.Database) *ProjectService {
+	return &ProjectService{
+		db: db,
+	}
+}
+
+// GetProjects получает проекты пользователя; архивные — только если withArchived.
+func (s *ProjectService) GetProjects(userID int64, withArchived bool) ([]model.Project, error) {
+	projects, err := s.db.Projects.GetProjectsByUserID(userID, withArchived)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении проектов: %w", err)
+	}
+	return projects, nil
+}
+
+// GetProjectByID получает проект, только если он принадлежит пользователю.
+func (s *ProjectService) GetProjectByID(projectID, userID int64) (*model.Project, error) {
+	p, err := s.db.Projects.GetProjectByIDWithOwner(projectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении проекта: %w", err)
+	}
+	return &p, nil
+}
+
+// CreateProject создаёт новый проект для пользователя.
+func (s *ProjectService) CreateProject(p *model.Project, userID int64) error {
+	p.UserId = userID
+	if err := normalizeProject(p); err != nil {
+		return err
+	}
+	if err := s.db.Projects.CreateProject(p); err != nil {
+		return fmt.Errorf("ошибка при создании проекта: %w", err)
+	}
+	return nil
+}
+
+// UpdateProjectByIDWithCheck обновляет проект, только если он принадлежит пользователю.
+func (s *ProjectService) UpdateProjectByIDWithCheck(projectID, userID int64, p *model.Project) error {
+	if err := normalizeProject(p); err != nil {
+		return err
+	}
+	if err := s.db.Projects.UpdateProjectByIDWithOwner(projectID, p, userID); err != nil {
+		return fmt.Errorf("не удалось обновить проект: %w", err)
+	}
+	return nil
+}
+
+// SetProjectArchivedWithCheck архивирует проект или возвращает его из архива.
+// Задачи архивного проекта не показываются в общем списке задач.
+func (s *ProjectService) SetProjectArchivedWithCheck(projectID, userID int64, archived bool) error {
+	if err := s.db.Projects.SetProjectArchived(projectID, userID, archived); err != nil {
+		return fmt.Errorf("не удалось изменить архивный статус проекта: %w", err)
+	}
+	return nil
+}
+
+// DeleteProjectByIDWithCheck удаляет проект пользователя; его задачи остаются без проекта.
+func (s *ProjectService) DeleteProjectByIDWithCheck(projectID, userID int64) error {
+	if err := s.db.Projects.DeleteProjectByIDWithOwner(projectID, userID); err != nil {
+		return fmt.Errorf("не удалось удалить проект: %w", err)
+	}
+	return nil
+}
+
+// normalizeProject обрезает пробелы, подставляет цвет по умолчанию и проверяет значения.
+func normalizeProject(p *model.Project) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Color == "" {
+		p.Color = model.DefaultTagColor
+	}
+	if p.Name == "" || utf8.RuneCountInString(p.Name) > maxProjectNameLength || !model.ValidTagColor(p.Color) {
+		return ErrInvalidProject
+	}
+	return nil
+}
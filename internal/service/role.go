@@ -0,0 +1,204 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.mood/internal/database"
+	"go.mood/internal/model"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrInvalidRole возвращается при некорректном имени роли или неизвестном праве.
+	ErrInvalidRole = errors.New("некорректные параметры роли")
+	// ErrBuiltInRole возвращается при попытке изменить или удалить встроенную роль.
+	ErrBuiltInRole = errors.New("встроенные роли user и admin нельзя изменить или удалить")
+)
+
+// roleNamePattern — имя роли: латиница в нижнем регистре, цифры, "_" и "-", до 50 символов.
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+// RoleService — роли как наборы прав и проверка прав пользователей.
+type RoleService struct {
+	db *database.Database
+}
+
+// NewRoleService создаёт новый экземпляр RoleService.
+func NewRoleService(db *database.Database) *RoleService {
+	return &RoleService{
+		db: db,
+	}
+}
+
+// GetRoles возвращает все роли с их правами.
+func (s *RoleService) GetRoles() ([]model.RoleDefinition, error) {
+	roles, err := s.db.Roles.GetRoles()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении ролей: %w", err)
+	}
+	return roles, nil
+}
+
+// CreateRole создаёт роль с набором прав.
+func (s *RoleService) CreateRole(input *model.NewRole) (*model.RoleDefinition, error) {
+	name := strings.TrimSpace(input.Name)
+	if !roleNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: имя из латинских букв в нижнем регистре, цифр, _ и - (до 50 символов)", ErrInvalidRole)
+	}
+	perms, err := checkPermissions(input.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := model.RoleDefinition{
+		Name:        name,
+		Description: strings.TrimSpace(input.Description),
+		Permissions: perms,
+	}
+	if err := s.db.Roles.CreateRole(&role); err != nil {
+		if errors.Is(err, model.ErrRoleExists) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("ошибка при создании роли: %w", err)
+	}
+	return &role, nil
+}
+
+// UpdateRole заменяет описание и права роли. Изменения действуют сразу для всех
+// пользователей с этой ролью, в том числе с уже выданными токенами.
+func (s *RoleService) UpdateRole(name string, input *model.NewRole) (*model.RoleDefinition, error) {
+	if _, err := s.getCustomRole(name); err != nil {
+		return nil, err
+	}
+	perms, err := checkPermissions(input.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	description := strings.TrimSpace(input.Description)
+	if err := s.db.Roles.UpdateRole(name, description, perms); err != nil {
+		if errors.Is(err, model.ErrUnknownRole) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("ошибка при изменении роли: %w", err)
+	}
+	role, err := s.db.Roles.GetRole(name)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении роли: %w", err)
+	}
+	return &role, nil
+}
+
+// DeleteRole удаляет роль, которая не назначена ни одному пользователю.
+func (s *RoleService) DeleteRole(name string) error {
+	if _, err := s.getCustomRole(name); err != nil {
+		return err
+	}
+	if err := s.db.Roles.DeleteRole(name); err != nil {
+		if errors.Is(err, model.ErrUnknownRole) || errors.Is(err, model.ErrRoleInUse) {
+			return err
+		}
+		return fmt.Errorf("ошибка при удалении роли: %w", err)
+	}
+	return nil
+}
+
+// AssignRole назначает пользователю userID роль. Свою роль менять нельзя,
+// чтобы администратор случайно не лишил себя доступа.
+func (s *RoleService) AssignRole(userID int64, role string, currentUserID int64) error {
+	if userID == currentUserID {
+		return fmt.Errorf("%w: нельзя изменить собственную роль", ErrInvalidRole)
+	}
+	if _, err := s.db.Users.GetUserByID(userID); err != nil {
+		return fmt.Errorf("не удалось найти пользователя: %w", err)
+	}
+	if _, err := s.db.Roles.GetRole(role); err != nil {
+		if errors.Is(err, model.ErrUnknownRole) {
+			return err
+		}
+		return fmt.Errorf("ошибка при получении роли: %w", err)
+	}
+	if err := s.db.Users.UpdateRole(userID, role); err != nil {
+		if errors.Is(err, model.ErrUnknownRole) {
+			return err
+		}
+		return fmt.Errorf("ошибка при назначении роли: %w", err)
+	}
+	return nil
+}
+
+// HasPermission сообщает, есть ли право perm у текущей роли пользователя.
+func (s *RoleService) HasPermission(userID int64, perm model.Permission) (bool, error) {
+	perms, err := s.db.Roles.GetUserPermissions(userID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при получении прав пользователя: %w", err)
+	}
+	for _, p := range perms {
+		if p == perm {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isAdministrator сообщает, равносильна ли роль пользователя администратору
+// (см. model.RoleDefinition.Administrative).
+func isAdministrator(db *database.Database, user model.User) (bool, error) {
+	role, err := db.Roles.GetRole(user.Role)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при получении роли: %w", err)
+	}
+	return role.Administrative(), nil
+}
+
+// countAdministrators считает пользователей всех ролей, равносильных администратору,
+// включая пользовательские роли с правом roles.manage.
+func countAdministrators(db *database.Database) (int, error) {
+	roles, err := db.Roles.GetRoles()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при получении ролей: %w", err)
+	}
+	total := 0
+	for _, role := range roles {
+		if !role.Administrative() {
+			continue
+		}
+		count, err := db.Users.CountUsersByRole(role.Name)
+		if err != nil {
+			return 0, fmt.Errorf("ошибка при подсчёте администраторов: %w", err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// getCustomRole получает роль, которую можно менять: не встроенную.
+func (s *RoleService) getCustomRole(name string) (model.RoleDefinition, error) {
+	role, err := s.db.Roles.GetRole(name)
+	if err != nil {
+		if errors.Is(err, model.ErrUnknownRole) {
+			return role, err
+		}
+		return role, fmt.Errorf("ошибка при получении роли: %w", err)
+	}
+	if role.BuiltIn {
+		return role, ErrBuiltInRole
+	}
+	return role, nil
+}
+
+// checkPermissions проверяет, что все права известны, и убирает повторы.
+func checkPermissions(perms []model.Permission) ([]model.Permission, error) {
+	out := make([]model.Permission, 0, len(perms))
+	seen := make(map[model.Permission]bool, len(perms))
+	for _, p := range perms {
+		if !p.Valid() {
+			return nil, fmt.Errorf("%w: неизвестное право %q", ErrInvalidRole, p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.mood/internal/database"
+	"go.mood/internal/model"
+)
+
+// ErrInvalidRecurrence возвращается при некорректном правиле повторения задачи.
+var ErrInvalidRecurrence = errors.New("некорректное правило повторения")
+
+// SeriesService — сервис для работы с сериями повторяющихся задач.
+type SeriesService struct {
+	db *database.Database
+}
+
+// NewSeriesService создаёт новый экземпляр SeriesService.
+func NewSeriesService(db *database.Database) *SeriesService {
+	return &SeriesService{
+		db: db,
+	}
+}
+
+// GetSeriesByID получает серию, только если она принадлежит пользователю.
+func (s *SeriesService) GetSeriesByID(seriesID, userID int64) (*model.TaskSeries, error) {
+	series, err := s.db.Series.GetSeriesByIDWithOwner(seriesID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении серии: %w", err)
+	}
+	return &series, nil
+}
+
+// UpdateSeriesByIDWithCheck меняет правило серии пользователя и применяет текст
+// и приоритет ко всем её невыполненным задачам.
+func (s *SeriesService) UpdateSeriesByIDWithCheck(seriesID, userID int64, update model.SeriesUpdate) error {
+	if update.RRule != "" {
+		rule, err := parseRecurrence(update.RRule)
+		if err != nil {
+			return err
+		}
+		update.RRule = rule
+	}
+	if update.Priority != "" && !update.Priority.Valid() {
+		return ErrInvalidPriority
+	}
+	if err := s.db.Series.UpdateSeriesByIDWithOwner(seriesID, update, userID); err != nil {
+		return fmt.Errorf("не удалось обновить серию: %w", err)
+	}
+	return nil
+}
+
+// StopSeriesWithCheck останавливает серию пользователя: новые повторения больше не создаются.
+func (s *SeriesService) StopSeriesWithCheck(seriesID, userID int64) error {
+	if err := s.db.Series.StopSeries(seriesID, userID); err != nil {
+		return fmt.Errorf("не удалось остановить серию: %w", err)
+	}
+	return nil
+}
+
+// parseRecurrence проверяет правило и возвращает его в каноническом виде.
+func parseRecurrence(s string) (string, error) {
+	rule, err := model.ParseRecurrence(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	return rule.String(), nil
+}
@@ -2,18 +2,67 @@ package service
 
 import (
 	"go.mood/internal/database"
+	"go.mood/internal/jwtkeys"
+	"go.mood/internal/mailer"
+	"go.mood/internal/oidc"
+	"time"
 )
 
 // Service — структура, которая объединяет все сервисы приложения.
 type Service struct {
 	UserService
 	TaskService
+	TagService
+	ProjectService
+	SeriesService
+	TokenService
+	AccountService
+	MFAService
+	APIKeyService
+	RoleService
+	OIDCService
+	OAuthService
+}
+
+// Config — настройки сервисов, которые main собирает из окружения и config.yaml.
+type Config struct {
+	Keys            *jwtkeys.KeySet // ключи подписи JWT
+	AccessTokenTTL  time.Duration   // 0 — час
+	RefreshTokenTTL time.Duration   // 0 — 30 дней
+
+	Mailer                   mailer.Mailer
+	BaseURL                  string        // адрес API для ссылок в письмах и метаданных OAuth2
+	PasswordResetTTL         time.Duration // 0 — час
+	EmailVerificationTTL     time.Duration // 0 — двое суток
+	RequireEmailVerification bool          // запрещать вход до подтверждения email
+
+	MFAIssuer string // название сервиса в приложении-аутентификаторе
+
+	Lockout   LockoutPolicy  // защита входа от перебора
+	Passwords PasswordPolicy // требования к новым паролям
+	Hasher    PasswordHasher // алгоритм хеширования новых паролей; nil — Argon2id
+
+	OIDCProviders []oidc.Config // провайдеры входа OpenID Connect
 }
 
 // NewService создает и инициализирует все сервисы.
-func NewService(db *database.Database, jwtSecret string) *Service {
+func NewService(db *database.Database, cfg Config) *Service {
+	tokens := NewTokenService(db, cfg.Keys, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	guard := newLoginGuard(db, cfg.Lockout)
+	hashes := newPasswordHashes(cfg.Hasher)
+	passwords := newPasswordChecker(cfg.Passwords, hashes.current)
 	return &Service{
-		UserService: *NewUserService(db, jwtSecret),
-		TaskService: *NewTaskService(db),
+		UserService:    *NewUserService(db, tokens, guard, passwords, hashes, cfg.RequireEmailVerification),
+		TaskService:    *NewTaskService(db),
+		TagService:     *NewTagService(db),
+		ProjectService: *NewProjectService(db),
+		SeriesService:  *NewSeriesService(db),
+		TokenService:   *tokens,
+		AccountService: *NewAccountService(db, cfg.Mailer, passwords, hashes, cfg.BaseURL, cfg.PasswordResetTTL, cfg.EmailVerificationTTL),
+		MFAService:     *NewMFAService(db, tokens, guard, cfg.MFAIssuer),
+		APIKeyService:  *NewAPIKeyService(db),
+		RoleService:    *NewRoleService(db),
+		OIDCService:    *NewOIDCService(db, tokens, guard, cfg.OIDCProviders, cfg.RequireEmailVerification),
+		OAuthService:   *NewOAuthService(db, tokens, cfg.BaseURL),
 	}
 }
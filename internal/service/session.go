@@ -0,0 +1,70 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.mood/internal/model"
+	"time"
+)
+
+// ErrSessionTerminated возвращается для access-токена, сессия которого завершена
+// (выход, завершение с другого устройства, смена пароля).
+var ErrSessionTerminated = errors.New("сессия завершена: войдите заново")
+
+const (
+	// maxUserAgentLength — сколько символов User-Agent сохранять в сессии.
+	maxUserAgentLength = 512
+	// sessionTouchInterval — не чаще этого обновлять время последнего запроса в сессии,
+	// чтобы не писать в хранилище на каждый запрос.
+	sessionTouchInterval = time.Minute
+)
+
+// GetSessions возвращает действующие сессии пользователя: входы на устройствах и доступы
+// приложений OAuth2. currentSID — сессия запроса, она отмечается как текущая.
+func (s *TokenService) GetSessions(userID int64, currentSID string) ([]model.Session, error) {
+	sessions, err := s.db.Sessions.GetSessionsByUserID(userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении сессий: %w", err)
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Id == currentSID
+	}
+	return sessions, nil
+}
+
+// TerminateSession завершает сессию пользователя: её refresh-токены отзываются, а
+// access-токены перестают приниматься сразу. Чужая сессия — model.ErrUnknownSession.
+func (s *TokenService) TerminateSession(userID int64, sid string) error {
+	session, err := s.db.Sessions.GetSession(sid)
+	if err != nil {
+		return err
+	}
+	if session.UserId != userID {
+		return model.ErrUnknownSession
+	}
+	if err := s.db.Tokens.RevokeRefreshTokenFamily(sid, time.Now()); err != nil {
+		return fmt.Errorf("ошибка при завершении сессии: %w", err)
+	}
+	return nil
+}
+
+// CheckSession проверяет, что сессия access-токена не завершена, и запоминает время
+// последнего запроса в ней. Возвращает ErrSessionTerminated для завершённой или удалённой сессии.
+func (s *TokenService) CheckSession(sid string) error {
+	session, err := s.db.Sessions.GetSession(sid)
+	if errors.Is(err, model.ErrUnknownSession) {
+		return ErrSessionTerminated
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при получении сессии: %w", err)
+	}
+	if session.RevokedAt != nil {
+		return ErrSessionTerminated
+	}
+	if now := time.Now(); now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.db.Sessions.TouchSession(sid, now); err != nil {
+			return fmt.Errorf("ошибка при обновлении сессии: %w", err)
+		}
+	}
+	return nil
+}
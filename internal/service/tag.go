@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.mood/internal/database"
+	"go.mood/internal/model"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidTag возвращается при пустом или слишком длинном имени метки либо неверном цвете.
+var ErrInvalidTag = errors.New("имя метки обязательно (до 50 символов), цвет — в формате #RRGGBB")
+
+const maxTagNameLength = 50
+
+// TagService — сервис для работы с метками задач.
+type TagService struct {
+	db *database.Database
+}
+
+// NewTagService создаёт новый экземпляр TagService.
+func NewTagService(db *database.Database) *TagService {
+	return &TagService{
+		db: db,
+	}
+}
+
+// GetTags получает все метки пользователя.
+func (s *TagService) GetTags(userID int64) ([]model.Tag, error) {
+	tags, err := s.db.Tags.GetTagsByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении меток: %w", err)
+	}
+	return tags, nil
+}
+
+// CreateTag создаёт новую метку для пользователя.
+func (s *TagService) CreateTag(tag *model.Tag, userID int64) error {
+	tag.UserId = userID
+	if err := normalizeTag(tag); err != nil {
+		return err
+	}
+	if err := s.db.Tags.CreateTag(tag); err != nil {
+		return fmt.Errorf("ошибка при создании метки: %w", err)
+	}
+	return nil
+}
+
+// UpdateTagByIDWithCheck обновляет метку, только если она принадлежит пользователю.
+func (s *TagService) UpdateTagByIDWithCheck(tagID, userID int64, tag *model.Tag) error {
+	if err := normalizeTag(tag); err != nil {
+		return err
+	}
+	if err := s.db.Tags.UpdateTagByIDWithOwner(tagID, tag, userID); err != nil {
+		return fmt.Errorf("не удалось обновить метку: %w", err)
+	}
+	return nil
+}
+
+// DeleteTagByIDWithCheck удаляет метку, только если она принадлежит пользователю.
+// Метка отвязывается от всех задач.
+func (s *TagService) DeleteTagByIDWithCheck(tagID, userID int64) error {
+	if err := s.db.Tags.DeleteTagByIDWithOwner(tagID, userID); err != nil {
+		return fmt.Errorf("не удалось удалить метку: %w", err)
+	}
+	return nil
+}
+
+// normalizeTag обрезает пробелы в имени, подставляет цвет по умолчанию и проверяет значения.
+func normalizeTag(tag *model.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Color == "" {
+		tag.Color = model.DefaultTagColor
+	}
+	if tag.Name == "" || utf8.RuneCountInString(tag.Name) > maxTagNameLength || !model.ValidTagColor(tag.Color) {
+		return ErrInvalidTag
+	}
+	return nil
+}
@@ -2,10 +2,39 @@ package service
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"go.mood/internal/database"
 	"go.mood/internal/model"
+	"time"
+)
+
+// ErrInvalidPriority возвращается, если приоритет задачи не из списка допустимых.
+var ErrInvalidPriority = errors.New("недопустимый приоритет: ожидается low, normal, high или urgent")
+
+// Ошибки разбора параметров списка задач.
+var (
+	ErrInvalidSort   = errors.New("недопустимое поле сортировки")
+	ErrInvalidCursor = errors.New("некорректный курсор")
+)
+
+// Ошибки смены статуса задачи.
+var (
+	ErrInvalidStatus     = errors.New("недопустимый статус: ожидается pending, in_progress, blocked, done или cancelled")
+	ErrInvalidTransition = model.ErrInvalidTransition
+)
+
+// ErrEmptySearchQuery возвращается, если в поисковом запросе нет ни одного слова.
+var ErrEmptySearchQuery = errors.New("пустой поисковый запрос")
+
+const (
+	defaultTaskPageSize = 50
+	maxTaskPageSize     = 200
+
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
 )
 
 // TaskService — сервис для работы с задачами.
@@ -22,8 +51,8 @@ func NewTaskService(db *database.Database) *TaskService {
 
 // GetAllTasksByUserID получает все задачи для конкретного пользователя.
 func (s *TaskService) GetAllTasksByUserID(userID int64) ([]model.Task, error) {
-	//  Вызов метода из нового объекта TaskQueries
-	tasks, err := s.db.TaskQueries.GetTasksByUserID(userID)
+	// Вызов метода репозитория задач
+	tasks, err := s.db.Tasks.GetTasksByUserID(userID)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при получении задач: %w", err)
 	}
@@ -32,8 +61,8 @@ func (s *TaskService) GetAllTasksByUserID(userID int64) ([]model.Task, error) {
 
 // GetTaskByID получает задачу по ID, проверяя, принадлежит ли она пользователю.
 func (s *TaskService) GetTaskByID(taskID, userID int64) (*model.Task, error) {
-	// Вызов метода из нового объекта TaskQueries
-	task, err := s.db.TaskQueries.GetTaskByID(taskID)
+	// Вызов метода репозитория задач
+	task, err := s.db.Tasks.GetTaskByID(taskID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("задача с id %d не найдена", taskID)
@@ -43,21 +72,106 @@ func (s *TaskService) GetTaskByID(taskID, userID int64) (*model.Task, error) {
 	if task.UserId != userID {
 		return nil, errors.New("доступ запрещён")
 	}
-	return &task, nil
+	tasks := []model.Task{task}
+	if err := s.attachTags(tasks); err != nil {
+		return nil, err
+	}
+	if err := s.attachProgress(tasks); err != nil {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
+
+// GetTaskTree получает задачу пользователя вместе с деревом подзадач;
+// прогресс считается для каждой задачи дерева, у которой есть подзадачи.
+func (s *TaskService) GetTaskTree(taskID, userID int64) (*model.Task, error) {
+	root, err := s.GetTaskByID(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+	subtasks, err := s.db.Tasks.GetSubtasks(taskID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении подзадач: %w", err)
+	}
+	if err := s.attachTags(subtasks); err != nil {
+		return nil, err
+	}
+
+	children := make(map[int64][]model.Task)
+	for _, t := range subtasks {
+		children[*t.ParentID] = append(children[*t.ParentID], t)
+	}
+	var build func(t *model.Task)
+	build = func(t *model.Task) {
+		t.Subtasks = children[int64(t.Id)]
+		t.Progress = nil
+		// Отменённые подзадачи в прогрессе не учитываются, как и в GetProgressByTaskIDs
+		total, done := 0, 0
+		for i := range t.Subtasks {
+			build(&t.Subtasks[i])
+			switch t.Subtasks[i].Status {
+			case model.StatusCancelled:
+				continue
+			case model.StatusDone:
+				done++
+			}
+			total++
+		}
+		if len(t.Subtasks) > 0 {
+			t.Progress = model.NewProgress(total, done)
+		}
+	}
+	build(root)
+	return root, nil
+}
+
+// GetSubtasks получает прямые подзадачи задачи пользователя.
+func (s *TaskService) GetSubtasks(taskID, userID int64) ([]model.Task, error) {
+	tree, err := s.GetTaskTree(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Внуки доступны через /tasks/{id}/tree, здесь отдаём только первый уровень
+	subtasks := tree.Subtasks
+	for i := range subtasks {
+		subtasks[i].Subtasks = nil
+	}
+	return subtasks, nil
 }
 
 // CreateTask создаёт новую задачу для пользователя.
 func (s *TaskService) CreateTask(task *model.Task, userID int64) error {
 	task.UserId = userID
-	if err := s.db.TaskQueries.CreateTask(task); err != nil {
+	// Новая задача всегда начинает с pending, как и DEFAULT в БД
+	task.Status = model.StatusPending
+	if err := normalizePriority(task); err != nil {
+		return err
+	}
+	if err := normalizeRecurrence(task); err != nil {
+		return err
+	}
+	if err := s.checkParent(task, userID); err != nil {
+		return err
+	}
+	if err := s.checkProject(task.ProjectID, userID); err != nil {
+		return err
+	}
+	if err := s.db.Tasks.CreateTask(task); err != nil {
 		return fmt.Errorf("ошибка при создании задачи: %w", err)
 	}
 	return nil
 }
 
+// CreateSubtask создаёт подзадачу задачи parentID; задача должна принадлежать пользователю.
+func (s *TaskService) CreateSubtask(parentID int64, task *model.Task, userID int64) error {
+	task.ParentID = &parentID
+	return s.CreateTask(task, userID)
+}
+
 // DeleteTaskByIDWithCheck удаляет задачу, только если она принадлежит пользователю.
-func (s *TaskService) DeleteTaskByIDWithCheck(taskID, userID int64) error {
-	if err := s.db.TaskQueries.DeleteTaskByIDWithOwner(taskID, userID); err != nil {
+// С cascade удаляются и подзадачи, без него задача с подзадачами не удаляется.
+func (s *TaskService) DeleteTaskByIDWithCheck(taskID, userID int64, cascade bool) error {
+	if err := s.db.Tasks.DeleteTaskByIDWithOwner(taskID, userID, cascade); err != nil {
 		return fmt.Errorf("не удалось удалить задачу: %w", err)
 	}
 	return nil
@@ -65,16 +179,287 @@ func (s *TaskService) DeleteTaskByIDWithCheck(taskID, userID int64) error {
 
 // UpdateTaskByIDWithCheck обновляет задачу, только если она принадлежит пользователю.
 func (s *TaskService) UpdateTaskByIDWithCheck(taskID, userID int64, updatedTask *model.Task) error {
-	if err := s.db.TaskQueries.UpdateTaskByIDWithOwner(taskID, updatedTask, userID); err != nil {
+	if err := normalizePriority(updatedTask); err != nil {
+		return err
+	}
+	if err := s.db.Tasks.UpdateTaskByIDWithOwner(taskID, updatedTask, userID); err != nil {
 		return fmt.Errorf("не удалось обновить задачу: %w", err)
 	}
 	return nil
 }
 
-// UpdateTaskStatusByIDWithCheck обновляет статус задачи, только если она принадлежит пользователю.
-func (s *TaskService) UpdateTaskStatusByIDWithCheck(taskID, userID int64, status bool) error {
-	if err := s.db.TaskQueries.UpdateTaskStatus(taskID, userID, status); err != nil {
+// UpdateTaskStatusByIDWithCheck переводит задачу пользователя в статус status, если такой
+// переход разрешён. С cascade тот же статус получают подзадачи, для которых переход тоже разрешён.
+func (s *TaskService) UpdateTaskStatusByIDWithCheck(taskID, userID int64, status model.TaskStatus, cascade bool) error {
+	if !status.Valid() {
+		return ErrInvalidStatus
+	}
+	task, err := s.db.Tasks.GetTaskByID(taskID)
+	if err != nil || task.UserId != userID {
+		return errors.New("не удалось обновить статус задачи: задача не найдена или вы не владелец")
+	}
+	if !task.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s → %s", ErrInvalidTransition, task.Status, status)
+	}
+	// Хранилище проверяет переход ещё раз под блокировкой: статус мог смениться параллельным запросом
+	if err := s.db.Tasks.UpdateTaskStatus(taskID, userID, status, cascade); err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return err
+		}
 		return fmt.Errorf("не удалось обновить статус задачи: %w", err)
 	}
 	return nil
 }
+
+// MoveTaskToProject переносит задачу пользователя в его проект; nil убирает задачу из проекта.
+func (s *TaskService) MoveTaskToProject(taskID, userID int64, projectID *int64) error {
+	if err := s.checkProject(projectID, userID); err != nil {
+		return err
+	}
+	if err := s.db.Tasks.MoveTaskToProject(taskID, userID, projectID); err != nil {
+		return fmt.Errorf("не удалось перенести задачу: %w", err)
+	}
+	return nil
+}
+
+// GetTasksDueToday возвращает невыполненные задачи пользователя со сроком на сегодня.
+func (s *TaskService) GetTasksDueToday(userID int64) ([]model.Task, error) {
+	from := startOfDay(time.Now())
+	tasks, err := s.db.Tasks.GetTasksDueBetween(userID, from, from.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении задач на сегодня: %w", err)
+	}
+	return tasks, nil
+}
+
+// GetTasksDueThisWeek возвращает невыполненные задачи пользователя со сроком
+// на текущую неделю (с понедельника по воскресенье).
+func (s *TaskService) GetTasksDueThisWeek(userID int64) ([]model.Task, error) {
+	today := startOfDay(time.Now())
+	// В Go неделя начинается с воскресенья (0), поэтому сдвигаем на понедельник.
+	offset := (int(today.Weekday()) + 6) % 7
+	from := today.AddDate(0, 0, -offset)
+	tasks, err := s.db.Tasks.GetTasksDueBetween(userID, from, from.AddDate(0, 0, 7))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении задач на неделю: %w", err)
+	}
+	return tasks, nil
+}
+
+// GetOverdueTasks возвращает невыполненные задачи пользователя с истёкшим сроком.
+func (s *TaskService) GetOverdueTasks(userID int64) ([]model.Task, error) {
+	tasks, err := s.db.Tasks.GetOverdueTasks(userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении просроченных задач: %w", err)
+	}
+	return tasks, nil
+}
+
+// ListTasks возвращает страницу задач пользователя по фильтру. cursor — значение
+// next_cursor с предыдущей страницы или пустая строка для первой страницы.
+func (s *TaskService) ListTasks(userID int64, filter model.TaskFilter, cursor string) (*model.TaskPage, error) {
+	filter.UserID = userID
+	if filter.SortBy == "" {
+		filter.SortBy = "id"
+	}
+	if !model.ValidTaskSortField(filter.SortBy) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSort, filter.SortBy)
+	}
+	if filter.Priority != "" && !filter.Priority.Valid() {
+		return nil, ErrInvalidPriority
+	}
+	if filter.Status != "" && !filter.Status.Valid() {
+		return nil, ErrInvalidStatus
+	}
+	if err := s.checkProject(filter.ProjectID, userID); err != nil {
+		return nil, err
+	}
+	if filter.SeriesID != nil {
+		if _, err := s.db.Series.GetSeriesByIDWithOwner(*filter.SeriesID, userID); err != nil {
+			return nil, err
+		}
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultTaskPageSize
+	}
+	if filter.Limit > maxTaskPageSize {
+		filter.Limit = maxTaskPageSize
+	}
+
+	if cursor != "" {
+		after, err := decodeTaskCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		// Курсор действителен только для того же порядка сортировки
+		if after.SortBy != filter.SortBy || after.Desc != filter.Desc {
+			return nil, fmt.Errorf("%w: курсор выдан для другой сортировки", ErrInvalidCursor)
+		}
+		filter.After = after
+	}
+
+	// Запрашиваем на одну задачу больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+	tasks, err := s.db.Tasks.ListTasks(filter)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении задач: %w", err)
+	}
+
+	if err := s.attachTags(tasks); err != nil {
+		return nil, err
+	}
+	if err := s.attachProgress(tasks); err != nil {
+		return nil, err
+	}
+
+	page := &model.TaskPage{Tasks: tasks}
+	if len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		last := page.Tasks[limit-1]
+		page.NextCursor = encodeTaskCursor(&model.TaskCursor{
+			SortBy: filter.SortBy,
+			Desc:   filter.Desc,
+			Value:  last.SortKey(filter.SortBy),
+			ID:     last.Id,
+		})
+	}
+	return page, nil
+}
+
+// SearchTasks ищет задачи пользователя по тексту и возвращает их по убыванию релевантности.
+func (s *TaskService) SearchTasks(userID int64, q string, limit int) ([]model.TaskSearchResult, error) {
+	query := model.ParseSearchQuery(q)
+	if query.Empty() {
+		return nil, ErrEmptySearchQuery
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	results, err := s.db.Tasks.SearchTasks(userID, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске задач: %w", err)
+	}
+	return results, nil
+}
+
+// checkProject проверяет, что проект принадлежит пользователю; nil — «без проекта».
+func (s *TaskService) checkProject(projectID *int64, userID int64) error {
+	if projectID == nil {
+		return nil
+	}
+	if _, err := s.db.Projects.GetProjectByIDWithOwner(*projectID, userID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkParent проверяет, что родительская задача принадлежит пользователю.
+// Подзадача без явного проекта попадает в проект родителя.
+func (s *TaskService) checkParent(task *model.Task, userID int64) error {
+	if task.ParentID == nil {
+		return nil
+	}
+	parent, err := s.db.Tasks.GetTaskByID(*task.ParentID)
+	if err != nil || parent.UserId != userID {
+		return model.ErrUnknownParentTask
+	}
+	if task.ProjectID == nil {
+		task.ProjectID = parent.ProjectID
+	}
+	return nil
+}
+
+// attachProgress заполняет Progress у задач, у которых есть подзадачи.
+func (s *TaskService) attachProgress(tasks []model.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(tasks))
+	for i, t := range tasks {
+		ids[i] = int64(t.Id)
+	}
+	progress, err := s.db.Tasks.GetProgressByTaskIDs(ids)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении прогресса задач: %w", err)
+	}
+	for i := range tasks {
+		if p, ok := progress[int64(tasks[i].Id)]; ok {
+			tasks[i].Progress = &p
+		}
+	}
+	return nil
+}
+
+// attachTags заполняет Tags у задач одним запросом к хранилищу.
+func (s *TaskService) attachTags(tasks []model.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(tasks))
+	for i, t := range tasks {
+		ids[i] = int64(t.Id)
+	}
+	tags, err := s.db.Tags.GetTagsByTaskIDs(ids)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении меток задач: %w", err)
+	}
+	for i := range tasks {
+		tasks[i].Tags = tags[int64(tasks[i].Id)]
+	}
+	return nil
+}
+
+func encodeTaskCursor(c *model.TaskCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTaskCursor(s string) (*model.TaskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c model.TaskCursor
+	if err := json.Unmarshal(data, &c); err != nil || !model.ValidTaskSortField(c.SortBy) || !model.ValidTaskSortKey(c.SortBy, c.Value) {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// normalizeRecurrence приводит правило повторения к каноническому виду.
+// Повторения отсчитываются от срока задачи, поэтому без due_at правило не принимается.
+func normalizeRecurrence(task *model.Task) error {
+	if task.Recurrence == "" {
+		return nil
+	}
+	if task.DueAt == nil {
+		return fmt.Errorf("%w: для повторяющейся задачи нужен срок due_at", ErrInvalidRecurrence)
+	}
+	rule, err := parseRecurrence(task.Recurrence)
+	if err != nil {
+		return err
+	}
+	task.Recurrence = rule
+	return nil
+}
+
+// normalizePriority подставляет приоритет по умолчанию и проверяет допустимость значения.
+func normalizePriority(task *model.Task) error {
+	if task.Priority == "" {
+		task.Priority = model.PriorityNormal
+	}
+	if !task.Priority.Valid() {
+		return ErrInvalidPriority
+	}
+	return nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
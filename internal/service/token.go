@@ -0,0 +1,398 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mood/internal/database"
+	"go.mood/internal/jwtkeys"
+	"go.mood/internal/model"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrInvalidRefreshToken возвращается, если refresh-токен не найден, истёк или принадлежит другому пользователю.
+	ErrInvalidRefreshToken = errors.New("неверный или просроченный refresh-токен")
+	// ErrInvalidMFAToken возвращается для неверного или просроченного токена MFA-проверки.
+	ErrInvalidMFAToken = errors.New("неверный или просроченный mfa_token: войдите заново")
+)
+
+const (
+	defaultAccessTokenTTL  = time.Hour
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	// mfaChallengeTTL — сколько действует токен, выданный после пароля до ввода кода 2FA.
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaChallengeType — значение claim typ у токена MFA-проверки; access-токены его не содержат.
+	mfaChallengeType = "mfa_challenge"
+
+	// impersonationTTL — сколько действует токен входа администратора от имени пользователя.
+	impersonationTTL = 15 * time.Minute
+)
+
+// TokenService — выдача, обновление и отзыв токенов доступа.
+type TokenService struct {
+	db         *database.Database
+	keys       *jwtkeys.KeySet
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewTokenService создаёт новый экземпляр TokenService. Нулевые сроки жизни
+// заменяются значениями по умолчанию: час для access- и 30 дней для refresh-токена.
+// Токены подписываются активным ключом из keys.
+func NewTokenService(db *database.Database, keys *jwtkeys.KeySet, accessTTL, refreshTTL time.Duration) *TokenService {
+	if accessTTL <= 0 {
+		accessTTL = defaultAccessTokenTTL
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = defaultRefreshTokenTTL
+	}
+	return &TokenService{
+		db:         db,
+		keys:       keys,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// IssueTokens начинает сессию на устройстве device: выдаёт пользователю access-токен
+// и refresh-токен новой цепочки. mfa — пользователь прошёл проверку второго фактора
+// (claim "mfa" в access-токене).
+func (s *TokenService) IssueTokens(user model.User, mfa bool, device model.DeviceInfo) (*model.TokenPair, error) {
+	familyID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.startFamily(user, familyID, mfa, nil, device)
+}
+
+// startFamily начинает сессию familyID: выдаёт access-токен и первый refresh-токен цепочки.
+// grant — приложение OAuth2 и выданные ему права; nil — своя сессия пользователя.
+func (s *TokenService) startFamily(user model.User, familyID string, mfa bool, grant *model.OAuthGrant, device model.DeviceInfo) (*model.TokenPair, error) {
+	pair, refresh, err := s.newTokenPair(user, familyID, mfa, grant)
+	if err != nil {
+		return nil, err
+	}
+	session := &model.Session{
+		Id:         familyID,
+		UserId:     refresh.UserId,
+		ClientID:   refresh.ClientID,
+		UserAgent:  truncateRunes(device.UserAgent, maxUserAgentLength),
+		IP:         device.IP,
+		CreatedAt:  refresh.CreatedAt,
+		LastSeenAt: refresh.CreatedAt,
+		ExpiresAt:  refresh.ExpiresAt,
+	}
+	if err := s.db.Sessions.CreateSession(session, refresh); err != nil {
+		return nil, fmt.Errorf("ошибка при сохранении сессии: %w", err)
+	}
+	return pair, nil
+}
+
+// RefreshTokens обменивает refresh-токен на новую пару токенов; старый refresh-токен
+// становится недействительным. Повторное использование уже обменянного токена
+// считается кражей: вся цепочка отзывается и возвращается model.ErrRefreshTokenReused.
+// Refresh-токены приложений OAuth2 здесь не принимаются — только в OAuthService.
+func (s *TokenService) RefreshTokens(rawRefreshToken string) (*model.TokenPair, error) {
+	pair, _, err := s.rotateRefreshToken(rawRefreshToken, "", nil)
+	return pair, err
+}
+
+// rotateRefreshToken обменивает refresh-токен на новую пару. clientID — приложение,
+// которому должен принадлежать токен (пусто — своя сессия пользователя); scopes сужают
+// права приложения, пусто — прежние права. Возвращает права новой пары.
+func (s *TokenService) rotateRefreshToken(rawRefreshToken, clientID string, scopes []model.Scope) (*model.TokenPair, []model.Scope, error) {
+	now := time.Now()
+	old, err := s.db.Tokens.GetRefreshTokenByHash(hashToken(rawRefreshToken))
+	if err != nil {
+		if errors.Is(err, model.ErrUnknownRefreshToken) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, fmt.Errorf("ошибка при получении refresh-токена: %w", err)
+	}
+	if old.ClientID != clientID {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if old.RevokedAt != nil {
+		// Цепочка завершённой сессии (выход, завершение с другого устройства) — это не кража
+		if session, err := s.db.Sessions.GetSession(old.FamilyID); err == nil && session.RevokedAt != nil {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, s.revokeReusedFamily(old.FamilyID, now)
+	}
+	if now.After(old.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	// Роль могла измениться с момента входа, поэтому берём пользователя заново
+	user, err := s.db.Users.GetUserByID(old.UserId)
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	var grant *model.OAuthGrant
+	mfa := false
+	if clientID != "" {
+		if len(scopes) == 0 {
+			scopes = old.Scopes
+		}
+		for _, scope := range scopes {
+			if !(model.OAuthGrant{Scopes: old.Scopes}).Allows(scope) {
+				return nil, nil, fmt.Errorf("%w: право %s не выдавалось приложению", ErrInvalidScope, scope)
+			}
+		}
+		grant = &model.OAuthGrant{ClientID: clientID, Scopes: scopes}
+	} else {
+		// При подключении 2FA все прежние цепочки отзываются, поэтому цепочка
+		// пользователя с 2FA всегда начата со входа с проверкой кода
+		if mfa, err = s.mfaEnabled(old.UserId); err != nil {
+			return nil, nil, err
+		}
+	}
+	pair, next, err := s.newTokenPair(user, old.FamilyID, mfa, grant)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.db.Tokens.RotateRefreshToken(old.Id, next, now); err != nil {
+		if errors.Is(err, model.ErrRefreshTokenReused) {
+			// Токен успели обменять параллельным запросом
+			return nil, nil, s.revokeReusedFamily(old.FamilyID, now)
+		}
+		return nil, nil, fmt.Errorf("ошибка при обновлении refresh-токена: %w", err)
+	}
+	return pair, scopes, nil
+}
+
+// Logout отзывает текущий access-токен (jti) и завершает текущую сессию sid. Если передан
+// refresh-токен пользователя, отзывается и его цепочка (нужно для токенов, выданных до
+// появления сессий); с all — завершаются все сессии пользователя на всех устройствах.
+func (s *TokenService) Logout(userID int64, jti, sid, rawRefreshToken string, all bool) error {
+	now := time.Now()
+	if jti != "" {
+		if err := s.db.Tokens.RevokeAccessToken(jti, now.Add(s.accessTTL), now); err != nil {
+			return fmt.Errorf("ошибка при отзыве токена: %w", err)
+		}
+	}
+	if all {
+		if err := s.db.Tokens.RevokeRefreshTokensByUserID(userID, now); err != nil {
+			return fmt.Errorf("ошибка при отзыве refresh-токенов: %w", err)
+		}
+		return nil
+	}
+	if sid != "" {
+		if err := s.db.Tokens.RevokeRefreshTokenFamily(sid, now); err != nil {
+			return fmt.Errorf("ошибка при завершении сессии: %w", err)
+		}
+	}
+	if rawRefreshToken == "" {
+		return nil
+	}
+	refresh, err := s.db.Tokens.GetRefreshTokenByHash(hashToken(rawRefreshToken))
+	if err != nil || refresh.UserId != userID {
+		return ErrInvalidRefreshToken
+	}
+	if err := s.db.Tokens.RevokeRefreshTokenFamily(refresh.FamilyID, now); err != nil {
+		return fmt.Errorf("ошибка при отзыве refresh-токенов: %w", err)
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked сообщает, отозван ли access-токен с данным jti.
+func (s *TokenService) IsAccessTokenRevoked(jti string) (bool, error) {
+	return s.db.Tokens.IsAccessTokenRevoked(jti)
+}
+
+// VerifyJWT проверяет подпись и срок действия JWT, выданного сервисом, и возвращает его claims.
+// Подходят и токены, подписанные выведенными ключами, пока те ещё принимаются.
+func (s *TokenService) VerifyJWT(token string) (jwt.MapClaims, error) {
+	return s.keys.Parse(token)
+}
+
+// JWKS возвращает открытые ключи для проверки токенов другими сервисами.
+func (s *TokenService) JWKS() jwtkeys.JWKS {
+	return s.keys.JWKS()
+}
+
+// IssueImpersonationToken выдаёт администратору actorID access-токен пользователя user
+// с claim act (RFC 8693) — кто на самом деле выполняет запросы. Токен не продлевается
+// и не несёт claim mfa, поэтому права ролей пользователя с ним не действуют.
+func (s *TokenService) IssueImpersonationToken(user model.User, actorID int64) (*model.ImpersonationToken, error) {
+	if user.Suspended() {
+		return nil, ErrAccountSuspended
+	}
+	now := time.Now()
+	jti, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := s.keys.Sign(jwt.MapClaims{
+		"user_id": user.Id,
+		"role":    user.Role,
+		"act":     map[string]string{"sub": strconv.FormatInt(actorID, 10)},
+		"jti":     jti,
+		"iat":     now.Unix(),
+		"exp":     now.Add(impersonationTTL).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подписать токен: %w", err)
+	}
+	return &model.ImpersonationToken{
+		AccessToken: signed,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(impersonationTTL.Seconds()),
+		UserID:      int64(user.Id),
+		ActorID:     actorID,
+	}, nil
+}
+
+// IssueMFAChallenge выдаёт короткоживущий токен, который вместе с кодом 2FA
+// обменивается на токены доступа. Как access-токен он не принимается.
+func (s *TokenService) IssueMFAChallenge(user model.User) (*model.MFAChallenge, error) {
+	now := time.Now()
+	signed, err := s.keys.Sign(jwt.MapClaims{
+		"sub": strconv.Itoa(user.Id),
+		"typ": mfaChallengeType,
+		"iat": now.Unix(),
+		"exp": now.Add(mfaChallengeTTL).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подписать токен: %w", err)
+	}
+	return &model.MFAChallenge{
+		MFARequired: true,
+		MFAToken:    signed,
+		ExpiresIn:   int64(mfaChallengeTTL.Seconds()),
+	}, nil
+}
+
+// ParseMFAChallenge проверяет токен MFA-проверки и возвращает id пользователя.
+func (s *TokenService) ParseMFAChallenge(challenge string) (int64, error) {
+	claims, err := s.keys.Parse(challenge)
+	if err != nil || claims["typ"] != mfaChallengeType {
+		return 0, ErrInvalidMFAToken
+	}
+	sub, _ := claims["sub"].(string)
+	userID, err := strconv.ParseInt(sub, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidMFAToken
+	}
+	return userID, nil
+}
+
+// newTokenPair подписывает access-токен сессии familyID (claim sid) и готовит к сохранению
+// следующий refresh-токен её цепочки.
+// С grant токены выдаются приложению OAuth2: в access-токене появляются claims client_id
+// и scope, по которым AuthMiddleware ограничивает доступ. Приостановленным пользователям
+// токены не выдаются.
+func (s *TokenService) newTokenPair(user model.User, familyID string, mfa bool, grant *model.OAuthGrant) (*model.TokenPair, *model.RefreshToken, error) {
+	if user.Suspended() {
+		return nil, nil, ErrAccountSuspended
+	}
+	now := time.Now()
+	jti, err := randomHex(16)
+	if err != nil {
+		return nil, nil, err
+	}
+	accessExp := now.Add(s.accessTTL)
+	claims := jwt.MapClaims{
+		"user_id": user.Id,
+		"role":    user.Role,
+		"jti":     jti,
+		"sid":     familyID,
+		"iat":     now.Unix(),
+		"exp":     accessExp.Unix(),
+	}
+	if mfa {
+		claims["mfa"] = true
+	}
+	if grant != nil {
+		claims["client_id"] = grant.ClientID
+		claims["scope"] = model.JoinScopes(grant.Scopes)
+	}
+	signedToken, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось подписать токен: %w", err)
+	}
+
+	rawRefresh, err := randomToken(32)
+	if err != nil {
+		return nil, nil, err
+	}
+	refresh := &model.RefreshToken{
+		UserId:          int64(user.Id),
+		TokenHash:       hashToken(rawRefresh),
+		FamilyID:        familyID,
+		AccessJTI:       jti,
+		AccessExpiresAt: accessExp,
+		ExpiresAt:       now.Add(s.refreshTTL),
+		CreatedAt:       now,
+	}
+	if grant != nil {
+		refresh.ClientID = grant.ClientID
+		refresh.Scopes = grant.Scopes
+	}
+	pair := &model.TokenPair{
+		AccessToken:  signedToken,
+		RefreshToken: rawRefresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+	}
+	return pair, refresh, nil
+}
+
+// mfaEnabled сообщает, подключена ли у пользователя двухфакторная аутентификация.
+func (s *TokenService) mfaEnabled(userID int64) (bool, error) {
+	m, err := s.db.MFA.GetMFA(userID)
+	if errors.Is(err, model.ErrMFANotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ошибка при получении настроек 2FA: %w", err)
+	}
+	return m.Enabled(), nil
+}
+
+func (s *TokenService) revokeReusedFamily(familyID string, now time.Time) error {
+	if err := s.db.Tokens.RevokeRefreshTokenFamily(familyID, now); err != nil {
+		return fmt.Errorf("ошибка при отзыве цепочки refresh-токенов: %w", err)
+	}
+	return model.ErrRefreshTokenReused
+}
+
+// randomToken возвращает n случайных байт в base64url без выравнивания.
+func randomToken(n int) (string, error) {
+	b, err := randomBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// randomHex возвращает n случайных байт в шестнадцатеричном виде.
+func randomHex(n int) (string, error) {
+	b, err := randomBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("ошибка генерации случайных данных: %w", err)
+	}
+	return b, nil
+}
+
+// hashToken возвращает SHA-256 от токена в шестнадцатеричном виде — так токены хранятся в БД.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,78 +1,254 @@
 package service
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
-	"github.com/golang-jwt/jwt/v5"
 	"go.mood/internal/database"
 	"go.mood/internal/model"
-	"golang.org/x/crypto/bcrypt"
+	"strconv"
+	"strings"
 	"time"
 )
 
+var (
+	// ErrInvalidCredentials возвращается при входе с неизвестным именем пользователя или неверным паролем.
+	ErrInvalidCredentials = errors.New("неверные учетные данные")
+	// ErrAccountSuspended возвращается при входе и запросах пользователя, аккаунт которого приостановлен.
+	ErrAccountSuspended = errors.New("аккаунт приостановлен администратором")
+	// ErrSuspendSelf возвращается при попытке администратора приостановить собственный аккаунт.
+	ErrSuspendSelf = errors.New("нельзя приостановить собственный аккаунт")
+	// ErrSuspendAdmin возвращается при попытке приостановить аккаунт администратора.
+	ErrSuspendAdmin = errors.New("нельзя приостановить аккаунт администратора")
+	// ErrImpersonateSelf возвращается при попытке войти от имени самого себя.
+	ErrImpersonateSelf = errors.New("нельзя войти от имени самого себя")
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
 // UserService — сервис для работы с пользователями.
 type UserService struct {
-	db        *database.Database
-	jwtSecret []byte
+	db                   *database.Database
+	tokens               *TokenService
+	guard                *loginGuard
+	passwords            *passwordChecker
+	hashes               *passwordHashes
+	requireVerifiedEmail bool
 }
 
-// NewUserService создаёт новый экземпляр UserService.
-func NewUserService(db *database.Database, jwtSecret string) *UserService {
+// NewUserService создаёт новый экземпляр UserService. С requireVerifiedEmail
+// пользователи с неподтверждённым email не могут войти.
+func NewUserService(db *database.Database, tokens *TokenService, guard *loginGuard, passwords *passwordChecker, hashes *passwordHashes, requireVerifiedEmail bool) *UserService {
 	return &UserService{
-		db:        db,
-		jwtSecret: []byte(jwtSecret),
+		db:                   db,
+		tokens:               tokens,
+		guard:                guard,
+		passwords:            passwords,
+		hashes:               hashes,
+		requireVerifiedEmail: requireVerifiedEmail,
 	}
 }
 
-// GetAllUsers получает всех пользователей.
-func (s *UserService) GetAllUsers() ([]model.User, error) {
-	users, err := s.db.UserQueries.GetAllUsers()
+// ListUsers возвращает страницу пользователей по фильтру в порядке id.
+// cursor — next_cursor предыдущей страницы.
+func (s *UserService) ListUsers(filter model.UserFilter, cursor string) (*model.UserPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultUserPageSize
+	}
+	if filter.Limit > maxUserPageSize {
+		filter.Limit = maxUserPageSize
+	}
+	if cursor != "" {
+		afterID, err := decodeUserCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.AfterID = afterID
+	}
+
+	// Запрашиваем на одного пользователя больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+	users, err := s.db.Users.ListUsers(filter)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при получении списка пользователей: %w", err)
 	}
-	return users, nil
+
+	page := &model.UserPage{Users: make([]model.UserView, 0, len(users))}
+	if len(users) > limit {
+		users = users[:limit]
+		page.NextCursor = encodeUserCursor(users[limit-1].Id)
+	}
+	for _, u := range users {
+		page.Users = append(page.Users, u.View())
+	}
+	return page, nil
+}
+
+// GetUser возвращает пользователя по id.
+func (s *UserService) GetUser(id int64) (*model.UserView, error) {
+	user, err := s.db.Users.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	view := user.View()
+	return &view, nil
+}
+
+// SuspendUser приостанавливает аккаунт: пользователь не может войти, а его токены,
+// refresh-токены и API-ключи перестают приниматься. Данные пользователя сохраняются.
+// Администраторов (роль с правом roles.manage) приостановить нельзя, как и удалить:
+// иначе роль с одним правом users.suspend могла бы отключить всех администраторов.
+func (s *UserService) SuspendUser(id, currentUserID int64) error {
+	if id == currentUserID {
+		return ErrSuspendSelf
+	}
+	user, err := s.db.Users.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	if user.Suspended() {
+		return nil
+	}
+	admin, err := isAdministrator(s.db, user)
+	if err != nil {
+		return err
+	}
+	if admin {
+		return ErrSuspendAdmin
+	}
+
+	now := time.Now()
+	if err := s.db.Users.SuspendUser(id, now); err != nil {
+		return fmt.Errorf("не удалось приостановить пользователя: %w", err)
+	}
+	if err := s.db.Tokens.RevokeRefreshTokensByUserID(id, now); err != nil {
+		return fmt.Errorf("ошибка при отзыве токенов: %w", err)
+	}
+	return nil
 }
 
-// DeleteUserByIDWithCheck удаляет пользователя, но с проверкой, не является ли он админом.
+// ReactivateUser снимает приостановку аккаунта; войти пользователю нужно заново.
+func (s *UserService) ReactivateUser(id int64) error {
+	if _, err := s.db.Users.GetUserByID(id); err != nil {
+		return err
+	}
+	if err := s.db.Users.ReactivateUser(id); err != nil {
+		return fmt.Errorf("не удалось восстановить пользователя: %w", err)
+	}
+	return nil
+}
+
+// Impersonate выдаёт администратору actorID токен для входа от имени пользователя id.
+func (s *UserService) Impersonate(id, actorID int64) (*model.ImpersonationToken, error) {
+	if id == actorID {
+		return nil, ErrImpersonateSelf
+	}
+	user, err := s.db.Users.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokens.IssueImpersonationToken(user, actorID)
+}
+
+// CheckAccount проверяет при каждом запросе с токеном, что аккаунт не удалён
+// (model.ErrUnknownUser) и не приостановлен (ErrAccountSuspended).
+func (s *UserService) CheckAccount(userID int64) error {
+	user, err := s.db.Users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.Suspended() {
+		return ErrAccountSuspended
+	}
+	return nil
+}
+
+// DeleteUserByIDWithCheck удаляет пользователя, но с проверкой, не является ли он админом:
+// администратором считается любой, чья роль даёт право roles.manage.
 func (s *UserService) DeleteUserByIDWithCheck(idToDelete, userID int64) error {
 	if idToDelete == userID {
 		return errors.New("нельзя удалить собственный аккаунт")
 	}
 
-	userToDelete, err := s.db.UserQueries.GetUserByID(idToDelete)
+	userToDelete, err := s.db.Users.GetUserByID(idToDelete)
 	if err != nil {
 		return fmt.Errorf("не удалось найти пользователя для удаления: %w", err)
 	}
 
-	if userToDelete.Role == "admin" {
+	admin, err := isAdministrator(s.db, userToDelete)
+	if err != nil {
+		return err
+	}
+	if admin {
 		return errors.New("нельзя удалить другого администратора")
 	}
 
-	if err := s.db.UserQueries.DeleteUserByID(idToDelete); err != nil {
+	if err := s.db.Users.DeleteUserByID(idToDelete); err != nil {
 		return fmt.Errorf("не удалось удалить пользователя: %w", err)
 	}
 	return nil
 }
 
-// RegisterUser регистрирует нового пользователя.
+// UnlockUser снимает блокировку входа, наложенную после неудачных попыток.
+func (s *UserService) UnlockUser(id int64) error {
+	if _, err := s.db.Users.GetUserByID(id); err != nil {
+		return fmt.Errorf("не удалось найти пользователя: %w", err)
+	}
+	if err := s.db.Users.UnlockUser(id); err != nil {
+		return fmt.Errorf("не удалось разблокировать пользователя: %w", err)
+	}
+	return nil
+}
+
+// RegisterUser регистрирует нового пользователя. Неверные поля возвращаются
+// одной *ValidationError: формат имени и email, их занятость и требования к паролю.
 func (s *UserService) RegisterUser(input *model.NewUser) (*model.User, error) {
-	if input.Username == "" || input.Password == "" {
-		return nil, errors.New("username и password обязательны")
+	input.Username = strings.TrimSpace(input.Username)
+	input.Email = strings.TrimSpace(input.Email)
+
+	var invalid ValidationError
+	invalid.add("username", usernameProblem(input.Username))
+	invalid.add("email", emailProblem(input.Email))
+	if !invalid.has("username") {
+		if _, err := s.db.Users.GetUserByUsername(input.Username); err == nil {
+			invalid.add("username", ErrUsernameTaken.Error())
+		}
+	}
+	if !invalid.has("email") {
+		if _, err := s.db.Users.GetUserByEmail(input.Email); err == nil {
+			invalid.add("email", ErrEmailTaken.Error())
+		}
+	}
+	reason, err := s.passwords.check(input.Password, input.Username, input.Email)
+	if err != nil {
+		return nil, err
+	}
+	invalid.add("password", reason)
+	if err := invalid.err(); err != nil {
+		return nil, err
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	hash, err := s.hashes.hash(input.Password)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка хеширования пароля: %w", err)
+		return nil, err
 	}
 
 	user := model.User{
 		Username:     input.Username,
 		Email:        input.Email,
-		PasswordHash: string(hash),
-		Role:         "user",
+		PasswordHash: hash,
+		Role:         string(model.RoleUser),
 	}
 
-	if err := s.db.UserQueries.CreateUser(&user); err != nil {
+	if err := s.db.Users.CreateUser(&user); err != nil {
+		// Email мог занять другой запрос между проверкой и вставкой
+		if errors.Is(err, model.ErrEmailTaken) {
+			return nil, &ValidationError{Fields: map[string]string{"email": ErrEmailTaken.Error()}}
+		}
 		return nil, fmt.Errorf("ошибка при создании пользователя: %w", err)
 	}
 
@@ -80,27 +256,87 @@ func (s *UserService) RegisterUser(input *model.NewUser) (*model.User, error) {
 	return &user, nil
 }
 
-// LoginUser аутентифицирует пользователя и возвращает JWT.
-func (s *UserService) LoginUser(username, password string) (string, error) {
-	user, err := s.db.UserQueries.GetUserByUsername(username)
+// LoginUser аутентифицирует пользователя и возвращает access- и refresh-токены новой
+// сессии на устройстве device (её id — claim sid в access-токене).
+// Если у пользователя подключена 2FA, токены не выдаются: возвращается MFAChallenge,
+// который вместе с кодом обменивается на токены через MFAService.VerifyLogin.
+// Неудачные попытки считаются по пользователю и по ip клиента; при блокировке
+// возвращается *LockedError, и пароль не проверяется. Хеш, полученный не текущим
+// алгоритмом или с устаревшими параметрами, после успешной проверки пересчитывается.
+func (s *UserService) LoginUser(username, password string, device model.DeviceInfo) (*model.TokenPair, *model.MFAChallenge, error) {
+	ip := device.IP
+	if err := s.guard.checkIP(ip); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.db.Users.GetUserByUsername(username)
 	if err != nil {
-		return "", errors.New("неверные учетные данные")
+		s.hashes.verifyDummy(password)
+		s.guard.failIP(ip)
+		return nil, nil, ErrInvalidCredentials
+	}
+	if err := s.guard.checkUser(user); err != nil {
+		s.guard.failIP(ip)
+		return nil, nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return "", errors.New("неверные учетные данные")
+	ok, rehash := s.hashes.verify(password, user.PasswordHash)
+	if !ok {
+		s.guard.failIP(ip)
+		if err := s.guard.failUser(int64(user.Id)); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidCredentials
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.Id,
-		"role":    user.Role,
-		"exp":     time.Now().Add(1 * time.Hour).Unix(),
-	})
+	if user.Suspended() {
+		return nil, nil, ErrAccountSuspended
+	}
+	if s.requireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return nil, nil, ErrEmailNotVerified
+	}
+	// Пароль в открытом виде есть только сейчас — другого случая обновить хеш не будет
+	if rehash {
+		hash, err := s.hashes.hash(password)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.db.Users.UpdatePassword(int64(user.Id), hash); err != nil {
+			return nil, nil, fmt.Errorf("ошибка при обновлении хеша пароля: %w", err)
+		}
+	}
 
-	signedToken, err := token.SignedString(s.jwtSecret)
+	mfa, err := s.tokens.mfaEnabled(int64(user.Id))
 	if err != nil {
-		return "", fmt.Errorf("не удалось подписать токен: %w", err)
+		return nil, nil, err
+	}
+	if mfa {
+		// Счётчик неудачных попыток сбрасывается только после кода 2FA,
+		// иначе знающий пароль мог бы перебирать коды без блокировки
+		challenge, err := s.tokens.IssueMFAChallenge(user)
+		return nil, challenge, err
 	}
 
-	return signedToken, nil
+	if err := s.guard.succeed(int64(user.Id)); err != nil {
+		return nil, nil, err
+	}
+	tokens, err := s.tokens.IssueTokens(user, false, device)
+	return tokens, nil, err
+}
+
+// encodeUserCursor возвращает курсор страницы пользователей, следующей после id.
+func encodeUserCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeUserCursor(s string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(string(data))
+	if err != nil || id < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
 }
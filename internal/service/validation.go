@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const minUsernameLength = 3
+
+// ValidationError возвращается, когда входные данные не прошли проверку.
+// Fields — причина для каждого неверного поля запроса (по имени поля в JSON).
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %s", name, e.Fields[name]))
+	}
+	return "некорректные данные: " + strings.Join(parts, "; ")
+}
+
+// FieldErrors возвращает причины по полям для ответа API.
+func (e *ValidationError) FieldErrors() map[string]string {
+	return e.Fields
+}
+
+// add запоминает причину для поля; для каждого поля сохраняется первая причина.
+func (e *ValidationError) add(field, reason string) {
+	if reason == "" {
+		return
+	}
+	if e.Fields == nil {
+		e.Fields = make(map[string]string)
+	}
+	if _, ok := e.Fields[field]; !ok {
+		e.Fields[field] = reason
+	}
+}
+
+// has сообщает, есть ли уже причина для поля.
+func (e *ValidationError) has(field string) bool {
+	_, ok := e.Fields[field]
+	return ok
+}
+
+// err возвращает nil, если ни одно поле не отмечено неверным.
+func (e *ValidationError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// usernameProblem возвращает причину, по которой имя пользователя не подходит, или пустую строку.
+// Имя — от 3 до 50 букв, цифр и символов «_», «.», «-», начинается с буквы или цифры.
+func usernameProblem(username string) string {
+	if username == "" {
+		return "имя пользователя обязательно"
+	}
+	if n := utf8.RuneCountInString(username); n < minUsernameLength || n > maxUsernameLength {
+		return fmt.Sprintf("имя пользователя должно быть от %d до %d символов", minUsernameLength, maxUsernameLength)
+	}
+	for i, r := range username {
+		alnum := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if i == 0 && !alnum {
+			return "имя пользователя должно начинаться с буквы или цифры"
+		}
+		if !alnum && r != '_' && r != '.' && r != '-' {
+			return "имя пользователя может содержать только буквы, цифры и символы _ . -"
+		}
+	}
+	return ""
+}
+
+// emailProblem возвращает причину, по которой адрес email не подходит, или пустую строку.
+func emailProblem(email string) string {
+	if email == "" {
+		return "email обязателен"
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email || len(email) > maxEmailLength {
+		return "некорректный email"
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+// Package totp реализует одноразовые пароли по времени (RFC 6238) с параметрами,
+// которые понимают приложения-аутентификаторы: HMAC-SHA1, 6 цифр, шаг 30 секунд.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits — число цифр в коде.
+	Digits = 6
+	// Period — шаг времени в секундах.
+	Period = 30
+	// skew — сколько соседних шагов принимать из-за расхождения часов.
+	skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret возвращает новый 160-битный секрет в base32 без выравнивания.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("ошибка генерации секрета: %w", err)
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// Counter возвращает номер шага времени для момента t.
+func Counter(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code вычисляет код для шага counter (RFC 4226, раздел 5.3).
+func Code(secret string, counter int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("некорректный секрет: %w", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, value%1_000_000), nil
+}
+
+// Validate проверяет код на момент t с допуском в один шаг в обе стороны
+// и возвращает шаг, которому код соответствует. Шаг нужно запомнить,
+// чтобы один и тот же код нельзя было использовать дважды.
+func Validate(secret, code string, t time.Time) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+	current := Counter(t)
+	for c := current - skew; c <= current+skew; c++ {
+		expected, err := Code(secret, c)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// URI возвращает ссылку otpauth:// для QR-кода приложения-аутентификатора.
+func URI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", strconv.Itoa(Digits))
+	v.Set("period", strconv.Itoa(Period))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + v.Encode()
+}
@@ -8,6 +8,13 @@ import (
 	"strconv"
 )
 
+// Page — страница списка для WriteJSONResponse: Data попадает в "data",
+// а NextCursor — в "next_cursor" конверта ответа.
+type Page struct {
+	Data       interface{}
+	NextCursor string
+}
+
 // Проверка метода
 func AllowMethod(w http.ResponseWriter, r *http.Request, allowed string) bool {
 	if r.Method != allowed {
@@ -17,6 +24,12 @@ func AllowMethod(w http.ResponseWriter, r *http.Request, allowed string) bool {
 	return true
 }
 
+// fieldErrors — ошибка проверки данных с причинами по полям запроса.
+type fieldErrors interface {
+	error
+	FieldErrors() map[string]string
+}
+
 // Универсальный JSON-ответ
 func WriteJSONResponse(w http.ResponseWriter, statusCode int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -31,9 +44,19 @@ func WriteJSONResponse(w http.ResponseWriter, statusCode int, payload interface{
 	case string:
 		// Просто сообщение
 		response["message"] = v
+	case fieldErrors:
+		// Ошибка проверки данных: причины по полям попадают в "fields"
+		response["error"] = v.Error()
+		response["fields"] = v.FieldErrors()
 	case error:
 		// Ошибка
 		response["error"] = v.Error()
+	case Page:
+		// Страница списка с курсором на следующую
+		response["data"] = v.Data
+		if v.NextCursor != "" {
+			response["next_cursor"] = v.NextCursor
+		}
 	default:
 		// Структура/массив/объект
 		response["data"] = v